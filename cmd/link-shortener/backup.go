@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"link-shortener/internal/backup"
+	"link-shortener/internal/config"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage/sqlite"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const backupUsage = `Usage:
+  link-shortener backup [-dir DIR] [-list]
+                         snapshot the configured storage (safe while serving)
+  link-shortener restore [-dir DIR] -latest | SNAPSHOT
+                         replace the storage with a snapshot (stop the server first)
+`
+
+// runBackup creates a snapshot in the configured backup directory, or lists
+// the existing ones, and returns the process exit code.
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, backupUsage) }
+	dir := fs.String("dir", "", "snapshot directory (backup.dir from config if empty)")
+	list := fs.Bool("list", false, "list snapshots instead of creating one")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.MustLoadConfig()
+	if *dir == "" {
+		*dir = cfg.Backup.Dir
+	}
+
+	s, err := sqlite.New(cfg.StoragePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening storage: %v\n", err)
+		return 1
+	}
+	defer func() { _ = s.DB.Close() }()
+
+	m := backup.New(slogdiscard.NewDiscardLogger(), s, cfg.StoragePath, *dir, cfg.Backup.Retention)
+
+	if *list {
+		snapshots, err := m.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+			return 1
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CREATED\tSIZE\tPATH")
+		for _, snapshot := range snapshots {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", snapshot.CreatedAt.Format(time.RFC3339), snapshot.Size, snapshot.Path)
+		}
+		_ = tw.Flush()
+		return 0
+	}
+
+	snapshot, err := m.Create()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(snapshot.Path)
+	return 0
+}
+
+// runRestore swaps a validated snapshot in place of the configured storage
+// and returns the process exit code.
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, backupUsage) }
+	dir := fs.String("dir", "", "snapshot directory for -latest (backup.dir from config if empty)")
+	latest := fs.Bool("latest", false, "restore the most recent snapshot")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 || (fs.NArg() == 1) == *latest {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.MustLoadConfig()
+	if *dir == "" {
+		*dir = cfg.Backup.Dir
+	}
+
+	path := fs.Arg(0)
+	if *latest {
+		snapshot, err := backup.New(nil, nil, cfg.StoragePath, *dir, 0).Latest()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+			return 1
+		}
+		path = snapshot.Path
+	}
+
+	previous, err := backup.Restore(path, cfg.StoragePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("restored %s from %s\n", cfg.StoragePath, path)
+	if previous != "" {
+		fmt.Printf("previous database kept at %s\n", previous)
+	}
+	return 0
+}
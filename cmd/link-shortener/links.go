@@ -0,0 +1,498 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"link-shortener/internal/config"
+	"link-shortener/internal/http-server/handlers/url/save"
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const linksUsage = `Usage: link-shortener links <command> [flags]
+
+Commands:
+  create -url URL [-alias ALIAS] [-domain HOST] [-status CODE] [-password PASSWORD] [-max-clicks N]
+         [-active-from TIME] [-active-until TIME] [-fallback URL]
+         [-target PLATFORM=URL ...] [-geo COUNTRY=URL ...] [-variant WEIGHT=URL ...]
+         [-param KEY=VALUE ...] [-passthrough] [-prefix] [-interstitial]
+         [-unfurl-title TEXT] [-unfurl-description TEXT] [-unfurl-image URL]
+         [-folder NAME] [-tag TAG ...]
+                                   create a link (alias is generated if omitted)
+  get [-domain HOST] ALIAS         print the link stored under ALIAS
+  list [-limit N] [-offset N] [-title TEXT] [-workspace NAME] [-tag TAG] [-folder NAME]
+                                   list links ordered by id, or those whose
+                                   page title contains TEXT, of a workspace,
+                                   with a tag or in a folder
+  delete -id ID | -alias ALIAS [-domain HOST]
+                                   delete a link
+  import [-file PATH] [-format csv|ndjson] [-policy skip|overwrite|fail]
+                                   import links (stdin by default)
+  export [-file PATH] [-format csv|ndjson]
+                                   export links (stdout by default)
+
+TIME is in RFC 3339 format, e.g. 2025-03-01T09:00:00+02:00.
+PLATFORM is ios, android, windows, macos, linux, mobile or desktop, and
+COUNTRY a two-letter code such as DE. HOST is one of the configured
+domains; links are on the default domain without it.
+`
+
+var errUsage = errors.New("invalid usage")
+
+type linksCommand func(s *sqlite.Storage, args []string) error
+
+var linksCommands = map[string]linksCommand{
+	"create": linksCreate,
+	"get":    linksGet,
+	"list":   linksList,
+	"delete": linksDelete,
+	"import": linksImport,
+	"export": linksExport,
+}
+
+// runLinks executes a "links" subcommand against the configured storage
+// and returns the process exit code.
+// linksPolicy checks the destinations of links the commands create, as the
+// server would.
+var linksPolicy save.URLPolicy
+
+// linksDomains are the short domains links may be created on.
+var linksDomains *shortdomain.Set
+
+func runLinks(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, linksUsage)
+		return 2
+	}
+
+	cmd, ok := linksCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown links command %q\n\n%s", args[0], linksUsage)
+		return 2
+	}
+
+	cfg := config.MustLoadConfig()
+
+	policy, err := newURLPolicy(slogdiscard.NewDiscardLogger(), cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading url policy: %v\n", err)
+		return 1
+	}
+	linksPolicy = policy
+
+	domains, err := newDomains(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading domains: %v\n", err)
+		return 1
+	}
+	linksDomains = domains
+
+	s, err := sqlite.New(cfg.StoragePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening storage: %v\n", err)
+		return 1
+	}
+
+	if err := cmd(s, args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(os.Stderr, linksUsage)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "links %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func linksCreate(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	rawURL := fs.String("url", "", "destination URL")
+	alias := fs.String("alias", "", "alias (generated if empty)")
+	domain := fs.String("domain", "", "short domain of the link (default domain if empty)")
+	status := fs.Int("status", 0, "redirect status: 301, 302, 307 or 308 (server default if 0)")
+	password := fs.String("password", "", "password required to follow the link")
+	maxClicks := fs.Int64("max-clicks", 0, "number of redirects before the link expires (0 = unlimited)")
+	activeFrom := fs.String("active-from", "", "time from which the link redirects to URL")
+	activeUntil := fs.String("active-until", "", "time from which the link no longer redirects to URL")
+	fallback := fs.String("fallback", "", "URL to redirect to outside of the active time")
+	var targets targetsFlag
+	fs.Var(&targets, "target", "PLATFORM=URL destination for one platform (repeatable)")
+	var geoTargets geoTargetsFlag
+	fs.Var(&geoTargets, "geo", "COUNTRY=URL destination for one country (repeatable)")
+	var variants variantsFlag
+	fs.Var(&variants, "variant", "WEIGHT=URL destination for a share of the visitors (repeatable)")
+	var params paramsFlag
+	fs.Var(&params, "param", "KEY=VALUE query parameter added to redirects (repeatable)")
+	passthrough := fs.Bool("passthrough", false, "forward the query of the short URL to the destination")
+	prefix := fs.Bool("prefix", false, "also match paths below the alias and append them to the destination")
+	interstitial := fs.Bool("interstitial", false, "warn browsers before leaving for an external domain")
+	var unfurl save.Unfurl
+	fs.StringVar(&unfurl.Title, "unfurl-title", "", "title chat apps show for the link")
+	fs.StringVar(&unfurl.Description, "unfurl-description", "", "description chat apps show for the link")
+	fs.StringVar(&unfurl.Image, "unfurl-image", "", "URL of the image chat apps show for the link")
+	folder := fs.String("folder", "", "folder to file the link in")
+	var tags tagsFlag
+	fs.Var(&tags, "tag", "tag of the link (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := save.Request{
+		URL:              *rawURL,
+		Alias:            *alias,
+		Domain:           *domain,
+		RedirectStatus:   *status,
+		Password:         *password,
+		MaxClicks:        *maxClicks,
+		FallbackURL:      *fallback,
+		Targets:          targets,
+		GeoTargets:       geoTargets,
+		Destinations:     variants,
+		QueryParams:      params,
+		QueryPassthrough: *passthrough,
+		Prefix:           *prefix,
+		Interstitial:     *interstitial,
+		Folder:           *folder,
+		Tags:             tags,
+	}
+	if unfurl != (save.Unfurl{}) {
+		req.Unfurl = &unfurl
+	}
+	var err error
+	if req.ActiveFrom, err = parseTime("active-from", *activeFrom); err != nil {
+		return err
+	}
+	if req.ActiveUntil, err = parseTime("active-until", *activeUntil); err != nil {
+		return err
+	}
+
+	link, err := createLink(s, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d\t%s\t%s\n", link.ID, linkName(link), link.URL)
+	return nil
+}
+
+func linksGet(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	domain := fs.String("domain", "", "short domain of the link (default domain if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	host, err := linksDomains.Normalize(*domain)
+	if err != nil {
+		return err
+	}
+
+	link, err := s.GetLink(host, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d\t%s\t%s\n", link.ID, linkName(link), link.URL)
+	return nil
+}
+
+func linksList(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of links (0 = all)")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	title := fs.String("title", "", "only links whose page title contains this")
+	workspace := fs.String("workspace", "", "only links of this workspace")
+	tag := fs.String("tag", "", "only links with this tag")
+	folder := fs.String("folder", "", "only links in this folder")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := storage.ListOptions{
+		Limit:  *limit,
+		Offset: *offset,
+		Title:  *title,
+		Tag:    strings.ToLower(strings.TrimSpace(*tag)),
+		Folder: strings.TrimSpace(*folder),
+	}
+	if *workspace != "" {
+		ws, err := s.GetWorkspace(*workspace)
+		if err != nil {
+			return err
+		}
+		opts.WorkspaceID = ws.ID
+	}
+
+	links, err := s.ListLinks(opts)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tALIAS\tURL")
+	for _, link := range links {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", link.ID, linkName(link), link.URL)
+	}
+	return tw.Flush()
+}
+
+func linksDelete(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "id of the link")
+	alias := fs.String("alias", "", "alias of the link")
+	domain := fs.String("domain", "", "short domain of the link with -alias (default domain if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if (*id == 0) == (*alias == "") {
+		return fmt.Errorf("%w: exactly one of -id or -alias is required", errUsage)
+	}
+
+	if *alias != "" {
+		host, err := linksDomains.Normalize(*domain)
+		if err != nil {
+			return err
+		}
+		link, err := s.GetLink(host, *alias)
+		if err != nil {
+			return err
+		}
+		*id = link.ID
+	}
+
+	if err := s.DeleteURL(*id); err != nil {
+		return err
+	}
+
+	fmt.Printf("deleted %d\n", *id)
+	return nil
+}
+
+func linksImport(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "file to read (stdin if empty)")
+	format := fs.String("format", "", "csv or ndjson (guessed from -file, ndjson by default)")
+	policyName := fs.String("policy", string(linkio.PolicyFail), "on alias collision: skip, overwrite or fail")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	policy, err := linkio.ParsePolicy(*policyName)
+	if err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = linkio.FormatFromPath(*file)
+	}
+
+	in := io.Reader(os.Stdin)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+
+	r, err := linkio.NewReader(in, *format)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.BeginImport()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	report, err := linkio.Import(r, tx, policy, save.LinkValidator(linksPolicy))
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(os.Stderr, "line %d: %s\n", rowErr.Line, rowErr.Error)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("created %d, overwritten %d, skipped %d, failed %d\n",
+		report.Created, report.Overwritten, report.Skipped, report.Failed)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d link(s) were not imported", report.Failed)
+	}
+	return nil
+}
+
+func linksExport(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := fs.String("file", "", "file to write (stdout if empty)")
+	format := fs.String("format", "", "csv or ndjson (guessed from -file, ndjson by default)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = linkio.FormatFromPath(*file)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	w, err := linkio.NewWriter(out, *format)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ForEachLink(w.Write); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// createLink validates req exactly like the save handler does and stores it.
+func createLink(s *sqlite.Storage, req save.Request) (storage.Link, error) {
+	var err error
+	if req.Domain, err = linksDomains.Normalize(req.Domain); err != nil {
+		return storage.Link{}, err
+	}
+	if err := workspace.CheckUnowned(req.Alias); err != nil {
+		return storage.Link{}, err
+	}
+	if err := save.Validate(req, linksPolicy); err != nil {
+		return storage.Link{}, err
+	}
+
+	link, err := req.Link()
+	if err != nil {
+		return storage.Link{}, err
+	}
+	if link.Alias == "" {
+		link.Alias = save.NewAlias()
+	}
+
+	link.ID, err = s.SaveLink(link)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	return link, nil
+}
+
+// linkName returns the alias of link, after its domain if it is not on the
+// default one.
+func linkName(link storage.Link) string {
+	if link.Domain == "" {
+		return link.Alias
+	}
+	return link.Domain + "/" + link.Alias
+}
+
+// targetsFlag collects repeated -target PLATFORM=URL flags.
+type targetsFlag []save.Target
+
+func (f *targetsFlag) String() string { return "" }
+
+func (f *targetsFlag) Set(value string) error {
+	platform, url, ok := strings.Cut(value, "=")
+	if !ok || platform == "" {
+		return errors.New("want PLATFORM=URL")
+	}
+	*f = append(*f, save.Target{Platform: platform, URL: url})
+	return nil
+}
+
+// geoTargetsFlag collects repeated -geo COUNTRY=URL flags.
+type geoTargetsFlag []save.GeoTarget
+
+func (f *geoTargetsFlag) String() string { return "" }
+
+func (f *geoTargetsFlag) Set(value string) error {
+	country, url, ok := strings.Cut(value, "=")
+	if !ok || country == "" {
+		return errors.New("want COUNTRY=URL")
+	}
+	*f = append(*f, save.GeoTarget{Country: strings.ToUpper(country), URL: url})
+	return nil
+}
+
+// paramsFlag collects repeated -param KEY=VALUE flags.
+type paramsFlag []save.QueryParam
+
+func (f *paramsFlag) String() string { return "" }
+
+func (f *paramsFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("want KEY=VALUE")
+	}
+	*f = append(*f, save.QueryParam{Key: key, Value: v})
+	return nil
+}
+
+// variantsFlag collects repeated -variant WEIGHT=URL flags.
+type variantsFlag []save.Destination
+
+func (f *variantsFlag) String() string { return "" }
+
+func (f *variantsFlag) Set(value string) error {
+	weight, url, ok := strings.Cut(value, "=")
+	if !ok {
+		return errors.New("want WEIGHT=URL")
+	}
+	w, err := strconv.Atoi(weight)
+	if err != nil {
+		return errors.New("want WEIGHT=URL")
+	}
+	*f = append(*f, save.Destination{URL: url, Weight: w})
+	return nil
+}
+
+// tagsFlag collects repeated -tag flags.
+type tagsFlag []string
+
+func (f *tagsFlag) String() string { return "" }
+
+func (f *tagsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// parseTime parses the RFC 3339 value of the named flag, if it is set.
+func parseTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s: %w", name, err)
+	}
+	return &t, nil
+}
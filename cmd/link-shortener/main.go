@@ -1,21 +1,48 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"link-shortener/internal/backup"
 	"link-shortener/internal/config"
+	"link-shortener/internal/healthcheck"
+	backupHandler "link-shortener/internal/http-server/handlers/admin/backup"
+	"link-shortener/internal/http-server/handlers/admin/export"
+	"link-shortener/internal/http-server/handlers/admin/health"
+	"link-shortener/internal/http-server/handlers/admin/importer"
+	"link-shortener/internal/http-server/handlers/qr"
 	"link-shortener/internal/http-server/handlers/redirect"
+	"link-shortener/internal/http-server/handlers/url/batch"
 	"link-shortener/internal/http-server/handlers/url/delete"
+	"link-shortener/internal/http-server/handlers/url/info"
+	"link-shortener/internal/http-server/handlers/url/list"
 	"link-shortener/internal/http-server/handlers/url/save"
+	"link-shortener/internal/http-server/handlers/url/search"
+	"link-shortener/internal/http-server/handlers/url/stats"
+	"link-shortener/internal/http-server/handlers/url/tagstats"
+	"link-shortener/internal/http-server/handlers/url/update"
+	"link-shortener/internal/http-server/middleware/auth"
 	mwLogger "link-shortener/internal/http-server/middleware/logger"
+	"link-shortener/internal/lib/clientip"
+	"link-shortener/internal/lib/geoip"
 	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/opengraph"
+	"link-shortener/internal/lib/ratelimit"
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/urlpolicy"
+	"link-shortener/internal/linkmeta"
 	"link-shortener/internal/storage/sqlite"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
-	_ "time"
+	"time"
 )
 
 const (
@@ -24,8 +51,42 @@ const (
 	envProd  = "prod"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take to finish
+// after a shutdown signal.
+const shutdownTimeout = 10 * time.Second
+
+const usage = `Usage:
+  link-shortener [serve]       start the HTTP server
+  link-shortener links <cmd>   manage links in the configured storage
+  link-shortener workspaces <cmd>
+                               manage workspaces, their quotas and API keys
+  link-shortener backup        snapshot the configured storage
+  link-shortener restore       restore the storage from a snapshot
+
+`
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+		case "links":
+			os.Exit(runLinks(os.Args[2:]))
+		case "workspaces":
+			os.Exit(runWorkspaces(os.Args[2:]))
+		case "backup":
+			os.Exit(runBackup(os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(os.Args[2:]))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+			os.Exit(2)
+		}
+	}
 
+	serve()
+}
+
+func serve() {
 	cfg := config.MustLoadConfig()
 
 	log := setupLogger(cfg.Env)
@@ -44,6 +105,54 @@ func main() {
 		log.Info("storage opened successfully")
 	}
 
+	backups := backup.New(log, storage, cfg.StoragePath, cfg.Backup.Dir, cfg.Backup.Retention)
+
+	urlPolicy, err := newURLPolicy(log, cfg)
+	if err != nil {
+		log.Error("error loading url policy", sl.Err(err))
+		os.Exit(1)
+	}
+
+	domains, err := newDomains(cfg)
+	if err != nil {
+		log.Error("invalid domains", sl.Err(err))
+		os.Exit(1)
+	}
+
+	saveOpts := save.Options{PublicURL: cfg.HTTPServer.PublicURL, Policy: urlPolicy, Domains: domains}
+	switch cfg.RedirectChains.Mode {
+	case "off":
+	case "reject", "flatten":
+		saveOpts.Resolver = redirectchain.New(redirectchain.Config{
+			MaxHops:      cfg.RedirectChains.MaxHops,
+			Timeout:      cfg.RedirectChains.Timeout,
+			Flatten:      cfg.RedirectChains.Mode == "flatten",
+			AllowPrivate: cfg.URLPolicy.AllowPrivate,
+			Check:        urlPolicy.Check,
+		})
+	default:
+		log.Error("invalid redirect_chains.mode", slog.String("mode", cfg.RedirectChains.Mode))
+		os.Exit(1)
+	}
+
+	// metadata stays a nil interface when disabled; the handlers check it.
+	var (
+		metaQueue *linkmeta.Queue
+		metadata  save.MetadataQueue
+	)
+	if cfg.Metadata.Enabled {
+		metaQueue = linkmeta.New(log, storage, opengraph.New(opengraph.Config{
+			Timeout:      cfg.Metadata.Timeout,
+			MaxBytes:     cfg.Metadata.MaxBytes,
+			AllowPrivate: cfg.URLPolicy.AllowPrivate,
+		}), linkmeta.Config{
+			Workers:   cfg.Metadata.Workers,
+			QueueSize: cfg.Metadata.QueueSize,
+		})
+		metadata = metaQueue
+		saveOpts.Metadata = metaQueue
+	}
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
@@ -52,23 +161,117 @@ func main() {
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
 
+	basicAuth := middleware.BasicAuth("link-shortener", map[string]string{
+		cfg.HTTPServer.User: cfg.HTTPServer.Password,
+	})
+
 	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("link-shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+		// Workspaces use the API with their keys; the admin sees all of them.
+		r.Use(auth.New(log, cfg.HTTPServer.User, cfg.HTTPServer.Password, storage))
 
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{id}", delete.New(log, storage)) // Delete by ID
+		r.Post("/", save.New(log, storage, saveOpts))
+		r.Post("/batch", batch.New(log, storage, urlPolicy, saveOpts.Resolver, cfg.RedirectChains.Timeout, metadata, domains))
+		r.Get("/", list.New(log, storage))
+		r.Get("/search", search.New(log, storage))
+		r.Get("/{alias}", info.New(log, storage, domains))
+		r.Get("/{alias}/stats", stats.New(log, storage, domains))
+		r.Get("/tags/{tag}/stats", tagstats.New(log, storage))
+		r.Patch("/{id}", update.New(log, storage, urlPolicy, saveOpts.Resolver, metadata, domains)) // Update by ID
+		r.Delete("/{id}", delete.New(log, storage))                                                 // Delete by ID
 	})
 
-	router.Get("/{alias}", redirect.New(log, storage))
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(basicAuth)
 
-	log.Info("starting server", slog.String("address", cfg.Address))
+		r.Get("/export", export.New(log, storage))
+		r.Post("/import", importer.New(log, storage, urlPolicy))
+		r.Post("/backup", backupHandler.New(log, backups, cfg.Backup.Timeout))
+		r.Get("/health", health.New(log, storage))
+	})
+
+	if !redirect.ValidStatus(cfg.Redirect.DefaultStatus) {
+		log.Error("invalid redirect.default_status", slog.Int("status", cfg.Redirect.DefaultStatus))
+		os.Exit(1)
+	}
+
+	redirectOpts := redirect.Options{
+		DefaultStatus:   cfg.Redirect.DefaultStatus,
+		PermanentMaxAge: cfg.Redirect.PermanentMaxAge,
+		AttemptLimiter:  ratelimit.New(cfg.Redirect.PasswordAttempts, cfg.Redirect.PasswordWindow),
+		Interstitial:    cfg.Redirect.Interstitial,
+		InternalDomains: cfg.Redirect.InternalDomains,
+		Unfurl:          cfg.Redirect.Unfurl,
+		Domains:         domains,
+	}
+
+	if cfg.GeoIP.Database != "" {
+		ips, err := clientip.NewResolver(cfg.GeoIP.TrustedProxies)
+		if err != nil {
+			log.Error("invalid geoip.trusted_proxies", sl.Err(err))
+			os.Exit(1)
+		}
+
+		geoDB, err := geoip.Open(cfg.GeoIP.Database)
+		if err != nil {
+			log.Error("error opening GeoIP database", sl.Err(err))
+			os.Exit(1)
+		}
+		defer func() { _ = geoDB.Close() }()
 
-	// add
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	// -
+		redirectOpts.CountryLocator = geoip.NewLocator(geoDB, ips)
+	}
+
+	// Takes precedence over the paths of a prefix link named "qr", which
+	// save.Validate refuses.
+	router.Get("/qr/{alias}", qr.New(log, storage, cfg.HTTPServer.PublicURL, domains))
+
+	rootHandler := redirect.NewRoot(log, domains)
+	router.Get("/", rootHandler)
+	router.Head("/", rootHandler)
+
+	redirectHandler := redirect.New(log, storage, storage, storage, redirectOpts)
+	// 307 and 308 links keep the method, so they must be reachable with it.
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		router.Method(method, "/{alias}", redirectHandler)
+		router.Method(method, "/{alias}/*", redirectHandler)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Backup.Interval > 0 {
+		log.Info("scheduled backups enabled",
+			slog.String("dir", cfg.Backup.Dir),
+			slog.Duration("interval", cfg.Backup.Interval),
+			slog.Int("retention", cfg.Backup.Retention),
+		)
+		go backups.Run(ctx, cfg.Backup.Interval)
+	}
+
+	if cfg.URLPolicy.DomainsFile != "" && cfg.URLPolicy.ReloadInterval > 0 {
+		go urlPolicy.Run(ctx, cfg.URLPolicy.ReloadInterval)
+	}
+
+	if metaQueue != nil {
+		go metaQueue.Run(ctx)
+	}
+
+	if cfg.HealthCheck.Interval > 0 {
+		log.Info("link health checks enabled",
+			slog.Duration("interval", cfg.HealthCheck.Interval),
+			slog.Int("concurrency", cfg.HealthCheck.Concurrency),
+		)
+		checker := healthcheck.New(log, storage, healthcheck.Config{
+			Concurrency:  cfg.HealthCheck.Concurrency,
+			HostDelay:    cfg.HealthCheck.HostDelay,
+			Timeout:      cfg.HealthCheck.Timeout,
+			BrokenAfter:  cfg.HealthCheck.BrokenAfter,
+			AllowPrivate: cfg.URLPolicy.AllowPrivate,
+		})
+		go checker.Run(ctx, cfg.HealthCheck.Interval)
+	}
+
+	log.Info("starting server", slog.String("address", cfg.Address))
 
 	srv := &http.Server{
 		Addr:              cfg.Address,
@@ -78,11 +281,67 @@ func main() {
 		IdleTimeout:       cfg.HTTPServer.IdleTimeout,
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Error("error starting server", sl.Err(err))
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("error starting server", sl.Err(err))
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to stop server", sl.Err(err))
+	}
+	if err := storage.DB.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
+
+	log.Info("server stopped")
+}
+
+// newURLPolicy returns the policy for link destinations configured in cfg.
+func newURLPolicy(log *slog.Logger, cfg *config.Config) (*urlpolicy.Policy, error) {
+	var own []string
+	if cfg.HTTPServer.PublicURL != "" {
+		u, err := url.Parse(cfg.HTTPServer.PublicURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http_server.public_url: %w", err)
+		}
+		own = append(own, u.Hostname())
+	}
+	for _, d := range cfg.Domains {
+		own = append(own, d.Host)
+	}
+
+	return urlpolicy.New(log, urlpolicy.Config{
+		Schemes:      cfg.URLPolicy.Schemes,
+		AllowPrivate: cfg.URLPolicy.AllowPrivate,
+		DomainsFile:  cfg.URLPolicy.DomainsFile,
+		OwnHosts:     own,
+	})
+}
+
+// newDomains returns the short domains cfg configures.
+func newDomains(cfg *config.Config) (*shortdomain.Set, error) {
+	others := make([]shortdomain.Config, 0, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		others = append(others, shortdomain.Config{
+			Host:         d.Host,
+			RootURL:      d.RootURL,
+			NotFoundPage: d.NotFoundPage,
+			Workspace:    d.Workspace,
+		})
 	}
 
-	log.Error("shutting down")
+	return shortdomain.New(shortdomain.Config{
+		RootURL:      cfg.Redirect.RootURL,
+		NotFoundPage: cfg.Redirect.NotFoundPage,
+	}, others)
 }
 
 func setupLogger(env string) *slog.Logger {
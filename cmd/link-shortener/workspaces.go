@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"link-shortener/internal/config"
+	"link-shortener/internal/lib/apikey"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const workspacesUsage = `Usage: link-shortener workspaces <command> [flags]
+
+Commands:
+  create [-max-links N] [-max-daily N] NAME
+                                   create a workspace (0 = no limit)
+  list                             list workspaces with their quotas and usage
+  quota [-max-links N] [-max-daily N] NAME
+                                   change the quotas of a workspace
+  key [-name LABEL] NAME           create an API key for a workspace; the key
+                                   is only ever printed here
+  keys NAME                        list the API keys of a workspace
+  revoke ID                        revoke an API key
+
+NAME is 1 to 32 lowercase letters, digits and hyphens. Links a workspace
+creates on shared domains get aliases prefixed with "NAME~".
+`
+
+var workspacesCommands = map[string]linksCommand{
+	"create": workspacesCreate,
+	"list":   workspacesList,
+	"quota":  workspacesQuota,
+	"key":    workspacesKey,
+	"keys":   workspacesKeys,
+	"revoke": workspacesRevoke,
+}
+
+// runWorkspaces executes a "workspaces" subcommand against the configured
+// storage and returns the process exit code.
+func runWorkspaces(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, workspacesUsage)
+		return 2
+	}
+
+	cmd, ok := workspacesCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown workspaces command %q\n\n%s", args[0], workspacesUsage)
+		return 2
+	}
+
+	cfg := config.MustLoadConfig()
+
+	s, err := sqlite.New(cfg.StoragePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening storage: %v\n", err)
+		return 1
+	}
+
+	if err := cmd(s, args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(os.Stderr, workspacesUsage)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "workspaces %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+// quotaFlags adds the quota flags to fs, writing to ws.
+func quotaFlags(fs *flag.FlagSet, ws *storage.Workspace) {
+	fs.Int64Var(&ws.MaxLinks, "max-links", ws.MaxLinks, "number of links the workspace may have (0 = no limit)")
+	fs.Int64Var(&ws.MaxDailyCreates, "max-daily", ws.MaxDailyCreates, "number of links the workspace may create per day (0 = no limit)")
+}
+
+// workspaceArg returns the workspace named by the only argument of fs.
+func workspaceArg(s *sqlite.Storage, fs *flag.FlagSet) (storage.Workspace, error) {
+	if fs.NArg() != 1 {
+		return storage.Workspace{}, errUsage
+	}
+	return s.GetWorkspace(fs.Arg(0))
+}
+
+func workspacesCreate(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	var ws storage.Workspace
+	quotaFlags(fs, &ws)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	ws.Name = fs.Arg(0)
+	if err := workspace.ValidateName(ws.Name); err != nil {
+		return err
+	}
+	if ws.MaxLinks < 0 || ws.MaxDailyCreates < 0 {
+		return fmt.Errorf("%w: quotas must not be negative", errUsage)
+	}
+
+	id, err := s.CreateWorkspace(ws)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d\t%s\n", id, ws.Name)
+	return nil
+}
+
+func workspacesList(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	list, err := s.ListWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tLINKS\tTODAY")
+	for _, ws := range list {
+		usage, err := s.WorkspaceUsage(ws.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", ws.ID, ws.Name,
+			ofQuota(usage.Links, ws.MaxLinks), ofQuota(usage.CreatedToday, ws.MaxDailyCreates))
+	}
+	return tw.Flush()
+}
+
+// ofQuota formats n with the quota it counts against, if there is one.
+func ofQuota(n, quota int64) string {
+	if quota == 0 {
+		return strconv.FormatInt(n, 10)
+	}
+	return fmt.Sprintf("%d/%d", n, quota)
+}
+
+func quotaString(quota int64) string {
+	if quota == 0 {
+		return "none"
+	}
+	return strconv.FormatInt(quota, 10)
+}
+
+func workspacesQuota(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("quota", flag.ContinueOnError)
+	var quota storage.Workspace
+	quotaFlags(fs, &quota)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ws, err := workspaceArg(s, fs)
+	if err != nil {
+		return err
+	}
+
+	// Quotas not given on the command line stay as they are.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-links":
+			ws.MaxLinks = quota.MaxLinks
+		case "max-daily":
+			ws.MaxDailyCreates = quota.MaxDailyCreates
+		}
+	})
+	if ws.MaxLinks < 0 || ws.MaxDailyCreates < 0 {
+		return fmt.Errorf("%w: quotas must not be negative", errUsage)
+	}
+
+	if err := s.SetWorkspaceQuota(ws.ID, ws); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\tmax links %s\tmax daily %s\n", ws.Name, quotaString(ws.MaxLinks), quotaString(ws.MaxDailyCreates))
+	return nil
+}
+
+func workspacesKey(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("key", flag.ContinueOnError)
+	name := fs.String("name", "", "label telling the key apart from the others of the workspace")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ws, err := workspaceArg(s, fs)
+	if err != nil {
+		return err
+	}
+
+	key, hash, err := apikey.New()
+	if err != nil {
+		return err
+	}
+
+	id, err := s.CreateAPIKey(storage.APIKey{WorkspaceID: ws.ID, Name: *name, CreatedAt: time.Now()}, hash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "created key %d for %s; it is not shown again\n", id, ws.Name)
+	fmt.Println(key)
+	return nil
+}
+
+func workspacesKeys(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("keys", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ws, err := workspaceArg(s, fs)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.ListAPIKeys(ws.ID)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tCREATED")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", key.ID, key.Name, key.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func workspacesRevoke(s *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid key id %q", errUsage, fs.Arg(0))
+	}
+
+	if err := s.DeleteAPIKey(id); err != nil {
+		return err
+	}
+
+	fmt.Printf("revoked %d\n", id)
+	return nil
+}
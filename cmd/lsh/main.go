@@ -0,0 +1,644 @@
+// Command lsh is a command-line client for the link-shortener HTTP API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"link-shortener/internal/lib/api"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: lsh [flags] <command> [args]
+
+Commands:
+  create [-alias ALIAS] [-domain HOST] [-status CODE] [-password PASSWORD] [-max-clicks N]
+         [-active-from TIME] [-active-until TIME] [-fallback URL]
+         [-target PLATFORM=URL ...] [-geo COUNTRY=URL ...] [-variant WEIGHT=URL ...]
+         [-param KEY=VALUE ...] [-passthrough] [-prefix]
+         [-interstitial] [-unfurl-title TEXT] [-unfurl-description TEXT]
+         [-unfurl-image URL] [-folder NAME] [-tag TAG ...] URL
+                                         shorten URL
+  get [-domain HOST] ALIAS               show a link
+  list [-limit N] [-offset N] [-title TEXT] [-tag TAG] [-folder NAME]
+                                         list links, or those whose page
+                                         title contains TEXT, with a tag or
+                                         in a folder
+  search [-limit N] [-offset N] QUERY...
+                                         find links by words of their alias,
+                                         URL, title or tags, best first
+  update [-url URL] [-alias ALIAS] [-status CODE] [-password PASSWORD] [-max-clicks N]
+         [-active-from TIME] [-active-until TIME] [-fallback URL]
+         [-target PLATFORM=URL ...] [-geo COUNTRY=URL ...] [-variant WEIGHT=URL ...]
+         [-param KEY=VALUE ...] [-passthrough=true|false] [-prefix=true|false]
+         [-interstitial=true|false] [-unfurl-title TEXT] [-unfurl-description TEXT]
+         [-unfurl-image URL] [-folder NAME] [-tag TAG ...] ID
+                                         change a link
+  delete ID                              delete a link
+  stats [-domain HOST] ALIAS             show click statistics
+  tagstats TAG                           show click statistics of all links
+                                         with a tag
+
+TIME is in RFC 3339 format, e.g. 2025-03-01T09:00:00+02:00.
+PLATFORM is ios, android, windows, macos, linux, mobile or desktop, and
+COUNTRY a two-letter code such as DE. The first platform target matching a
+visitor is used, then the country target. Other visitors are split between
+the variants by weight and keep theirs on return, or go to URL if there are
+no variants. Each -param is added to the query of the redirect, and with
+-passthrough the query of the short URL as well; VALUE may contain {alias}
+and {country}. With -prefix, paths below the alias are appended to the
+destination: /docs/api goes to URL/api. With -interstitial, browsers see a
+warning page before leaving for an external domain. The -unfurl flags set
+what chat apps show when the link is shared, instead of what was fetched
+from its page. Update replaces each list given, and all -unfurl fields if
+one is given; -target "", -geo "", -variant "", -param "" and -tag ""
+remove them, and -folder "" unfiles the link. Tags are lowercased.
+HOST is one of the short domains of the server; without -domain, links
+are on its default domain. With -key, lsh acts for the workspace of the
+key and ALIAS is as the workspace calls it, without the "NAME~" prefix.
+
+Flags:
+`
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var errUsage = errors.New("invalid usage")
+
+type cli struct {
+	client *api.Client
+	output string
+	out    io.Writer
+}
+
+type command func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]command{
+	"create":   cmdCreate,
+	"get":      cmdGet,
+	"list":     cmdList,
+	"search":   cmdSearch,
+	"update":   cmdUpdate,
+	"delete":   cmdDelete,
+	"stats":    cmdStats,
+	"tagstats": cmdTagStats,
+}
+
+func main() {
+	fs := flag.NewFlagSet("lsh", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	server := fs.String("server", envOr("LSH_SERVER", "http://localhost:8087"), "server URL (env LSH_SERVER)")
+	user := fs.String("user", os.Getenv("LSH_USER"), "BasicAuth user (env LSH_USER)")
+	password := fs.String("password", os.Getenv("LSH_PASSWORD"), "BasicAuth password (env LSH_PASSWORD)")
+	key := fs.String("key", os.Getenv("LSH_API_KEY"), "API key of a workspace, used instead of BasicAuth (env LSH_API_KEY)")
+	output := fs.String("o", outputText, "output format: text or json")
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	_ = fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 || (*output != outputText && *output != outputJSON) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	c := &cli{
+		client: api.NewClient(*server, *user, *password),
+		output: *output,
+		out:    os.Stdout,
+	}
+	c.client.APIKey = *key
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	err := cmd(ctx, c, fs.Args()[1:])
+	cancel()
+
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lsh: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func cmdCreate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	alias := fs.String("alias", "", "alias (generated if empty)")
+	domain := fs.String("domain", "", "short domain of the link (default domain if empty)")
+	status := fs.Int("status", 0, "redirect status: 301, 302, 307 or 308 (server default if 0)")
+	password := fs.String("password", "", "password required to follow the link")
+	maxClicks := fs.Int64("max-clicks", 0, "number of redirects before the link expires (0 = unlimited)")
+	activeFrom := fs.String("active-from", "", "time from which the link redirects to URL")
+	activeUntil := fs.String("active-until", "", "time from which the link no longer redirects to URL")
+	fallback := fs.String("fallback", "", "URL to redirect to outside of the active time")
+	var targets targetsFlag
+	fs.Var(&targets, "target", "PLATFORM=URL destination for one platform (repeatable)")
+	var geoTargets geoTargetsFlag
+	fs.Var(&geoTargets, "geo", "COUNTRY=URL destination for one country (repeatable)")
+	var variants variantsFlag
+	fs.Var(&variants, "variant", "WEIGHT=URL destination for a share of the visitors (repeatable)")
+	var params paramsFlag
+	fs.Var(&params, "param", "KEY=VALUE query parameter added to redirects (repeatable)")
+	passthrough := fs.Bool("passthrough", false, "forward the query of the short URL to the destination")
+	prefix := fs.Bool("prefix", false, "also match paths below the alias and append them to the destination")
+	interstitial := fs.Bool("interstitial", false, "warn browsers before leaving for an external domain")
+	unfurlTitle := fs.String("unfurl-title", "", "title chat apps show for the link")
+	unfurlDescription := fs.String("unfurl-description", "", "description chat apps show for the link")
+	unfurlImage := fs.String("unfurl-image", "", "URL of the image chat apps show for the link")
+	folder := fs.String("folder", "", "folder to file the link in")
+	var tags tagsFlag
+	fs.Var(&tags, "tag", "tag of the link (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	req := api.CreateRequest{
+		URL:              fs.Arg(0),
+		Alias:            *alias,
+		Domain:           *domain,
+		RedirectStatus:   *status,
+		Password:         *password,
+		MaxClicks:        *maxClicks,
+		FallbackURL:      *fallback,
+		Targets:          targets,
+		GeoTargets:       geoTargets,
+		Destinations:     variants,
+		QueryParams:      params,
+		QueryPassthrough: *passthrough,
+		Prefix:           *prefix,
+		Interstitial:     *interstitial,
+		Folder:           *folder,
+		Tags:             tags,
+	}
+	if unfurl := (api.Unfurl{Title: *unfurlTitle, Description: *unfurlDescription, Image: *unfurlImage}); unfurl != (api.Unfurl{}) {
+		req.Unfurl = &unfurl
+	}
+	var err error
+	if req.ActiveFrom, err = parseTime("active-from", *activeFrom); err != nil {
+		return err
+	}
+	if req.ActiveUntil, err = parseTime("active-until", *activeUntil); err != nil {
+		return err
+	}
+
+	link, err := c.client.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		return c.printJSON(struct {
+			api.Link
+			ShortURL string `json:"short_url"`
+		}{link, c.client.ShortURLOn(link.Domain, link.Alias)})
+	}
+
+	_, err = fmt.Fprintln(c.out, c.client.ShortURLOn(link.Domain, link.Alias))
+	return err
+}
+
+func cmdGet(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	domain := fs.String("domain", "", "short domain of the link (default domain if empty)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	link, err := c.client.GetOn(ctx, *domain, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks(link)
+}
+
+func cmdList(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of links")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	title := fs.String("title", "", "only links whose page title contains this")
+	tag := fs.String("tag", "", "only links with this tag")
+	folder := fs.String("folder", "", "only links in this folder")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	links, err := c.client.List(ctx, api.ListOptions{Limit: *limit, Offset: *offset, Title: *title, Tag: *tag, Folder: *folder})
+	if err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		if links == nil {
+			links = []api.Link{}
+		}
+		return c.printJSON(links)
+	}
+	return c.printLinks(links...)
+}
+
+func cmdSearch(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of links (server default if 0)")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+
+	results, err := c.client.Search(ctx, api.SearchOptions{
+		Query:  strings.Join(fs.Args(), " "),
+		Limit:  *limit,
+		Offset: *offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		if results == nil {
+			results = []api.SearchResult{}
+		}
+		return c.printJSON(results)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tALIAS\tURL\tTITLE\tTAGS")
+	for _, res := range results {
+		link, h := res.Link, res.Highlights
+		alias := highlighted(h.Alias, link.Alias)
+		if link.Domain != "" {
+			alias = link.Domain + "/" + alias
+		}
+		tags := make([]string, len(h.Tags))
+		for i, tag := range h.Tags {
+			tags[i] = highlighted(tag, "")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", link.ID, alias,
+			highlighted(h.URL, link.URL), highlighted(h.Title, link.Title), strings.Join(tags, ","))
+	}
+	return tw.Flush()
+}
+
+// highlighted returns the highlight of a search result field for the
+// terminal, with the matches in brackets, or plain if the field did not
+// match.
+func highlighted(highlight, plain string) string {
+	if highlight == "" {
+		return plain
+	}
+	highlight = strings.NewReplacer("<mark>", "[", "</mark>", "]").Replace(highlight)
+	return html.UnescapeString(highlight)
+}
+
+func cmdUpdate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	newURL := fs.String("url", "", "new destination URL")
+	newAlias := fs.String("alias", "", "new alias")
+	newStatus := fs.Int("status", 0, "new redirect status (0 for the server default)")
+	newPassword := fs.String("password", "", "new password (empty to remove it)")
+	newMaxClicks := fs.Int64("max-clicks", 0, "new click limit (0 = unlimited)")
+	newActiveFrom := fs.String("active-from", "", "new start of the active time (empty to remove it)")
+	newActiveUntil := fs.String("active-until", "", "new end of the active time (empty to remove it)")
+	newFallback := fs.String("fallback", "", "new fallback URL (empty to remove it)")
+	var newTargets targetsFlag
+	fs.Var(&newTargets, "target", "PLATFORM=URL destination for one platform (repeatable, replaces all)")
+	var newGeoTargets geoTargetsFlag
+	fs.Var(&newGeoTargets, "geo", "COUNTRY=URL destination for one country (repeatable, replaces all)")
+	var newVariants variantsFlag
+	fs.Var(&newVariants, "variant", "WEIGHT=URL destination for a share of the visitors (repeatable, replaces all)")
+	var newParams paramsFlag
+	fs.Var(&newParams, "param", "KEY=VALUE query parameter added to redirects (repeatable, replaces all)")
+	newPassthrough := fs.Bool("passthrough", false, "whether to forward the query of the short URL")
+	newPrefix := fs.Bool("prefix", false, "whether to match paths below the alias")
+	newInterstitial := fs.Bool("interstitial", false, "whether to warn before leaving for an external domain")
+	newUnfurl := api.Unfurl{}
+	fs.StringVar(&newUnfurl.Title, "unfurl-title", "", "new title chat apps show (replaces all unfurl fields)")
+	fs.StringVar(&newUnfurl.Description, "unfurl-description", "", "new description chat apps show (replaces all unfurl fields)")
+	fs.StringVar(&newUnfurl.Image, "unfurl-image", "", "new image URL chat apps show (replaces all unfurl fields)")
+	newFolder := fs.String("folder", "", "new folder (empty to unfile the link)")
+	var newTags tagsFlag
+	fs.Var(&newTags, "tag", "tag of the link (repeatable, replaces all)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return errUsage
+	}
+
+	var req api.UpdateRequest
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			req.URL = newURL
+		case "alias":
+			req.Alias = newAlias
+		case "status":
+			req.RedirectStatus = newStatus
+		case "password":
+			req.Password = newPassword
+		case "max-clicks":
+			req.MaxClicks = newMaxClicks
+		case "active-from":
+			req.ActiveFrom = newActiveFrom
+		case "active-until":
+			req.ActiveUntil = newActiveUntil
+		case "fallback":
+			req.FallbackURL = newFallback
+		case "target":
+			// Non-nil, so that no targets are sent as [] rather than null.
+			targets := append([]api.Target{}, newTargets...)
+			req.Targets = &targets
+		case "geo":
+			geoTargets := append([]api.GeoTarget{}, newGeoTargets...)
+			req.GeoTargets = &geoTargets
+		case "variant":
+			destinations := append([]api.Destination{}, newVariants...)
+			req.Destinations = &destinations
+		case "param":
+			params := append([]api.QueryParam{}, newParams...)
+			req.QueryParams = &params
+		case "passthrough":
+			req.QueryPassthrough = newPassthrough
+		case "prefix":
+			req.Prefix = newPrefix
+		case "interstitial":
+			req.Interstitial = newInterstitial
+		case "unfurl-title", "unfurl-description", "unfurl-image":
+			req.Unfurl = &newUnfurl
+		case "folder":
+			req.Folder = newFolder
+		case "tag":
+			tags := append([]string{}, newTags...)
+			req.Tags = &tags
+		}
+	})
+
+	link, err := c.client.Update(ctx, id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks(link)
+}
+
+func cmdDelete(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errUsage
+	}
+
+	if err := c.client.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		return c.printJSON(struct {
+			ID      int64 `json:"id"`
+			Deleted bool  `json:"deleted"`
+		}{id, true})
+	}
+
+	_, err = fmt.Fprintf(c.out, "deleted %d\n", id)
+	return err
+}
+
+func cmdStats(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	domain := fs.String("domain", "", "short domain of the link (default domain if empty)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	stats, err := c.client.StatsOn(ctx, *domain, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		return c.printJSON(stats)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "clicks\t%d\n", stats.Clicks)
+	if stats.LastClickAt != nil {
+		fmt.Fprintf(tw, "last click\t%s\n", stats.LastClickAt.Format(time.RFC3339))
+	}
+	for _, day := range stats.Daily {
+		fmt.Fprintf(tw, "%s\t%d\n", day.Date, day.Clicks)
+	}
+	for _, v := range stats.Variants {
+		fmt.Fprintf(tw, "%s\t%d\n", v.URL, v.Clicks)
+	}
+	for _, c := range stats.Countries {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Country, c.Clicks)
+	}
+	return tw.Flush()
+}
+
+func cmdTagStats(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	stats, err := c.client.TagStats(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if c.output == outputJSON {
+		return c.printJSON(stats)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "links\t%d\n", stats.Links)
+	fmt.Fprintf(tw, "clicks\t%d\n", stats.Clicks)
+	if stats.LastClickAt != nil {
+		fmt.Fprintf(tw, "last click\t%s\n", stats.LastClickAt.Format(time.RFC3339))
+	}
+	for _, day := range stats.Daily {
+		fmt.Fprintf(tw, "%s\t%d\n", day.Date, day.Clicks)
+	}
+	for _, c := range stats.Countries {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Country, c.Clicks)
+	}
+	return tw.Flush()
+}
+
+// printLinks writes links as a table, or a single link as a JSON object.
+func (c *cli) printLinks(links ...api.Link) error {
+	if c.output == outputJSON {
+		return c.printJSON(links[0])
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tALIAS\tURL")
+	for _, link := range links {
+		alias := link.Alias
+		if link.Domain != "" {
+			alias = link.Domain + "/" + alias
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", link.ID, alias, link.URL)
+	}
+	return tw.Flush()
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// targetsFlag collects repeated -target PLATFORM=URL flags. An empty value
+// adds nothing, so that update can be told to remove all targets.
+type targetsFlag []api.Target
+
+func (f *targetsFlag) String() string { return "" }
+
+func (f *targetsFlag) Set(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	platform, url, ok := strings.Cut(value, "=")
+	if !ok || platform == "" {
+		return errors.New("want PLATFORM=URL")
+	}
+	*f = append(*f, api.Target{Platform: platform, URL: url})
+	return nil
+}
+
+// geoTargetsFlag collects repeated -geo COUNTRY=URL flags. Like
+// targetsFlag, it ignores empty values.
+type geoTargetsFlag []api.GeoTarget
+
+func (f *geoTargetsFlag) String() string { return "" }
+
+func (f *geoTargetsFlag) Set(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	country, url, ok := strings.Cut(value, "=")
+	if !ok || country == "" {
+		return errors.New("want COUNTRY=URL")
+	}
+	*f = append(*f, api.GeoTarget{Country: strings.ToUpper(country), URL: url})
+	return nil
+}
+
+// paramsFlag collects repeated -param KEY=VALUE flags. Like targetsFlag, it
+// ignores empty values.
+type paramsFlag []api.QueryParam
+
+func (f *paramsFlag) String() string { return "" }
+
+func (f *paramsFlag) Set(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	key, v, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("want KEY=VALUE")
+	}
+	*f = append(*f, api.QueryParam{Key: key, Value: v})
+	return nil
+}
+
+// variantsFlag collects repeated -variant WEIGHT=URL flags. Like
+// targetsFlag, it ignores empty values.
+type variantsFlag []api.Destination
+
+func (f *variantsFlag) String() string { return "" }
+
+func (f *variantsFlag) Set(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	weight, url, ok := strings.Cut(value, "=")
+	if !ok {
+		return errors.New("want WEIGHT=URL")
+	}
+	w, err := strconv.Atoi(weight)
+	if err != nil {
+		return errors.New("want WEIGHT=URL")
+	}
+	*f = append(*f, api.Destination{URL: url, Weight: w})
+	return nil
+}
+
+// tagsFlag collects repeated -tag flags. Like targetsFlag, it ignores empty
+// values.
+type tagsFlag []string
+
+func (f *tagsFlag) String() string { return "" }
+
+func (f *tagsFlag) Set(value string) error {
+	if value != "" {
+		*f = append(*f, value)
+	}
+	return nil
+}
+
+// parseTime parses the RFC 3339 value of the named flag, if it is set.
+func parseTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s: %w", name, err)
+	}
+	return &t, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,305 @@
+// Package backup takes timestamped snapshots of the storage database,
+// prunes old ones and restores a snapshot in place of the live database.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage/sqlite"
+)
+
+// timeLayout is embedded in snapshot file names. It sorts lexically in
+// chronological order.
+const timeLayout = "20060102T150405.000Z"
+
+const snapshotExt = ".db"
+
+var (
+	ErrNoSnapshots = errors.New("no snapshots")
+	ErrInUse       = errors.New("database has a pending journal, stop the server first")
+)
+
+// Snapshotter writes a consistent copy of the live database to path.
+type Snapshotter interface {
+	Backup(path string) error
+}
+
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager creates snapshots in a directory and keeps at most retention of
+// them, deleting the oldest first.
+type Manager struct {
+	log       *slog.Logger
+	source    Snapshotter
+	dir       string
+	prefix    string
+	retention int
+
+	mu sync.Mutex
+}
+
+// New returns a Manager writing snapshots of source into dir. Snapshot names
+// start with the base name of storagePath. retention <= 0 keeps everything.
+func New(log *slog.Logger, source Snapshotter, storagePath, dir string, retention int) *Manager {
+	base := filepath.Base(storagePath)
+
+	return &Manager{
+		log:       log,
+		source:    source,
+		dir:       dir,
+		prefix:    strings.TrimSuffix(base, filepath.Ext(base)) + "-",
+		retention: retention,
+	}
+}
+
+// Create takes a snapshot now and applies retention.
+func (m *Manager) Create() (Snapshot, error) {
+	const op = "backup.Manager.Create"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	name := m.prefix + now.Format(timeLayout) + snapshotExt
+	path := filepath.Join(m.dir, name)
+
+	// Write under a temporary name so a failed or interrupted backup is never
+	// mistaken for a snapshot.
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+
+	if err := m.source.Backup(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.prune(); err != nil {
+		m.log.Error("failed to prune snapshots", slog.String("op", op), sl.Err(err))
+	}
+
+	return Snapshot{
+		Name:      name,
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: now.Truncate(time.Millisecond),
+	}, nil
+}
+
+// List returns the snapshots in the directory, oldest first.
+func (m *Manager) List() ([]Snapshot, error) {
+	const op = "backup.Manager.List"
+
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, m.prefix) || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, m.prefix), snapshotExt)
+		createdAt, err := time.Parse(timeLayout, stamp)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		snapshots = append(snapshots, Snapshot{
+			Name:      name,
+			Path:      filepath.Join(m.dir, name),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// Latest returns the most recent snapshot or ErrNoSnapshots.
+func (m *Manager) Latest() (Snapshot, error) {
+	snapshots, err := m.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return Snapshot{}, ErrNoSnapshots
+	}
+
+	return snapshots[len(snapshots)-1], nil
+}
+
+// Run takes a snapshot every interval until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	const op = "backup.Manager.Run"
+
+	log := m.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := m.Create()
+			if err != nil {
+				log.Error("scheduled backup failed", sl.Err(err))
+				continue
+			}
+			log.Info("scheduled backup created",
+				slog.String("path", snapshot.Path),
+				slog.Int64("size", snapshot.Size),
+			)
+		}
+	}
+}
+
+func (m *Manager) prune() error {
+	if m.retention <= 0 {
+		return nil
+	}
+
+	snapshots, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for len(snapshots) > m.retention {
+		if err := os.Remove(snapshots[0].Path); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+
+	return nil
+}
+
+// Restore validates the snapshot at snapshotPath and atomically replaces the
+// database at storagePath with a copy of it, migrated to the current schema
+// if the snapshot is older. The replaced database is kept
+// next to it and its path returned, so a bad restore can be undone. Nothing
+// may have storagePath open while it runs.
+func Restore(snapshotPath, storagePath string) (previous string, err error) {
+	const op = "backup.Restore"
+
+	if err := sqlite.ValidateSnapshot(snapshotPath); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A leftover journal would be replayed into the restored file.
+	for _, suffix := range []string{"-journal", "-wal"} {
+		if _, err := os.Stat(storagePath + suffix); err == nil {
+			return "", fmt.Errorf("%s: %w", op, ErrInUse)
+		}
+	}
+
+	// Copy next to the target first so the final rename stays on one
+	// filesystem and is atomic.
+	tmp := storagePath + ".restore"
+	if err := copyFile(snapshotPath, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Migrating the copy before it goes live means a migration that fails
+	// leaves the current database in place.
+	if err := migrateFile(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("%s: migrate snapshot: %w", op, err)
+	}
+
+	if _, err := os.Stat(storagePath); err == nil {
+		previous = storagePath + ".pre-restore-" + time.Now().UTC().Format(timeLayout)
+		if err := os.Link(storagePath, previous); err != nil {
+			if err := copyFile(storagePath, previous); err != nil {
+				_ = os.Remove(tmp)
+				return "", fmt.Errorf("%s: keep previous database: %w", op, err)
+			}
+		}
+	}
+
+	if err := os.Rename(tmp, storagePath); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return previous, nil
+}
+
+// migrateFile brings the database at path up to the current schema.
+func migrateFile(path string) error {
+	s, err := sqlite.New(path)
+	if err != nil {
+		return err
+	}
+	return s.DB.Close()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,165 @@
+package backup_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/backup"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestCreateAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	storagePath := filepath.Join(dir, "storage.db")
+
+	s, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "g")
+	require.NoError(t, err)
+
+	m := backup.New(slogdiscard.NewDiscardLogger(), s, storagePath, filepath.Join(dir, "backups"), 2)
+
+	first, err := m.Create()
+	require.NoError(t, err)
+	require.NoError(t, sqlite.ValidateSnapshot(first.Path))
+
+	_, err = s.SaveURL("https://example.com", "e")
+	require.NoError(t, err)
+
+	_, err = m.Create()
+	require.NoError(t, err)
+
+	latest, err := m.Latest()
+	require.NoError(t, err)
+	require.NotEqual(t, first.Name, latest.Name)
+
+	require.NoError(t, s.DB.Close())
+
+	previous, err := backup.Restore(first.Path, storagePath)
+	require.NoError(t, err)
+	require.FileExists(t, previous)
+
+	restored, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+	defer func() { _ = restored.DB.Close() }()
+
+	_, err = restored.GetLink("", "g")
+	require.NoError(t, err)
+	_, err = restored.GetLink("", "e")
+	require.Error(t, err, "links created after the snapshot are gone")
+}
+
+func TestRestoreRejectsInvalidSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	storagePath := filepath.Join(dir, "storage.db")
+	require.NoError(t, os.WriteFile(storagePath, []byte("live"), 0o644))
+
+	garbage := filepath.Join(dir, "garbage.db")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0o644))
+
+	_, err := backup.Restore(garbage, storagePath)
+	require.Error(t, err)
+
+	otherPath := filepath.Join(dir, "other.db")
+	other, err := sql.Open("sqlite", otherPath)
+	require.NoError(t, err)
+	_, err = other.Exec("CREATE TABLE links(id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	_, err = backup.Restore(otherPath, storagePath)
+	require.ErrorContains(t, err, "missing column alias")
+
+	newerPath := filepath.Join(dir, "newer.db")
+	newer, err := sqlite.New(newerPath)
+	require.NoError(t, err)
+	_, err = newer.DB.Exec("PRAGMA user_version = 1000")
+	require.NoError(t, err)
+	require.NoError(t, newer.DB.Close())
+
+	_, err = backup.Restore(newerPath, storagePath)
+	require.ErrorContains(t, err, "newer than supported")
+
+	unindexedPath := filepath.Join(dir, "unindexed.db")
+	unindexed, err := sqlite.New(unindexedPath)
+	require.NoError(t, err)
+	_, err = unindexed.DB.Exec("DROP TABLE links_fts")
+	require.NoError(t, err)
+	require.NoError(t, unindexed.DB.Close())
+
+	_, err = backup.Restore(unindexedPath, storagePath)
+	require.ErrorContains(t, err, "missing table links_fts")
+
+	live, err := os.ReadFile(storagePath)
+	require.NoError(t, err)
+	require.Equal(t, "live", string(live), "the live database is untouched")
+}
+
+func TestRestoreMigratesOlderSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	storagePath := filepath.Join(dir, "storage.db")
+
+	// A snapshot from before any migration.
+	oldPath := filepath.Join(dir, "old.db")
+	old, err := sql.Open("sqlite", oldPath)
+	require.NoError(t, err)
+	_, err = old.Exec(`
+		CREATE TABLE links (id INTEGER PRIMARY KEY, alias TEXT NOT NULL UNIQUE, url TEXT NOT NULL);
+		CREATE TABLE clicks (
+		    id INTEGER PRIMARY KEY,
+		    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+		    clicked_at DATETIME NOT NULL,
+		    referer TEXT NOT NULL DEFAULT '',
+		    user_agent TEXT NOT NULL DEFAULT ''
+		);
+		INSERT INTO links (alias, url) VALUES ('g', 'https://google.com');`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	require.NoError(t, sqlite.ValidateSnapshot(oldPath))
+
+	_, err = backup.Restore(oldPath, storagePath)
+	require.NoError(t, err)
+
+	// The restored file is migrated before anything opens it as storage.
+	restored, err := sql.Open("sqlite", storagePath)
+	require.NoError(t, err)
+	defer func() { _ = restored.Close() }()
+
+	var found int
+	require.NoError(t, restored.QueryRow("SELECT COUNT(*) FROM links_fts WHERE links_fts MATCH 'google'").Scan(&found))
+	require.Equal(t, 1, found)
+	require.NoError(t, sqlite.ValidateSnapshot(storagePath))
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	storagePath := filepath.Join(dir, "storage.db")
+
+	s, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	m := backup.New(slogdiscard.NewDiscardLogger(), s, storagePath, filepath.Join(dir, "backups"), 2)
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		snapshot, err := m.Create()
+		require.NoError(t, err)
+		names = append(names, snapshot.Name)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	snapshots, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, names[2], snapshots[0].Name)
+	require.Equal(t, names[3], snapshots[1].Name)
+}
@@ -11,6 +11,17 @@ type Config struct {
 	Env         string `yaml:"env" env:"ENV" env-default:"production"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 	HTTPServer  `yaml:"http_server"`
+	Backup      Backup    `yaml:"backup"`
+	Redirect    Redirect  `yaml:"redirect"`
+	GeoIP       GeoIP     `yaml:"geoip"`
+	URLPolicy   URLPolicy `yaml:"url_policy"`
+	// RedirectChains is applied to the destinations of links created, alone
+	// or in batches, and of links changed. Imports are stored as they are.
+	RedirectChains RedirectChains `yaml:"redirect_chains"`
+	HealthCheck    HealthCheck    `yaml:"health_check"`
+	Metadata       Metadata       `yaml:"metadata"`
+	// Domains are short domains served besides the default one.
+	Domains []Domain `yaml:"domains"`
 }
 
 type HTTPServer struct {
@@ -19,6 +30,113 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 	User        string        `yaml:"user" env-required:"true"`
 	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	// PublicURL is where short links are served, e.g. https://sho.rt. If
+	// it is empty, the host each request was sent to is used.
+	PublicURL string `yaml:"public_url" env:"PUBLIC_URL"`
+}
+
+// Backup configures database snapshots. Scheduled backups are disabled when
+// Interval is zero; Retention is the number of snapshots to keep (0 keeps all).
+// Timeout replaces the write timeout of the server for snapshots taken
+// through the API.
+type Backup struct {
+	Dir       string        `yaml:"dir" env-default:"./backups"`
+	Interval  time.Duration `yaml:"interval" env-default:"0"`
+	Retention int           `yaml:"retention" env-default:"7"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"5m"`
+}
+
+// Redirect configures how short links redirect. DefaultStatus (301, 302, 307
+// or 308) applies to links that do not set their own. A protected link
+// refuses further password attempts once PasswordAttempts wrong ones were
+// made within PasswordWindow. With Interstitial, browsers are warned before
+// leaving for any domain other than InternalDomains (and their subdomains);
+// otherwise only links that ask for it warn. With Unfurl, the crawlers of
+// chat apps get a page with the title, description and image of a link
+// instead of the redirect. RootURL and NotFoundPage are what the default
+// domain serves for / and for missing aliases, see Domain.
+type Redirect struct {
+	DefaultStatus    int           `yaml:"default_status" env-default:"302"`
+	PermanentMaxAge  time.Duration `yaml:"permanent_max_age" env-default:"24h"`
+	PasswordAttempts int           `yaml:"password_attempts" env-default:"5"`
+	PasswordWindow   time.Duration `yaml:"password_window" env-default:"15m"`
+	Interstitial     bool          `yaml:"interstitial"`
+	InternalDomains  []string      `yaml:"internal_domains"`
+	Unfurl           bool          `yaml:"unfurl" env-default:"true"`
+	RootURL          string        `yaml:"root_url"`
+	NotFoundPage     string        `yaml:"not_found_page"`
+}
+
+// Domain is a branded short domain. Its links have aliases of their own,
+// which may repeat those of other domains. Requests for / redirect to
+// RootURL if it is set, and browsers asking for a missing alias get the
+// HTML file NotFoundPage if it is set. A domain of a Workspace is its own:
+// only that workspace creates links on it, with aliases outside of its
+// namespace.
+type Domain struct {
+	Host         string `yaml:"host"`
+	RootURL      string `yaml:"root_url"`
+	NotFoundPage string `yaml:"not_found_page"`
+	Workspace    string `yaml:"workspace"`
+}
+
+// URLPolicy restricts the destinations of links. Only Schemes (http and
+// https if empty) are allowed, and with AllowPrivate unset no loopback,
+// private or link-local hosts. DomainsFile, if set, has "allow DOMAIN" and
+// "deny DOMAIN" lines and is reread every ReloadInterval. The host of
+// HTTPServer.PublicURL is always refused, as links to it would loop.
+type URLPolicy struct {
+	Schemes        []string      `yaml:"schemes"`
+	AllowPrivate   bool          `yaml:"allow_private"`
+	DomainsFile    string        `yaml:"domains_file" env:"URL_POLICY_DOMAINS_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"30s"`
+}
+
+// RedirectChains configures what happens to destinations that redirect
+// further. Mode is "off", "reject" to refuse loops and chains of more than
+// MaxHops redirects, or "flatten" to refuse those too and save the end of
+// shorter chains instead. Every hop must pass the URL policy. Timeout
+// bounds the whole chain, and the chains of all links of a batch together;
+// keep it below HTTPServer.Timeout.
+type RedirectChains struct {
+	Mode    string        `yaml:"mode" env-default:"off"`
+	MaxHops int           `yaml:"max_hops" env-default:"5"`
+	Timeout time.Duration `yaml:"timeout" env-default:"3s"`
+}
+
+// HealthCheck configures the periodic checks of link destinations. Every
+// Interval (never if zero) each destination is requested, by at most
+// Concurrency checks at once and with HostDelay between requests to the
+// same host. A link is reported broken once BrokenAfter checks in a row
+// have failed. Private addresses are refused unless URLPolicy allows them.
+type HealthCheck struct {
+	Interval    time.Duration `yaml:"interval" env-default:"0s"`
+	Concurrency int           `yaml:"concurrency" env-default:"4"`
+	HostDelay   time.Duration `yaml:"host_delay" env-default:"1s"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+	BrokenAfter int           `yaml:"broken_after" env-default:"3"`
+}
+
+// Metadata configures fetching the title, description and image of link
+// destinations after links are saved. Workers pages are fetched at once and
+// up to QueueSize links wait their turn; each fetch takes at most Timeout and
+// reads at most MaxBytes of the page. Private addresses are refused unless
+// URLPolicy allows them.
+type Metadata struct {
+	Enabled   bool          `yaml:"enabled" env-default:"true"`
+	Workers   int           `yaml:"workers" env-default:"2"`
+	QueueSize int           `yaml:"queue_size" env-default:"1000"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBytes  int64         `yaml:"max_bytes" env-default:"524288"`
+}
+
+// GeoIP configures country lookups of visitors. Database is the path of a
+// MaxMind Country or City database; without one, visitors have no country.
+// Requests from TrustedProxies (IP addresses or CIDR ranges) are attributed
+// to the client they name in X-Forwarded-For.
+type GeoIP struct {
+	Database       string   `yaml:"database" env:"GEOIP_DATABASE"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 func MustLoadConfig() *Config {
@@ -0,0 +1,322 @@
+// Package healthcheck requests the destinations of all links on a schedule
+// and records whether they still work, so that broken links can be found
+// before visitors do.
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/urlpolicy"
+	"link-shortener/internal/storage"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultHostDelay   = time.Second
+	defaultTimeout     = 10 * time.Second
+	defaultBrokenAfter = 3
+	userAgent          = "link-shortener (health check)"
+)
+
+// Store lists the links to check and records the outcome.
+type Store interface {
+	ForEachLink(fn func(link storage.Link) error) error
+	RecordHealth(check storage.HealthCheck, brokenAfter int) error
+}
+
+// Config configures a Checker. Zero values mean the defaults.
+type Config struct {
+	// Concurrency is how many checks run at once; 4 by default.
+	Concurrency int
+	// HostDelay is the least time between two requests to the same host;
+	// 1 second by default.
+	HostDelay time.Duration
+	// Timeout bounds each check, redirects included; 10 seconds by
+	// default.
+	Timeout time.Duration
+	// BrokenAfter is how many checks in a row must fail for a link to be
+	// broken; 3 by default.
+	BrokenAfter int
+	// AllowPrivate lets checks connect to private addresses.
+	AllowPrivate bool
+}
+
+// Checker checks the destinations of links.
+type Checker struct {
+	log         *slog.Logger
+	store       Store
+	client      *http.Client
+	concurrency int
+	timeout     time.Duration
+	brokenAfter int
+	hostDelay   time.Duration
+}
+
+// Summary counts the checks of a round.
+type Summary struct {
+	Checked int
+	Failed  int
+}
+
+// New returns a Checker of the links in store.
+func New(log *slog.Logger, store Store, cfg Config) *Checker {
+	c := &Checker{
+		log:         log,
+		store:       store,
+		concurrency: cfg.Concurrency,
+		timeout:     cfg.Timeout,
+		brokenAfter: cfg.BrokenAfter,
+	}
+	if c.concurrency <= 0 {
+		c.concurrency = defaultConcurrency
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.brokenAfter <= 0 {
+		c.brokenAfter = defaultBrokenAfter
+	}
+	c.hostDelay = cfg.HostDelay
+	if c.hostDelay <= 0 {
+		c.hostDelay = defaultHostDelay
+	}
+
+	dialer := &net.Dialer{Timeout: c.timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = urlpolicy.RefusePrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	transport.MaxIdleConnsPerHost = 1
+
+	c.client = &http.Client{Transport: transport}
+
+	return c
+}
+
+// CheckAll checks every link once and records the outcomes. It returns
+// early if ctx is done.
+func (c *Checker) CheckAll(ctx context.Context) (Summary, error) {
+	const op = "healthcheck.Checker.CheckAll"
+
+	log := c.log.With(slog.String("op", op))
+
+	// The links are collected first so that no query is open while the
+	// outcomes are written.
+	var targets []storage.Link
+	err := c.store.ForEachLink(func(link storage.Link) error {
+		if checkable(link.URL) {
+			targets = append(targets, storage.Link{ID: link.ID, URL: link.URL})
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	gate := &hostGate{delay: c.hostDelay, next: make(map[string]time.Time)}
+	jobs := make(chan storage.Link)
+	var (
+		wg              sync.WaitGroup
+		checked, failed atomic.Int64
+	)
+
+	for range c.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for link := range jobs {
+				check := c.check(ctx, gate, link.URL)
+				if ctx.Err() != nil {
+					// Cut short by shutdown, not by the destination.
+					return
+				}
+				check.LinkID = link.ID
+
+				checked.Add(1)
+				if !check.OK {
+					failed.Add(1)
+				}
+
+				if err := c.store.RecordHealth(check, c.brokenAfter); err != nil {
+					log.Error("failed to record health", slog.Int64("link_id", link.ID), sl.Err(err))
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, link := range targets {
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return Summary{Checked: int(checked.Load()), Failed: int(failed.Load())}, ctx.Err()
+}
+
+// Check requests rawURL, following redirects. HEAD is tried first and GET
+// if HEAD fails, as some servers do not answer it like they answer GET.
+func (c *Checker) Check(ctx context.Context, rawURL string) storage.HealthCheck {
+	return c.check(ctx, nil, rawURL)
+}
+
+// check is Check after waiting for the turn of the host at gate, if it is
+// not nil. The wait does not count against the timeout.
+func (c *Checker) check(ctx context.Context, gate *hostGate, rawURL string) storage.HealthCheck {
+	check := storage.HealthCheck{URL: rawURL}
+
+	var (
+		status int
+		err    error
+	)
+	if gate != nil {
+		err = gate.wait(ctx, hostname(rawURL))
+	}
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		status, err = c.do(ctx, http.MethodHead, rawURL, &check)
+		if err == nil && status >= http.StatusBadRequest {
+			status, err = c.do(ctx, http.MethodGet, rawURL, &check)
+		}
+	}
+
+	if err != nil {
+		// The url.Error around it repeats the method and the URL.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		check.Error = err.Error()
+		return check
+	}
+
+	check.StatusCode = status
+	check.OK = status < http.StatusBadRequest
+	return check
+}
+
+// Run checks all links now and then every interval, until ctx is done.
+// A round that is still running when the next one is due delays it.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	const op = "healthcheck.Checker.Run"
+
+	log := c.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		summary, err := c.CheckAll(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error("health check failed", sl.Err(err))
+		} else {
+			log.Info("health check done",
+				slog.Int("checked", summary.Checked),
+				slog.Int("failed", summary.Failed),
+				slog.Duration("duration", time.Since(start)),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// do sends one request to rawURL and returns the status of the response
+// redirects end at. It sets the time and latency of check.
+func (c *Checker) do(ctx context.Context, method, rawURL string, check *storage.HealthCheck) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	check.At, check.Latency = start.UTC(), time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	// Only the status matters.
+	_ = resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// hostname returns the host of rawURL, or rawURL if it does not parse.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// checkable reports whether rawURL can be requested over HTTP.
+func checkable(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return (scheme == "http" || scheme == "https") && u.Host != ""
+}
+
+// hostGate spaces out requests to the same host during a round.
+type hostGate struct {
+	delay time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait blocks until a request may be sent to host, and books the slot
+// after it.
+func (g *hostGate) wait(ctx context.Context, host string) error {
+	g.mu.Lock()
+	now := time.Now()
+	at := g.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	g.next[host] = at.Add(g.delay)
+	g.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
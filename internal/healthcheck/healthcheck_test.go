@@ -0,0 +1,198 @@
+package healthcheck_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/healthcheck"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/urlpolicy"
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newChecker(store healthcheck.Store, cfg healthcheck.Config) *healthcheck.Checker {
+	// httptest servers listen on loopback.
+	cfg.AllowPrivate = true
+	return healthcheck.New(slogdiscard.NewDiscardLogger(), store, cfg)
+}
+
+func TestCheck(t *testing.T) {
+	srv := newServer(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c := newChecker(nil, healthcheck.Config{})
+
+	cases := []struct {
+		path       string
+		url        string
+		wantStatus int
+		wantOK     bool
+	}{
+		{path: "/ok", wantStatus: http.StatusOK, wantOK: true},
+		{path: "/moved", wantStatus: http.StatusOK, wantOK: true},
+		{path: "/no-head", wantStatus: http.StatusOK, wantOK: true},
+		{path: "/gone", wantStatus: http.StatusNotFound},
+		{path: "/error", wantStatus: http.StatusInternalServerError},
+		{path: "down", url: down.URL},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			url := tc.url
+			if url == "" {
+				url = srv.URL + tc.path
+			}
+
+			check := c.Check(context.Background(), url)
+			assert.Equal(t, url, check.URL)
+			assert.Equal(t, tc.wantStatus, check.StatusCode)
+			assert.Equal(t, tc.wantOK, check.OK)
+			assert.False(t, check.At.IsZero())
+			if tc.wantStatus == 0 {
+				assert.Contains(t, check.Error, "connection refused")
+			} else {
+				assert.Empty(t, check.Error)
+			}
+		})
+	}
+}
+
+func TestCheckRefusesPrivate(t *testing.T) {
+	srv := newServer(t)
+
+	check := healthcheck.New(slogdiscard.NewDiscardLogger(), nil, healthcheck.Config{}).
+		Check(context.Background(), srv.URL+"/ok")
+	assert.False(t, check.OK)
+	assert.Contains(t, check.Error, urlpolicy.ErrPrivate.Error())
+}
+
+func TestCheckAll(t *testing.T) {
+	srv := newServer(t)
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	for alias, url := range map[string]string{
+		"ok":    srv.URL + "/ok",
+		"gone":  srv.URL + "/gone",
+		"mail":  "mailto:team@example.com",
+		"moved": srv.URL + "/moved",
+	} {
+		_, err := s.SaveURL(url, alias)
+		require.NoError(t, err)
+	}
+
+	c := newChecker(s, healthcheck.Config{HostDelay: time.Millisecond, BrokenAfter: 2})
+
+	summary, err := c.CheckAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, healthcheck.Summary{Checked: 3, Failed: 1}, summary)
+
+	report, err := s.HealthReport(storage.HealthOptions{BrokenOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Checked)
+	assert.Equal(t, int64(1), report.Failing)
+	assert.Empty(t, report.Links, "one failure is not broken yet")
+
+	_, err = c.CheckAll(context.Background())
+	require.NoError(t, err)
+
+	report, err = s.HealthReport(storage.HealthOptions{BrokenOnly: true})
+	require.NoError(t, err)
+	require.Len(t, report.Links, 1)
+	assert.Equal(t, "gone", report.Links[0].Alias)
+	assert.Equal(t, http.StatusNotFound, report.Links[0].StatusCode)
+	assert.Equal(t, 2, report.Links[0].Failures)
+	assert.NotNil(t, report.Links[0].BrokenSince)
+}
+
+func TestCheckAllHostDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	for _, alias := range []string{"a", "b", "c", "d"} {
+		_, err := s.SaveURL(srv.URL+"/"+alias, alias)
+		require.NoError(t, err)
+	}
+
+	c := newChecker(s, healthcheck.Config{Concurrency: 4, HostDelay: delay})
+	_, err = c.CheckAll(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, times, 4)
+	for i := 1; i < len(times); i++ {
+		// A little slack for the timer.
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), delay-5*time.Millisecond)
+	}
+}
+
+func TestCheckAllCancelled(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	_, err = s.SaveURL("https://example.com", "e")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = newChecker(s, healthcheck.Config{}).CheckAll(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	report, err := s.HealthReport(storage.HealthOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, report.Checked)
+}
@@ -0,0 +1,58 @@
+package backup
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/backup"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Response struct {
+	response.Response
+	backup.Snapshot
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=SnapshotCreator
+type SnapshotCreator interface {
+	Create() (backup.Snapshot, error)
+}
+
+// New takes a database snapshot immediately, in addition to the scheduled
+// ones, and returns where it was written. Snapshots of large databases
+// outlast the write timeout of the server, so the response may instead be
+// written for up to timeout, if it is positive.
+func New(log *slog.Logger, creator SnapshotCreator, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.backup.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		if timeout > 0 {
+			err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+			if err != nil {
+				log.Warn("failed to extend write deadline", sl.Err(err))
+			}
+		}
+
+		snapshot, err := creator.Create()
+		if err != nil {
+			log.Error("failed to create backup", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to create backup"))
+			return
+		}
+
+		log.Info("backup created", slog.String("path", snapshot.Path), slog.Int64("size", snapshot.Size))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Snapshot: snapshot,
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package backup_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/backup"
+	handler "link-shortener/internal/http-server/handlers/admin/backup"
+	"link-shortener/internal/http-server/handlers/admin/backup/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestBackupHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		snapshot  backup.Snapshot
+		mockError error
+		respError string
+	}{
+		{
+			name:     "Success",
+			snapshot: backup.Snapshot{Name: "storage-20250101T000000.000Z.db", Size: 8192},
+		},
+		{
+			name:      "Backup Error",
+			mockError: errors.New("disk full"),
+			respError: "failed to create backup",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			creatorMock := mocks.NewSnapshotCreator(t)
+			creatorMock.On("Create").Return(tc.snapshot, tc.mockError).Once()
+
+			h := handler.New(slogdiscard.NewDiscardLogger(), creatorMock, 0)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/backup", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp handler.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.snapshot.Name, resp.Name)
+		})
+	}
+}
+
+func TestBackupHandlerOutlastsWriteTimeout(t *testing.T) {
+	snapshot := backup.Snapshot{Name: "storage-20250101T000000.000Z.db", Size: 8192}
+
+	creatorMock := mocks.NewSnapshotCreator(t)
+	creatorMock.On("Create").Return(snapshot, nil).After(300 * time.Millisecond).Once()
+
+	srv := httptest.NewUnstartedServer(handler.New(slogdiscard.NewDiscardLogger(), creatorMock, time.Minute))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/admin/backup", "", nil)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+
+	var resp handler.Response
+
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+	require.Empty(t, resp.Error)
+	require.Equal(t, snapshot.Name, resp.Name)
+}
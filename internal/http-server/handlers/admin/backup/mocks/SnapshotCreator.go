@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	backup "link-shortener/internal/backup"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// SnapshotCreator is an autogenerated mock type for the SnapshotCreator type
+type SnapshotCreator struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields:
+func (_m *SnapshotCreator) Create() (backup.Snapshot, error) {
+	ret := _m.Called()
+
+	var r0 backup.Snapshot
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (backup.Snapshot, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() backup.Snapshot); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(backup.Snapshot)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewSnapshotCreator interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewSnapshotCreator creates a new instance of SnapshotCreator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewSnapshotCreator(t mockConstructorTestingTNewSnapshotCreator) *SnapshotCreator {
+	mock := &SnapshotCreator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// flushEvery is how many links are written between flushes to the client.
+const flushEvery = 500
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkIterator
+type LinkIterator interface {
+	ForEachLink(fn func(link storage.Link) error) error
+}
+
+// New streams all links as CSV or NDJSON (?format=, NDJSON by default).
+func New(log *slog.Logger, links LinkIterator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = linkio.FormatNDJSON
+		}
+
+		cw := &countingWriter{w: w}
+
+		lw, err := linkio.NewWriter(cw, format)
+		if err != nil {
+			log.Info("invalid format", slog.String("format", format))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid format"))
+			return
+		}
+
+		filename := fmt.Sprintf("links-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+		w.Header().Set("Content-Type", linkio.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		flusher, _ := w.(http.Flusher)
+		count := 0
+
+		err = links.ForEachLink(func(link storage.Link) error {
+			if err := lw.Write(link); err != nil {
+				return err
+			}
+
+			count++
+			if count%flushEvery == 0 && flusher != nil {
+				if err := lw.Flush(); err != nil {
+					return err
+				}
+				flusher.Flush()
+			}
+
+			return r.Context().Err()
+		})
+		if err == nil {
+			err = lw.Flush()
+		}
+		if err != nil {
+			log.Error("export failed", slog.Int("written", count), sl.Err(err))
+
+			if cw.n == 0 {
+				// Nothing reached the client yet, so it can still get a
+				// proper error instead of a truncated file.
+				w.Header().Del("Content-Disposition")
+				render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to export links"))
+				return
+			}
+
+			// The status line is already sent; abort the connection so that
+			// the client does not mistake a truncated export for a full one.
+			panic(http.ErrAbortHandler)
+		}
+
+		log.Info("links exported", slog.Int("count", count), slog.String("format", format))
+	}
+}
+
+// countingWriter tracks whether any part of the export reached the client.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
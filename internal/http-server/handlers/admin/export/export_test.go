@@ -0,0 +1,120 @@
+package export_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/admin/export"
+	"link-shortener/internal/http-server/handlers/admin/export/mocks"
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+)
+
+func TestExportHandler(t *testing.T) {
+	links := []storage.Link{
+		{ID: 1, Alias: "g", URL: "https://google.com"},
+		{ID: 2, Alias: "d", URL: "https://go.dev"},
+	}
+
+	cases := []struct {
+		name        string
+		query       string
+		iterate     bool
+		iterError   error
+		contentType string
+		body        string
+		// roundTrip checks that the body reads back as the exported links,
+		// instead of comparing it to body.
+		roundTrip bool
+	}{
+		{
+			name:        "NDJSON By Default",
+			iterate:     true,
+			contentType: "application/x-ndjson",
+			body: `{"id":1,"alias":"g","url":"https://google.com"}` + "\n" +
+				`{"id":2,"alias":"d","url":"https://go.dev"}` + "\n",
+		},
+		{
+			name:        "CSV",
+			query:       "?format=csv",
+			iterate:     true,
+			contentType: "text/csv; charset=utf-8",
+			roundTrip:   true,
+		},
+		{
+			name:        "Invalid Format",
+			query:       "?format=xml",
+			contentType: "application/json",
+			body:        `{"status":"Error","error":"invalid format","code":"invalid_request"}` + "\n",
+		},
+		{
+			name:        "Iteration Error",
+			query:       "?format=csv",
+			iterate:     true,
+			iterError:   errors.New("unexpected error"),
+			contentType: "application/json",
+			body:        `{"status":"Error","error":"failed to export links","code":"internal_error"}` + "\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			iteratorMock := mocks.NewLinkIterator(t)
+
+			if tc.iterate {
+				iteratorMock.On("ForEachLink", mock.Anything).
+					Run(func(args mock.Arguments) {
+						fn := args.Get(0).(func(storage.Link) error)
+						for _, link := range links {
+							require.NoError(t, fn(link))
+						}
+					}).
+					Return(tc.iterError).
+					Once()
+			}
+
+			handler := export.New(slogdiscard.NewDiscardLogger(), iteratorMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/export"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Contains(t, rr.Header().Get("Content-Type"), tc.contentType)
+			if !tc.roundTrip {
+				require.Equal(t, tc.body, rr.Body.String())
+				return
+			}
+
+			require.True(t, strings.HasPrefix(rr.Body.String(), "id,alias,url,"))
+
+			lr, err := linkio.NewReader(rr.Body, linkio.FormatCSV)
+			require.NoError(t, err)
+
+			var got []storage.Link
+			for {
+				link, err := lr.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, link)
+			}
+			require.Equal(t, links, got)
+		})
+	}
+}
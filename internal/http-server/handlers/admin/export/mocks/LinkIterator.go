@@ -0,0 +1,43 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// LinkIterator is an autogenerated mock type for the LinkIterator type
+type LinkIterator struct {
+	mock.Mock
+}
+
+// ForEachLink provides a mock function with given fields: fn
+func (_m *LinkIterator) ForEachLink(fn func(storage.Link) error) error {
+	ret := _m.Called(fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(storage.Link) error) error); ok {
+		r0 = rf(fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewLinkIterator interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewLinkIterator creates a new instance of LinkIterator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewLinkIterator(t mockConstructorTestingTNewLinkIterator) *LinkIterator {
+	mock := &LinkIterator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,101 @@
+package health
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+	storage.HealthReport
+}
+
+var errInvalidOptions = errors.New("invalid limit, offset or broken")
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=HealthReporter
+type HealthReporter interface {
+	HealthReport(opts storage.HealthOptions) (storage.HealthReport, error)
+}
+
+// New returns the report of the latest health checks of link destinations,
+// broken links first. ?broken=true lists only those; limit and offset page
+// through the list like they do for /url.
+func New(log *slog.Logger, reporter HealthReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.health.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid report options", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, err.Error()))
+			return
+		}
+
+		report, err := reporter.HealthReport(opts)
+		if err != nil {
+			log.Error("failed to build health report", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to build health report"))
+			return
+		}
+
+		if report.Links == nil {
+			report.Links = []storage.Health{}
+		}
+
+		render.JSON(w, r, Response{
+			Response:     response.OK(),
+			HealthReport: report,
+		})
+	}
+}
+
+// parseOptions reads the broken, limit and offset query parameters.
+func parseOptions(r *http.Request) (storage.HealthOptions, error) {
+	opts := storage.HealthOptions{ListOptions: storage.ListOptions{Limit: defaultLimit}}
+	query := r.URL.Query()
+
+	if v := query.Get("broken"); v != "" {
+		broken, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, errInvalidOptions
+		}
+		opts.BrokenOnly = broken
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return opts, errInvalidOptions
+		}
+		opts.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, errInvalidOptions
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
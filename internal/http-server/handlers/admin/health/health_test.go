@@ -0,0 +1,110 @@
+package health_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/admin/health"
+	"link-shortener/internal/http-server/handlers/admin/health/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+)
+
+func TestHealthHandler(t *testing.T) {
+	since := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	report := storage.HealthReport{
+		Checked: 2,
+		Failing: 1,
+		Broken:  1,
+		Links: []storage.Health{
+			{LinkID: 2, Alias: "gone", URL: "https://example.com/gone", StatusCode: 404, CheckedAt: since, Failures: 3, BrokenSince: &since},
+			{LinkID: 1, Alias: "fine", URL: "https://example.com", StatusCode: 200, CheckedAt: since},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		opts      storage.HealthOptions
+		report    storage.HealthReport
+		mockError error
+		wantCode  int
+		respError string
+	}{
+		{
+			name:     "Default Options",
+			opts:     storage.HealthOptions{ListOptions: storage.ListOptions{Limit: 100}},
+			report:   report,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Broken Only",
+			query:    "?broken=true&limit=10&offset=5",
+			opts:     storage.HealthOptions{ListOptions: storage.ListOptions{Limit: 10, Offset: 5}, BrokenOnly: true},
+			report:   storage.HealthReport{Checked: 2, Failing: 1, Broken: 1, Links: report.Links[:1]},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Nothing Checked",
+			opts:     storage.HealthOptions{ListOptions: storage.ListOptions{Limit: 100}},
+			report:   storage.HealthReport{Links: []storage.Health{}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid Broken",
+			query:     "?broken=maybe",
+			wantCode:  http.StatusBadRequest,
+			respError: "invalid limit, offset or broken",
+		},
+		{
+			name:      "Invalid Limit",
+			query:     "?limit=0",
+			wantCode:  http.StatusBadRequest,
+			respError: "invalid limit, offset or broken",
+		},
+		{
+			name:      "Storage Error",
+			opts:      storage.HealthOptions{ListOptions: storage.ListOptions{Limit: 100}},
+			mockError: errors.New("unexpected error"),
+			wantCode:  http.StatusInternalServerError,
+			respError: "failed to build health report",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reporterMock := mocks.NewHealthReporter(t)
+			if tc.wantCode != http.StatusBadRequest {
+				reporterMock.On("HealthReport", tc.opts).Return(tc.report, tc.mockError).Once()
+			}
+
+			h := health.New(slogdiscard.NewDiscardLogger(), reporterMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/health"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			var resp health.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.report, resp.HealthReport)
+			}
+		})
+	}
+}
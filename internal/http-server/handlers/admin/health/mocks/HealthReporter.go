@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// HealthReporter is an autogenerated mock type for the HealthReporter type
+type HealthReporter struct {
+	mock.Mock
+}
+
+// HealthReport provides a mock function with given fields: opts
+func (_m *HealthReporter) HealthReport(opts storage.HealthOptions) (storage.HealthReport, error) {
+	ret := _m.Called(opts)
+
+	var r0 storage.HealthReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(storage.HealthOptions) (storage.HealthReport, error)); ok {
+		return rf(opts)
+	}
+	if rf, ok := ret.Get(0).(func(storage.HealthOptions) storage.HealthReport); ok {
+		r0 = rf(opts)
+	} else {
+		r0 = ret.Get(0).(storage.HealthReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(storage.HealthOptions) error); ok {
+		r1 = rf(opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewHealthReporter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewHealthReporter creates a new instance of HealthReporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewHealthReporter(t mockConstructorTestingTNewHealthReporter) *HealthReporter {
+	mock := &HealthReporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/http-server/handlers/url/save"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+// maxBodySize bounds the size of an uploaded import file.
+const maxBodySize = 64 << 20
+
+type Response struct {
+	response.Response
+	linkio.Report
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Importer
+type Importer interface {
+	BeginImport() (storage.ImportTx, error)
+}
+
+// New imports the CSV or NDJSON request body (?format=, NDJSON by default)
+// in a single transaction. ?policy= selects what happens on alias
+// collisions: skip, overwrite or fail (the default). Destinations must
+// pass urlPolicy, unless it is nil.
+func New(log *slog.Logger, importer Importer, urlPolicy save.URLPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.importer.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = linkio.FormatNDJSON
+		}
+
+		policy := linkio.PolicyFail
+		if p := query.Get("policy"); p != "" {
+			var err error
+			if policy, err = linkio.ParsePolicy(p); err != nil {
+				log.Info("invalid policy", slog.String("policy", p))
+				render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid policy"))
+				return
+			}
+		}
+
+		lr, err := linkio.NewReader(http.MaxBytesReader(w, r.Body, maxBodySize), format)
+		if err != nil {
+			log.Info("invalid format", slog.String("format", format))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid format"))
+			return
+		}
+
+		tx, err := importer.BeginImport()
+		if err != nil {
+			log.Error("failed to begin import", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to import links"))
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		report, err := linkio.Import(lr, tx, policy, save.LinkValidator(urlPolicy))
+		if errors.Is(err, linkio.ErrAborted) {
+			log.Info("import aborted", sl.Err(err))
+			render.JSON(w, r, Response{
+				Response: response.ErrorWithCode(response.CodeAliasExists, err.Error()),
+				Report:   report,
+			})
+			return
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Error("failed to import links", sl.Err(err))
+			render.JSON(w, r, Response{
+				Response: response.ErrorWithCode(response.CodeInternal, "failed to import links"),
+				Report:   report,
+			})
+			return
+		}
+
+		log.Info("links imported",
+			slog.Int("created", report.Created),
+			slog.Int("overwritten", report.Overwritten),
+			slog.Int("skipped", report.Skipped),
+			slog.Int("failed", report.Failed),
+		)
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Report:   report,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package importer_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/admin/importer"
+	"link-shortener/internal/http-server/handlers/admin/importer/mocks"
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+	storagemocks "link-shortener/internal/storage/mocks"
+)
+
+func TestImportHandler(t *testing.T) {
+	const csvBody = "alias,url\ng,https://google.com\nbad,not a url\n"
+
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		saveError error
+		commit    bool
+		overwrite bool
+		respError string
+		report    linkio.Report
+	}{
+		{
+			name:   "CSV Success",
+			query:  "?format=csv",
+			body:   csvBody,
+			commit: true,
+			report: linkio.Report{Created: 1, Failed: 1},
+		},
+		{
+			name:   "NDJSON By Default",
+			body:   `{"alias": "g", "url": "https://google.com"}`,
+			commit: true,
+			report: linkio.Report{Created: 1},
+		},
+		{
+			name:   "Workspace Alias",
+			body:   `{"alias": "g", "url": "https://google.com"}` + "\n" + `{"alias": "acme~g", "url": "https://google.com"}`,
+			commit: true,
+			report: linkio.Report{Created: 1, Failed: 1},
+		},
+		{
+			name:      "Conflict Skipped",
+			query:     "?format=csv&policy=skip",
+			body:      csvBody,
+			saveError: storage.ErrURLExist,
+			commit:    true,
+			report:    linkio.Report{Skipped: 1, Failed: 1},
+		},
+		{
+			name:      "Conflict Overwritten",
+			query:     "?format=csv&policy=overwrite",
+			body:      csvBody,
+			saveError: storage.ErrURLExist,
+			overwrite: true,
+			commit:    true,
+			report:    linkio.Report{Overwritten: 1, Failed: 1},
+		},
+		{
+			name:      "Conflict Fails",
+			query:     "?format=csv",
+			body:      csvBody,
+			saveError: storage.ErrURLExist,
+			respError: `import aborted: line 2: alias "g" already exists`,
+			report:    linkio.Report{Failed: 1},
+		},
+		{
+			name:      "Invalid Policy",
+			query:     "?policy=maybe",
+			respError: "invalid policy",
+		},
+		{
+			name:      "Invalid Format",
+			query:     "?format=xml",
+			respError: "invalid format",
+		},
+		{
+			name:      "Save Error",
+			body:      `{"alias": "g", "url": "https://google.com"}`,
+			saveError: errors.New("unexpected error"),
+			respError: "failed to import links",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			importerMock := mocks.NewImporter(t)
+			txMock := storagemocks.NewImportTx(t)
+
+			if tc.body != "" {
+				importerMock.On("BeginImport").Return(txMock, nil).Once()
+				txMock.On("SaveLink", storage.Link{Alias: "g", URL: "https://google.com"}).
+					Return(int64(1), tc.saveError).
+					Once()
+				txMock.On("Rollback").Return(nil).Once()
+			}
+			if tc.overwrite {
+				txMock.On("OverwriteLink", mock.AnythingOfType("storage.Link")).
+					Return(int64(1), nil).
+					Once()
+			}
+			if tc.commit {
+				txMock.On("Commit").Return(nil).Once()
+			}
+
+			handler := importer.New(slogdiscard.NewDiscardLogger(), importerMock, nil)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/import"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp importer.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+
+			resp.Report.Errors = nil
+			require.Equal(t, tc.report, resp.Report)
+		})
+	}
+}
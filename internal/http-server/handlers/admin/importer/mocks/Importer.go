@@ -0,0 +1,55 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Importer is an autogenerated mock type for the Importer type
+type Importer struct {
+	mock.Mock
+}
+
+// BeginImport provides a mock function with given fields:
+func (_m *Importer) BeginImport() (storage.ImportTx, error) {
+	ret := _m.Called()
+
+	var r0 storage.ImportTx
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (storage.ImportTx, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() storage.ImportTx); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.ImportTx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewImporter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewImporter creates a new instance of Importer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewImporter(t mockConstructorTestingTNewImporter) *Importer {
+	mock := &Importer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
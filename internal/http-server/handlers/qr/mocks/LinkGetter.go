@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "link-shortener/internal/storage"
+)
+
+// LinkGetter is an autogenerated mock type for the LinkGetter type
+type LinkGetter struct {
+	mock.Mock
+}
+
+// GetLink provides a mock function with given fields: domain, alias
+func (_m *LinkGetter) GetLink(domain string, alias string) (storage.Link, error) {
+	ret := _m.Called(domain, alias)
+
+	var r0 storage.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (storage.Link, error)); ok {
+		return rf(domain, alias)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) storage.Link); ok {
+		r0 = rf(domain, alias)
+	} else {
+		r0 = ret.Get(0).(storage.Link)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(domain, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewLinkGetter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewLinkGetter creates a new instance of LinkGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewLinkGetter(t mockConstructorTestingTNewLinkGetter) *LinkGetter {
+	mock := &LinkGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,169 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/qrcode"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/shorturl"
+	"link-shortener/internal/storage"
+)
+
+const (
+	defaultSize   = 256
+	minSize       = 32
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 32
+	// maxAge is how long clients may cache a code. It only depends on the
+	// alias, so it stays valid when the destination changes.
+	maxAge = 24 * time.Hour
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkGetter
+type LinkGetter interface {
+	GetLink(domain, alias string) (storage.Link, error)
+}
+
+// New returns a handler serving the QR code of the short URL of a link, as
+// PNG or, with format=svg or a .svg extension, SVG. The query parameters
+// size (pixels), margin (modules), level (L, M, Q or H) and fg and bg (hex
+// colors) change how it is drawn. Short URLs are built on publicURL, or on
+// the host the request was sent to if it is empty, and links are looked up
+// on the domain of domains the request was sent to.
+func New(log *slog.Logger, linkGetter LinkGetter, publicURL string, domains *shortdomain.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.qr.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		format, opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid options", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeInvalidRequest, err.Error()))
+			return
+		}
+
+		domain := domains.ForRequest(r)
+
+		link, err := linkGetter.GetLink(domain.Host, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		text := shorturl.For(shorturl.OnDomain(shorturl.Base(r, publicURL), link.Domain), link.Alias)
+
+		var body []byte
+		switch format {
+		case "svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			body, err = qrcode.SVG(text, opts)
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			body, err = qrcode.PNG(text, opts)
+		}
+		if err != nil {
+			log.Error("failed to draw qr code", sl.Err(err))
+			w.Header().Del("Content-Type")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+		// Answers If-None-Match with 304 Not Modified.
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}
+}
+
+// parseOptions reads the format and drawing options from the query of r.
+// The returned error message is suitable for showing to the user.
+func parseOptions(r *http.Request) (string, qrcode.Options, error) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		// Set by middleware.URLFormat for paths like /qr/alias.svg.
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	if format != "" && format != "png" && format != "svg" {
+		return "", qrcode.Options{}, errors.New("format must be png or svg")
+	}
+
+	opts := qrcode.Options{Size: defaultSize, Margin: defaultMargin, Level: query.Get("level")}
+
+	var err error
+	if opts.Size, err = intParam(query.Get("size"), defaultSize, minSize, maxSize); err != nil {
+		return "", opts, fmt.Errorf("size %w", err)
+	}
+	if opts.Margin, err = intParam(query.Get("margin"), defaultMargin, 0, maxMargin); err != nil {
+		return "", opts, fmt.Errorf("margin %w", err)
+	}
+
+	switch strings.ToUpper(opts.Level) {
+	case "", "L", "M", "Q", "H":
+	default:
+		return "", opts, qrcode.ErrInvalidLevel
+	}
+
+	if opts.Foreground, err = colorParam(query.Get("fg"), "000000"); err != nil {
+		return "", opts, fmt.Errorf("fg: %w", err)
+	}
+	if opts.Background, err = colorParam(query.Get("bg"), "ffffff"); err != nil {
+		return "", opts, fmt.Errorf("bg: %w", err)
+	}
+
+	return format, opts, nil
+}
+
+func intParam(value string, def, lo, hi int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("must be a number from %d to %d", lo, hi)
+	}
+	return n, nil
+}
+
+func colorParam(value, def string) (color.NRGBA, error) {
+	if value == "" {
+		value = def
+	}
+	return qrcode.ParseColor(value)
+}
@@ -0,0 +1,163 @@
+package qr_test
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/qr"
+	"link-shortener/internal/http-server/handlers/qr/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/storage"
+)
+
+func newRouter(t *testing.T, getter qr.LinkGetter) http.Handler {
+	t.Helper()
+
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{{Host: "go.acme.com"}})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.URLFormat)
+	r.Get("/qr/{alias}", qr.New(slogdiscard.NewDiscardLogger(), getter, "https://sho.rt", domains))
+	return r
+}
+
+func TestQR(t *testing.T) {
+	cases := []struct {
+		name            string
+		path            string
+		wantContentType string
+	}{
+		{name: "PNG", path: "/qr/spring", wantContentType: "image/png"},
+		{name: "SVG Parameter", path: "/qr/spring?format=svg", wantContentType: "image/svg+xml"},
+		{name: "SVG Extension", path: "/qr/spring.svg", wantContentType: "image/svg+xml"},
+		{name: "Options", path: "/qr/spring?size=512&margin=0&level=H&fg=%23336699&bg=ffffff00", wantContentType: "image/png"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			linkGetterMock.On("GetLink", "", "spring").
+				Return(storage.Link{ID: 1, Alias: "spring", URL: "https://example.com"}, nil).
+				Once()
+
+			rr := httptest.NewRecorder()
+			newRouter(t, linkGetterMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tc.wantContentType, rr.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rr.Header().Get("ETag"))
+			assert.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+
+			if tc.wantContentType == "image/png" {
+				_, err := png.Decode(rr.Body)
+				require.NoError(t, err)
+			} else {
+				assert.True(t, strings.HasPrefix(rr.Body.String(), "<svg"))
+			}
+		})
+	}
+}
+
+func TestQRETag(t *testing.T) {
+	linkGetterMock := mocks.NewLinkGetter(t)
+	linkGetterMock.On("GetLink", "", "spring").
+		Return(storage.Link{ID: 1, Alias: "spring", URL: "https://example.com"}, nil)
+	router := newRouter(t, linkGetterMock)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/spring", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	first := rr.Body.Bytes()
+
+	req := httptest.NewRequest(http.MethodGet, "/qr/spring", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	// Another drawing is another entity.
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/spring?size=128", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.False(t, bytes.Equal(first, rr.Body.Bytes()))
+}
+
+func TestQRDomain(t *testing.T) {
+	linkGetterMock := mocks.NewLinkGetter(t)
+	linkGetterMock.On("GetLink", "", "spring").
+		Return(storage.Link{ID: 1, Alias: "spring", URL: "https://example.com"}, nil).
+		Once()
+	linkGetterMock.On("GetLink", "go.acme.com", "spring").
+		Return(storage.Link{ID: 2, Domain: "go.acme.com", Alias: "spring", URL: "https://example.com"}, nil).
+		Once()
+	router := newRouter(t, linkGetterMock)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qr/spring", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	onDefault := rr.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/qr/spring", nil)
+	req.Host = "GO.acme.com:443"
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	// The code holds the short URL on the link's domain.
+	assert.NotEqual(t, onDefault, rr.Header().Get("ETag"))
+}
+
+func TestQRErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		mockError error
+		wantCode  int
+		wantError string
+	}{
+		{name: "Size Too Small", path: "/qr/spring?size=8", wantCode: http.StatusBadRequest, wantError: "size must be a number from 32 to 2048"},
+		{name: "Invalid Margin", path: "/qr/spring?margin=x", wantCode: http.StatusBadRequest, wantError: "margin must be a number from 0 to 32"},
+		{name: "Invalid Level", path: "/qr/spring?level=X", wantCode: http.StatusBadRequest, wantError: "error correction level must be L, M, Q or H"},
+		{name: "Invalid Color", path: "/qr/spring?fg=red", wantCode: http.StatusBadRequest, wantError: "fg: color must be hex RGB, RRGGBB or RRGGBBAA"},
+		{name: "Invalid Format", path: "/qr/spring?format=gif", wantCode: http.StatusBadRequest, wantError: "format must be png or svg"},
+		{name: "Not Found", path: "/qr/spring", mockError: storage.ErrURLNotFound, wantCode: http.StatusNotFound, wantError: "not found"},
+		{name: "Storage Error", path: "/qr/spring", mockError: errors.New("unexpected error"), wantCode: http.StatusInternalServerError, wantError: "internal error"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			if tc.mockError != nil {
+				linkGetterMock.On("GetLink", "", "spring").Return(storage.Link{}, tc.mockError).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			newRouter(t, linkGetterMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), `"error":"`+tc.wantError+`"`)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package redirect
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/shortdomain"
+)
+
+// NewRoot returns the handler of the path / of each of domains: a redirect
+// to the root URL of the domain, or its page for missing aliases.
+func NewRoot(log *slog.Logger, domains *shortdomain.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.redirect.NewRoot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		domain := domains.ForRequest(r)
+		if domain.RootURL == "" {
+			renderNotFound(w, r, log, domain)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "private, no-store")
+		http.Redirect(w, r, domain.RootURL, http.StatusFound)
+	}
+}
+
+// renderNotFound tells the visitor of r that domain has no link there:
+// browsers see the page of the domain, if it has one.
+func renderNotFound(w http.ResponseWriter, r *http.Request, log *slog.Logger, domain shortdomain.Domain) {
+	if domain.NotFound == nil || !acceptsHTML(r) {
+		render.JSON(w, r, resp.ErrorWithCode(resp.CodeNotFound, "not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	if _, err := w.Write(domain.NotFound); err != nil {
+		log.Error("failed to write not found page", sl.Err(err))
+	}
+}
@@ -0,0 +1,58 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// AttemptLimiter is an autogenerated mock type for the AttemptLimiter type
+type AttemptLimiter struct {
+	mock.Mock
+}
+
+// Allow provides a mock function with given fields: key
+func (_m *AttemptLimiter) Allow(key string) (bool, time.Duration) {
+	ret := _m.Called(key)
+
+	var r0 bool
+	var r1 time.Duration
+	if rf, ok := ret.Get(0).(func(string) (bool, time.Duration)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) time.Duration); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(time.Duration)
+	}
+
+	return r0, r1
+}
+
+// Hit provides a mock function with given fields: key
+func (_m *AttemptLimiter) Hit(key string) {
+	_m.Called(key)
+}
+
+type mockConstructorTestingTNewAttemptLimiter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAttemptLimiter creates a new instance of AttemptLimiter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAttemptLimiter(t mockConstructorTestingTNewAttemptLimiter) *AttemptLimiter {
+	mock := &AttemptLimiter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
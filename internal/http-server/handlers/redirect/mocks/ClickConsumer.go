@@ -0,0 +1,39 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// ClickConsumer is an autogenerated mock type for the ClickConsumer type
+type ClickConsumer struct {
+	mock.Mock
+}
+
+// ConsumeClick provides a mock function with given fields: linkID
+func (_m *ClickConsumer) ConsumeClick(linkID int64) error {
+	ret := _m.Called(linkID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(linkID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewClickConsumer interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewClickConsumer creates a new instance of ClickConsumer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewClickConsumer(t mockConstructorTestingTNewClickConsumer) *ClickConsumer {
+	mock := &ClickConsumer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,43 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "link-shortener/internal/storage"
+)
+
+// ClickRecorder is an autogenerated mock type for the ClickRecorder type
+type ClickRecorder struct {
+	mock.Mock
+}
+
+// RecordClick provides a mock function with given fields: click
+func (_m *ClickRecorder) RecordClick(click storage.Click) error {
+	ret := _m.Called(click)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(storage.Click) error); ok {
+		r0 = rf(click)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewClickRecorder interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewClickRecorder creates a new instance of ClickRecorder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewClickRecorder(t mockConstructorTestingTNewClickRecorder) *ClickRecorder {
+	mock := &ClickRecorder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
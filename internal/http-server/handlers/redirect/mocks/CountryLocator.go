@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	http "net/http"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CountryLocator is an autogenerated mock type for the CountryLocator type
+type CountryLocator struct {
+	mock.Mock
+}
+
+// Country provides a mock function with given fields: r
+func (_m *CountryLocator) Country(r *http.Request) (string, error) {
+	ret := _m.Called(r)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*http.Request) (string, error)); ok {
+		return rf(r)
+	}
+	if rf, ok := ret.Get(0).(func(*http.Request) string); ok {
+		r0 = rf(r)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(*http.Request) error); ok {
+		r1 = rf(r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewCountryLocator interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewCountryLocator creates a new instance of CountryLocator. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewCountryLocator(t mockConstructorTestingTNewCountryLocator) *CountryLocator {
+	mock := &CountryLocator{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,122 @@
+package redirect
+
+import (
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+
+	resp "link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+)
+
+// PasswordHeader lets API clients follow a protected link without the form.
+const PasswordHeader = "X-Link-Password"
+
+const (
+	passwordField = "password"
+	// maxFormSize bounds the body of a password form submission.
+	maxFormSize = 4 << 10
+)
+
+//go:embed password.html
+var passwordPageHTML string
+
+var passwordPage = template.Must(template.New("password").Parse(passwordPageHTML))
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=AttemptLimiter
+type AttemptLimiter interface {
+	// Allow reports whether another attempt is allowed for key and, if not,
+	// when to retry.
+	Allow(key string) (bool, time.Duration)
+	// Hit records a failed attempt for key.
+	Hit(key string)
+}
+
+// checkPassword verifies the password sent for a protected link, either in
+// PasswordHeader or through the password form. If it is missing or wrong it
+// writes the response and returns false. fromForm reports whether the
+// password came from the form.
+func checkPassword(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	link storage.Link,
+	limiter AttemptLimiter,
+) (fromForm bool, ok bool) {
+	password := r.Header.Get(PasswordHeader)
+	if password == "" && r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+		password = r.PostFormValue(passwordField)
+		fromForm = r.PostForm.Has(passwordField)
+	}
+
+	html := fromForm || acceptsHTML(r)
+
+	if password == "" {
+		log.Info("password required", slog.String("alias", link.Alias))
+		denyPassword(w, r, log, html, http.StatusUnauthorized, resp.CodePasswordNeeded, "password required")
+		return fromForm, false
+	}
+
+	// The same alias can name different links on different domains, so
+	// attempts are counted per link.
+	limitKey := strconv.FormatInt(link.ID, 10)
+	if limiter != nil {
+		if allowed, retryAfter := limiter.Allow(limitKey); !allowed {
+			log.Warn("too many password attempts", slog.String("alias", link.Alias))
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+			denyPassword(w, r, log, html, http.StatusTooManyRequests, resp.CodeRateLimited, "too many attempts, try again later")
+			return fromForm, false
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		if limiter != nil {
+			limiter.Hit(limitKey)
+		}
+		log.Info("wrong password", slog.String("alias", link.Alias))
+		denyPassword(w, r, log, html, http.StatusUnauthorized, resp.CodeWrongPassword, "wrong password")
+		return fromForm, false
+	}
+
+	return fromForm, true
+}
+
+// acceptsHTML reports whether r comes from a browser rather than an API
+// client.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// denyPassword answers browsers with the password form and API clients
+// with a JSON error.
+func denyPassword(w http.ResponseWriter, r *http.Request, log *slog.Logger, html bool, status int, code, msg string) {
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if !html {
+		render.Status(r, status)
+		render.JSON(w, r, resp.ErrorWithCode(code, msg))
+		return
+	}
+
+	// Only the missing-password case is not an error worth showing.
+	var data struct{ Error string }
+	if code != resp.CodePasswordNeeded {
+		data.Error = msg
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	if err := passwordPage.Execute(w, data); err != nil {
+		log.Error("failed to render password page", sl.Err(err))
+	}
+}
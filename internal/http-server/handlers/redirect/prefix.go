@@ -0,0 +1,70 @@
+package redirect
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+var errInvalidPath = errors.New("invalid path")
+
+// pathSuffix returns the part of the request path after the alias, still
+// escaped and without the leading slash. It is taken from the URL rather
+// than the route, as middleware.URLFormat hides file extensions from the
+// router. Anything else after the alias, such as an extension, is not
+// a suffix.
+func pathSuffix(r *http.Request, alias string) string {
+	suffix, ok := strings.CutPrefix(r.URL.EscapedPath(), "/"+alias+"/")
+	if !ok {
+		return ""
+	}
+	return suffix
+}
+
+// cleanSuffix checks a path suffix and returns it escaped the canonical
+// way. Segments that could climb out of the destination path ("..",
+// encoded slashes and the like) are refused with errInvalidPath.
+func cleanSuffix(suffix string) (string, error) {
+	segments := strings.Split(suffix, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", errInvalidPath
+		}
+		// Only a trailing slash may leave a segment empty.
+		if unescaped == "" && i < len(segments)-1 {
+			return "", errInvalidPath
+		}
+		if unescaped == "." || unescaped == ".." || strings.ContainsAny(unescaped, `/\`) ||
+			strings.IndexFunc(unescaped, unicode.IsControl) >= 0 {
+			return "", errInvalidPath
+		}
+		segments[i] = url.PathEscape(unescaped)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// joinPath appends a suffix returned by cleanSuffix to the path of dest.
+// Only the path changes, so the suffix cannot lead to another host.
+func joinPath(dest, suffix string) (string, error) {
+	if suffix == "" {
+		return dest, nil
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", err
+	}
+
+	escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + suffix
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", err
+	}
+	u.Path, u.RawPath = path, escaped
+
+	return u.String(), nil
+}
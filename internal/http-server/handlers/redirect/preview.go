@@ -0,0 +1,245 @@
+package redirect
+
+import (
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+)
+
+// PreviewSuffix, appended to an alias, shows where the link goes instead
+// of following it. So does the query parameter previewParam=1.
+const PreviewSuffix = "+"
+
+const previewParam = "preview"
+
+// confirmedParam=1 marks the visit of someone who chose to continue on the
+// interstitial.
+const confirmedParam = "confirmed"
+
+//go:embed preview.html
+var previewPageHTML string
+
+var previewPage = template.Must(template.New("preview").Parse(previewPageHTML))
+
+// pageData fills in preview.html.
+type pageData struct {
+	// Warning turns the page into the interstitial shown instead of a
+	// redirect.
+	Warning bool
+	Alias   string
+	// URL is the destination, empty if it is protected or depends on the
+	// variant drawn for the visitor.
+	URL   string
+	Host  string
+	Title string
+	// Description and Image are those of the destination page, if it
+	// was fetched.
+	Description string
+	Image       string
+	Favicon     string
+	Variants    []string
+	Protected   bool
+	// Limited hides all of the destination but its host: following a
+	// click-limited link uses up a click, previewing it or being warned
+	// about it must not reveal what it guards.
+	Limited bool
+	// Continue is where the button leads.
+	Continue string
+}
+
+// isPreview reports whether r asks for the preview of the link with the
+// alias param, and returns the alias itself.
+func isPreview(r *http.Request, param string) (string, bool) {
+	alias, ok := strings.CutSuffix(param, PreviewSuffix)
+	return alias, ok || r.URL.Query().Get(previewParam) == "1"
+}
+
+// renderPreview shows where link would send the visitor of r without
+// sending them there. No click is used up or recorded; the continue button
+// leads to the short URL, so that the visit counts as usual. The
+// destination of a protected link stays hidden, as does all but the host
+// of that of a click-limited one.
+func renderPreview(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	link storage.Link,
+	suffix string,
+	opts Options,
+	now time.Time,
+) {
+	data := pageData{Alias: link.Alias, Continue: continueURL(r, link.Alias, suffix)}
+	// Whether the visitor would land on the page whose metadata was fetched.
+	fetched := false
+	// Whether following the link would use up a click.
+	limited := false
+
+	// Preview parameters are not meant for the destination.
+	r = withoutParam(r, previewParam)
+
+	switch {
+	case link.WindowAt(now) != storage.WindowActive:
+		if link.FallbackURL == "" {
+			redirectInactive(w, r, log, link, link.WindowAt(now))
+			return
+		}
+		data.URL = link.FallbackURL
+	case link.Exhausted():
+		renderGone(w, r)
+		return
+	case link.PasswordHash != "":
+		data.Protected = true
+	default:
+		limited = link.MaxClicks > 0
+		country := visitorCountry(r, log, opts.CountryLocator)
+
+		dest, matched := link.URL, false
+		if len(link.Targets) > 0 {
+			dest, matched = target(link, r.UserAgent())
+		}
+		if !matched && len(link.GeoTargets) > 0 {
+			dest, matched = geoTarget(link, country)
+		}
+		if !matched && len(link.Destinations) > 0 {
+			dest, matched = stickyVariant(r, link)
+			if !matched {
+				for _, d := range link.Destinations {
+					if d.Weight > 0 {
+						data.Variants = append(data.Variants, d.URL)
+					}
+				}
+				break
+			}
+		}
+
+		fetched = dest == link.URL
+		data.URL = finalURL(dest, link, r, suffix, country, log)
+	}
+
+	data.describe()
+	switch {
+	case limited:
+		data.redact(link)
+	case fetched:
+		data.fetched(link)
+	}
+	renderPage(w, log, data)
+}
+
+// renderInterstitial warns the visitor of r before they leave for dest.
+// Continuing follows the short URL again, marked as confirmed, so that the
+// password is asked for and the click counted only then. Until then the
+// destination of a protected or click-limited link stays hidden, as on its
+// preview.
+func renderInterstitial(w http.ResponseWriter, r *http.Request, log *slog.Logger, link storage.Link, dest, suffix string) {
+	cont := continueURL(r, link.Alias, suffix)
+	if strings.Contains(cont, "?") {
+		cont += "&" + confirmedParam + "=1"
+	} else {
+		cont += "?" + confirmedParam + "=1"
+	}
+
+	data := pageData{Warning: true, Alias: link.Alias, URL: dest, Continue: cont}
+	data.describe()
+	data.redact(link)
+	renderPage(w, log, data)
+}
+
+// redact hides what link guards from visitors who have not followed it
+// yet: all of the destination of a protected link, and all but the host
+// of that of a click-limited one.
+func (data *pageData) redact(link storage.Link) {
+	switch {
+	case link.PasswordHash != "":
+		data.Protected = true
+		data.URL, data.Host, data.Title, data.Favicon, data.Variants = "", "", "", "", nil
+	case link.MaxClicks > 0:
+		data.Limited = true
+		data.URL, data.Title, data.Favicon, data.Variants = "", "", "", nil
+	}
+}
+
+// withoutParam returns a copy of r without the query parameter name.
+func withoutParam(r *http.Request, name string) *http.Request {
+	r = r.Clone(r.Context())
+	query := r.URL.Query()
+	query.Del(name)
+	r.URL.RawQuery = query.Encode()
+	return r
+}
+
+// describe fills in what the page shows about the destination. Until the
+// page itself is fetched, its host stands in for the title, and the
+// favicon is loaded from where browsers look for it by default.
+func (data *pageData) describe() {
+	u, err := url.Parse(data.URL)
+	if data.URL == "" || err != nil {
+		return
+	}
+
+	data.Host = u.Hostname()
+	data.Title = data.Host
+	if u.Scheme == "http" || u.Scheme == "https" {
+		data.Favicon = u.Scheme + "://" + u.Host + "/favicon.ico"
+	}
+}
+
+// fetched shows the metadata fetched from the page link leads to, where
+// there is any.
+func (data *pageData) fetched(link storage.Link) {
+	if link.Title != "" {
+		data.Title = link.Title
+	}
+	data.Description = link.Description
+	data.Image = link.Image
+}
+
+func renderPage(w http.ResponseWriter, log *slog.Logger, data pageData) {
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	if err := previewPage.Execute(w, data); err != nil {
+		log.Error("failed to render preview page", sl.Err(err))
+	}
+}
+
+// continueURL returns the short URL r previews.
+func continueURL(r *http.Request, alias, suffix string) string {
+	path := "/" + alias
+	if suffix != "" {
+		path += "/" + suffix
+	}
+
+	query := r.URL.Query()
+	query.Del(previewParam)
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// external reports whether dest is on none of the internal domains or
+// their subdomains.
+func external(dest string, internal []string) bool {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range internal {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package redirect
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"link-shortener/internal/lib/urlquery"
+	"link-shortener/internal/storage"
+)
+
+// withQuery adds the query parameters of link to dest, after those of the
+// short URL if the link passes them through. Parameters dest has itself
+// are left alone.
+func withQuery(dest string, link storage.Link, r *http.Request, country string) (string, error) {
+	var layers []url.Values
+
+	if link.QueryPassthrough && r.URL.RawQuery != "" {
+		layers = append(layers, r.URL.Query())
+	}
+
+	if len(link.QueryParams) > 0 {
+		placeholders := strings.NewReplacer("{alias}", link.Alias, "{country}", country)
+
+		params := make(url.Values)
+		for _, p := range link.QueryParams {
+			value := placeholders.Replace(p.Value)
+			// Better no parameter than one with a placeholder left empty.
+			if value == "" && p.Value != "" {
+				continue
+			}
+			params.Add(p.Key, value)
+		}
+		layers = append(layers, params)
+	}
+
+	if len(layers) == 0 {
+		return dest, nil
+	}
+	return urlquery.Merge(dest, layers...)
+}
+
+// countryDependent reports whether where link redirects to depends on the
+// visitor's country.
+func countryDependent(link storage.Link) bool {
+	if len(link.GeoTargets) > 0 {
+		return true
+	}
+	for _, p := range link.QueryParams {
+		if strings.Contains(p.Value, "{country}") {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package redirect
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,14 +13,82 @@ import (
 
 	resp "link-shortener/internal/lib/api/response"
 	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/useragent"
 	"link-shortener/internal/storage"
 )
 
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetLink(domain, alias string) (storage.Link, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickRecorder
+type ClickRecorder interface {
+	RecordClick(click storage.Click) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickConsumer
+type ClickConsumer interface {
+	ConsumeClick(linkID int64) error
+}
+
+// Options configure New.
+type Options struct {
+	// DefaultStatus is used for links without a redirect status of their
+	// own. Zero means http.StatusFound.
+	DefaultStatus int
+	// PermanentMaxAge is how long clients may cache 301 and 308 redirects.
+	PermanentMaxAge time.Duration
+	// AttemptLimiter limits wrong passwords per link, keyed by the link
+	// ID. Nil means no limit.
+	AttemptLimiter AttemptLimiter
+	// CountryLocator finds the country of visitors for geo targets and
+	// click analytics. Nil means the country is never known.
+	CountryLocator CountryLocator
+	// Interstitial shows browsers a warning page instead of redirecting
+	// them to a domain other than InternalDomains and their subdomains.
+	// Links can ask for the page themselves with storage.Link.Interstitial.
+	Interstitial    bool
+	InternalDomains []string
+	// Unfurl shows the crawlers chat apps send to preview links a page
+	// with the title, description and image of the link instead of
+	// redirecting them. Nothing is counted for their visits.
+	Unfurl bool
+	// Domains are the short domains served; links are looked up on the
+	// one named by the Host header. Nil means only the default domain.
+	Domains *shortdomain.Set
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=CountryLocator
+type CountryLocator interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country r comes
+	// from, or an empty string if it is unknown.
+	Country(r *http.Request) (string, error)
+}
+
+// ValidStatus reports whether code can be used to redirect a link.
+func ValidStatus(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	clickRecorder ClickRecorder,
+	clickConsumer ClickConsumer,
+	opts Options,
+) http.HandlerFunc {
+	if opts.DefaultStatus == 0 {
+		opts.DefaultStatus = http.StatusFound
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -27,34 +97,286 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		alias := chi.URLParam(r, "alias")
+		param := chi.URLParam(r, "alias")
+		alias, preview := isPreview(r, param)
 		if alias == "" {
 			log.Info("alias is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeInvalidRequest, "invalid request"))
 
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		domain := opts.Domains.ForRequest(r)
+
+		link, err := urlGetter.GetLink(domain.Host, alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
-			log.Info("url not found", "alias", alias)
+			log.Info("url not found", "alias", alias, "domain", domain.Host)
 
-			render.JSON(w, r, resp.Error("not found"))
+			renderNotFound(w, r, log, domain)
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("internal error"))
+			render.JSON(w, r, resp.ErrorWithCode(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("got url", slog.String("url", link.URL))
+
+		// Only prefix links have paths below them.
+		suffix := pathSuffix(r, param)
+		if suffix != "" && !link.Prefix {
+			log.Info("url not found", "alias", alias, "path", r.URL.Path)
+
+			renderNotFound(w, r, log, domain)
+
+			return
+		}
+		if suffix != "" {
+			if suffix, err = cleanSuffix(suffix); err != nil {
+				log.Info("invalid path", slog.String("path", r.URL.Path))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.ErrorWithCode(resp.CodeInvalidRequest, "invalid path"))
+				return
+			}
+		}
+
+		now := time.Now()
+
+		if preview {
+			log.Info("showing preview", slog.String("alias", alias))
+			renderPreview(w, r, log, link, suffix, opts, now)
+			return
+		}
+
+		if window := link.WindowAt(now); window != storage.WindowActive {
+			redirectInactive(w, r, log, link, window)
+			return
+		}
+
+		// Checked up front so that nobody is asked for the password of a
+		// link that is used up anyway.
+		if link.Exhausted() {
+			log.Info("link exhausted", slog.String("alias", alias))
+			renderGone(w, r)
+			return
+		}
+
+		if opts.Unfurl {
+			if useragent.Unfurler(r.UserAgent()) {
+				log.Info("showing unfurl page", slog.String("alias", alias))
+				renderUnfurl(w, log, link)
+				return
+			}
+			// Nor may caches hand the redirect to crawlers.
+			w.Header().Set("Vary", "User-Agent")
+		}
+
+		status := link.RedirectStatus
+		if status == 0 {
+			status = opts.DefaultStatus
+		}
+		// A cached redirect must not outlive the activation window.
+		maxAge := opts.PermanentMaxAge
+		if link.ActiveUntil != nil {
+			maxAge = min(maxAge, link.ActiveUntil.Sub(now))
+		}
+		// Shared caches cannot tell visitors from different countries apart.
+		cache := cacheControl(status, maxAge, countryDependent(link))
+
+		// The marker of a visitor continuing past the interstitial is not
+		// meant for the destination.
+		confirmed := r.URL.Query().Get(confirmedParam) == "1"
+		if confirmed {
+			r = withoutParam(r, confirmedParam)
+		}
+
+		dest, matched := link.URL, false
+		if len(link.Targets) > 0 {
+			dest, matched = target(link, r.UserAgent())
+			// Caches must not hand one platform's redirect to another.
+			w.Header().Set("Vary", "User-Agent")
+		}
+
+		country := visitorCountry(r, log, opts.CountryLocator)
+		if !matched && len(link.GeoTargets) > 0 {
+			dest, matched = geoTarget(link, country)
+		}
+
+		var variant string
+		if !matched && len(link.Destinations) > 0 {
+			dest = pickVariant(w, r, link)
+			variant = dest
+			// Every visit is a draw (or a cookie lookup) and counts towards
+			// the variant's share.
+			cache = "private, no-store"
+		}
 
+		dest = finalURL(dest, link, r, suffix, country, log)
+
+		// The warning comes before the password and the click: a visitor
+		// who backs out has neither used up nor made one. Continuing
+		// follows the short URL again.
+		if !confirmed && acceptsHTML(r) && (opts.Interstitial || link.Interstitial) && external(dest, opts.InternalDomains) {
+			log.Info("showing interstitial", slog.String("url", dest))
+			renderInterstitial(w, r, log, link, dest, suffix)
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		if link.PasswordHash != "" {
+			fromForm, ok := checkPassword(w, r, log, link, opts.AttemptLimiter)
+			if !ok {
+				return
+			}
+			// The browser must not resend the form, and with it the
+			// password, to the destination.
+			if fromForm {
+				status = http.StatusSeeOther
+			}
+			// Nor may anyone cache a redirect that skips the password.
+			cache = "private, no-store"
+		}
+
+		if link.MaxClicks > 0 {
+			err := clickConsumer.ConsumeClick(link.ID)
+			if errors.Is(err, storage.ErrLinkExhausted) {
+				log.Info("link exhausted", slog.String("alias", alias))
+				renderGone(w, r)
+				return
+			}
+			if err != nil {
+				log.Error("failed to consume click", sl.Err(err))
+				render.JSON(w, r, resp.ErrorWithCode(resp.CodeInternal, "internal error"))
+				return
+			}
+			// A cached redirect would not be counted.
+			cache = "private, no-store"
+		}
+
+		// A failure to count the click must not break the redirect itself.
+		err = clickRecorder.RecordClick(storage.Click{
+			LinkID:    link.ID,
+			At:        now,
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+			Variant:   variant,
+			Country:   country,
+		})
+		if err != nil {
+			log.Error("failed to record click", sl.Err(err))
+		}
+
+		w.Header().Set("Cache-Control", cache)
 
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, dest, status)
+	}
+}
+
+// finalURL adds the path suffix and the query parameters of link to dest.
+// Like the click count, they are not worth failing the redirect for.
+func finalURL(dest string, link storage.Link, r *http.Request, suffix, country string, log *slog.Logger) string {
+	if joined, err := joinPath(dest, suffix); err != nil {
+		log.Error("failed to join path", sl.Err(err))
+	} else {
+		dest = joined
+	}
+
+	if withParams, err := withQuery(dest, link, r, country); err != nil {
+		log.Error("failed to add query parameters", sl.Err(err))
+	} else {
+		dest = withParams
+	}
+
+	return dest
+}
+
+// visitorCountry returns the country of the visitor of r, or an empty
+// string if it is unknown.
+func visitorCountry(r *http.Request, log *slog.Logger, locator CountryLocator) string {
+	if locator == nil {
+		return ""
+	}
+	country, err := locator.Country(r)
+	if err != nil {
+		log.Error("failed to locate visitor", sl.Err(err))
+	}
+	return country
+}
+
+// target returns the URL of the first target matching the platform of the
+// User-Agent ua, or the link URL if there is none.
+func target(link storage.Link, ua string) (string, bool) {
+	device := useragent.Parse(ua)
+	for _, t := range link.Targets {
+		if device.Matches(t.Platform) {
+			return t.URL, true
+		}
+	}
+	return link.URL, false
+}
+
+// geoTarget returns the URL of the geo target for country, or the link URL
+// if there is none.
+func geoTarget(link storage.Link, country string) (string, bool) {
+	if country == "" {
+		return link.URL, false
+	}
+	for _, t := range link.GeoTargets {
+		if t.Country == country {
+			return t.URL, true
+		}
+	}
+	return link.URL, false
+}
+
+// redirectInactive handles a link outside of its activation window. Such
+// visits go to the fallback URL, if any, and are not counted as clicks.
+func redirectInactive(w http.ResponseWriter, r *http.Request, log *slog.Logger, link storage.Link, window storage.Window) {
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if link.FallbackURL != "" {
+		log.Info("link inactive, redirecting to fallback", slog.String("url", link.FallbackURL))
+		http.Redirect(w, r, link.FallbackURL, http.StatusFound)
+		return
+	}
+
+	log.Info("link inactive", slog.String("alias", link.Alias))
+
+	if window == storage.WindowPending {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp.ErrorWithCode(resp.CodeLinkInactive, "link is not active yet"))
+		return
+	}
+
+	render.Status(r, http.StatusGone)
+	render.JSON(w, r, resp.ErrorWithCode(resp.CodeLinkInactive, "link is no longer active"))
+}
+
+func renderGone(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "private, no-store")
+	render.Status(r, http.StatusGone)
+	render.JSON(w, r, resp.ErrorWithCode(resp.CodeLinkExhausted, "link has expired"))
+}
+
+// cacheControl returns the Cache-Control header for a redirect with status.
+// Permanent redirects may be cached, after which clicks no longer reach the
+// server. Temporary ones must not be, so that a changed destination takes
+// effect at once and every click is counted. Private redirects are only
+// cached by the client.
+func cacheControl(status int, permanentMaxAge time.Duration, private bool) string {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		scope := "public"
+		if private {
+			scope = "private"
+		}
+		return fmt.Sprintf("%s, max-age=%d", scope, int(permanentMaxAge.Seconds()))
+	default:
+		return "private, no-store"
 	}
 }
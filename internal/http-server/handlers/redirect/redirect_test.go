@@ -1,54 +1,1547 @@
 package redirect_test
 
 import (
+	"errors"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"link-shortener/internal/http-server/handlers/redirect"
 	"link-shortener/internal/http-server/handlers/redirect/mocks"
-	"link-shortener/internal/lib/api"
 	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/storage"
 )
 
-func TestSaveHandler(t *testing.T) {
+func TestRedirectHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name             string
+		method           string
+		alias            string
+		url              string
+		linkStatus       int
+		defaultStatus    int
+		wantStatus       int
+		wantCacheControl string
+		mockError        error
+		maxClicks        int64
+		usedClicks       int64
+		consume          bool
+		consumeError     error
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
-			url:   "https://www.google.com/",
+			name:             "Success",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			wantStatus:       http.StatusFound,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Server Default",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			defaultStatus:    http.StatusMovedPermanently,
+			wantStatus:       http.StatusMovedPermanently,
+			wantCacheControl: "public, max-age=3600",
+		},
+		{
+			name:             "Permanent Link",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			linkStatus:       http.StatusPermanentRedirect,
+			defaultStatus:    http.StatusFound,
+			wantStatus:       http.StatusPermanentRedirect,
+			wantCacheControl: "public, max-age=3600",
+		},
+		{
+			name:             "Method Preserving Link",
+			method:           http.MethodPost,
+			alias:            "test_alias",
+			url:              "https://api.example.com/hook",
+			linkStatus:       http.StatusTemporaryRedirect,
+			wantStatus:       http.StatusTemporaryRedirect,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Limited Link",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			linkStatus:       http.StatusMovedPermanently,
+			maxClicks:        1,
+			consume:          true,
+			wantStatus:       http.StatusMovedPermanently,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Exhausted Link",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			maxClicks:        1,
+			usedClicks:       1,
+			wantStatus:       http.StatusGone,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Exhausted Concurrently",
+			alias:            "test_alias",
+			url:              "https://www.google.com/",
+			maxClicks:        1,
+			consume:          true,
+			consumeError:     storage.ErrLinkExhausted,
+			wantStatus:       http.StatusGone,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:       "Not Found",
+			alias:      "missing",
+			mockError:  storage.ErrURLNotFound,
+			wantStatus: http.StatusOK,
 		},
 	}
 
 	for _, tc := range cases {
+		tc := tc
+
 		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
 			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickConsumerMock := mocks.NewClickConsumer(t)
+
+			redirected := tc.wantStatus >= 300 && tc.wantStatus < 400
 
-			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+			urlGetterMock.On("GetLink", "", tc.alias).
+				Return(storage.Link{
+					ID:             1,
+					Alias:          tc.alias,
+					URL:            tc.url,
+					RedirectStatus: tc.linkStatus,
+					MaxClicks:      tc.maxClicks,
+					UsedClicks:     tc.usedClicks,
+				}, tc.mockError).
+				Once()
+			if tc.consume {
+				clickConsumerMock.On("ConsumeClick", int64(1)).Return(tc.consumeError).Once()
 			}
+			if redirected {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+					Return(nil).Once()
+			}
+
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, clickConsumerMock, redirect.Options{
+				DefaultStatus:   tc.defaultStatus,
+				PermanentMaxAge: time.Hour,
+			})
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", handler)
+			r.Post("/{alias}", handler)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/"+tc.alias, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			if redirected {
+				assert.Equal(t, tc.url, rr.Header().Get("Location"))
+			}
+			assert.Equal(t, tc.wantCacheControl, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestRedirectPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	const url = "https://docs.example.com/internal"
+
+	cases := []struct {
+		name         string
+		header       string
+		form         string
+		accept       string
+		linkStatus   int
+		blocked      bool
+		wantHit      bool
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:       "Form Shown",
+			accept:     "text/html",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `<input type="password" name="password"`,
+		},
+		{
+			name:       "API Client Without Password",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `"code":"password_required"`,
+		},
+		{
+			name:         "Header",
+			header:       "secret",
+			linkStatus:   http.StatusTemporaryRedirect,
+			wantStatus:   http.StatusTemporaryRedirect,
+			wantLocation: url,
+		},
+		{
+			name:         "Form",
+			form:         "password=secret",
+			linkStatus:   http.StatusTemporaryRedirect,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: url,
+		},
+		{
+			name:       "Wrong Password In Form",
+			form:       "password=guess",
+			wantHit:    true,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "wrong password",
+		},
+		{
+			name:       "Wrong Password In Header",
+			header:     "guess",
+			wantHit:    true,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `"code":"wrong_password"`,
+		},
+		{
+			name:       "Rate Limited",
+			header:     "secret",
+			blocked:    true,
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   `"code":"rate_limited"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickConsumerMock := mocks.NewClickConsumer(t)
+			limiterMock := mocks.NewAttemptLimiter(t)
+
+			urlGetterMock.On("GetLink", "", "doc").
+				Return(storage.Link{ID: 7, Alias: "doc", URL: url, RedirectStatus: tc.linkStatus, PasswordHash: string(hash)}, nil).
+				Once()
+			if tc.header != "" || tc.form != "" {
+				limiterMock.On("Allow", "7").Return(!tc.blocked, time.Minute).Once()
+			}
+			if tc.wantHit {
+				limiterMock.On("Hit", "7").Once()
+			}
+			if tc.wantLocation != "" {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+					Return(nil).Once()
+			}
 
-			ts := httptest.NewServer(r)
-			defer ts.Close()
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, clickConsumerMock, redirect.Options{
+				PermanentMaxAge: time.Hour,
+				AttemptLimiter:  limiterMock,
+			})
 
-			redirectedToURL, err := api.GetRedirect(ts.URL + "/" + tc.alias)
+			r := chi.NewRouter()
+			r.Get("/{alias}", handler)
+			r.Post("/{alias}", handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/doc", nil)
+			if tc.form != "" {
+				req = httptest.NewRequest(http.MethodPost, "/doc", strings.NewReader(tc.form))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tc.header != "" {
+				req.Header.Set(redirect.PasswordHeader, tc.header)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+			assert.Contains(t, rr.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestRedirectActivationWindow(t *testing.T) {
+	const (
+		url      = "https://shop.example.com/sale"
+		fallback = "https://shop.example.com"
+	)
+
+	hourAgo := time.Now().Add(-time.Hour)
+	inHour := time.Now().Add(time.Hour)
+	inMinute := time.Now().Add(time.Minute)
+
+	cases := []struct {
+		name         string
+		from, until  *time.Time
+		fallback     string
+		wantStatus   int
+		wantLocation string
+		// wantCacheControl, if it is the prefix of a max-age, is checked
+		// with wantMaxAge instead.
+		wantCacheControl string
+		wantMaxAge       int
+	}{
+		{
+			name:             "Active",
+			from:             &hourAgo,
+			until:            &inHour,
+			fallback:         fallback,
+			wantStatus:       http.StatusPermanentRedirect,
+			wantLocation:     url,
+			wantCacheControl: "public, max-age=",
+			wantMaxAge:       3600,
+		},
+		{
+			name:             "Cache Capped At End",
+			until:            &inMinute,
+			wantStatus:       http.StatusPermanentRedirect,
+			wantLocation:     url,
+			wantCacheControl: "public, max-age=",
+			wantMaxAge:       60,
+		},
+		{
+			name:             "Pending With Fallback",
+			from:             &inHour,
+			fallback:         fallback,
+			wantStatus:       http.StatusFound,
+			wantLocation:     fallback,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Ended With Fallback",
+			until:            &hourAgo,
+			fallback:         fallback,
+			wantStatus:       http.StatusFound,
+			wantLocation:     fallback,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Pending",
+			from:             &inHour,
+			wantStatus:       http.StatusNotFound,
+			wantCacheControl: "private, no-store",
+		},
+		{
+			name:             "Ended",
+			until:            &hourAgo,
+			wantStatus:       http.StatusGone,
+			wantCacheControl: "private, no-store",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			urlGetterMock.On("GetLink", "", "sale").
+				Return(storage.Link{
+					ID:             1,
+					Alias:          "sale",
+					URL:            url,
+					RedirectStatus: http.StatusPermanentRedirect,
+					ActiveFrom:     tc.from,
+					ActiveUntil:    tc.until,
+					FallbackURL:    tc.fallback,
+				}, nil).
+				Once()
+			// Only visits of an active link are clicks.
+			if tc.wantLocation == url {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+					Return(nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{
+				PermanentMaxAge: time.Hour,
+			}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sale", nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+
+			cache := rr.Header().Get("Cache-Control")
+			if tc.wantMaxAge == 0 {
+				assert.Equal(t, tc.wantCacheControl, cache)
+				return
+			}
+
+			require.True(t, strings.HasPrefix(cache, tc.wantCacheControl), cache)
+			maxAge, err := strconv.Atoi(strings.TrimPrefix(cache, tc.wantCacheControl))
 			require.NoError(t, err)
+			// Allow for the time the test takes.
+			assert.InDelta(t, tc.wantMaxAge, maxAge, 5)
+		})
+	}
+}
+
+func TestRedirectTargets(t *testing.T) {
+	const (
+		web      = "https://example.com/app"
+		appStore = "https://apps.apple.com/app/id1"
+		play     = "https://play.google.com/store/apps/details?id=app"
+		mobile   = "https://m.example.com/app"
+	)
+
+	link := storage.Link{
+		ID:    1,
+		Alias: "app",
+		URL:   web,
+		Targets: []storage.Target{
+			{Platform: "ios", URL: appStore},
+			{Platform: "android", URL: play},
+			{Platform: "mobile", URL: mobile},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		ua           string
+		wantLocation string
+	}{
+		{
+			name:         "iOS",
+			ua:           "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) Mobile/15E148 Safari/604.1",
+			wantLocation: appStore,
+		},
+		{
+			name:         "Android",
+			ua:           "Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/123.0.0.0 Mobile Safari/537.36",
+			wantLocation: play,
+		},
+		{
+			name:         "Other Mobile",
+			ua:           "Mozilla/5.0 (Mobile; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5",
+			wantLocation: mobile,
+		},
+		{
+			name:         "Desktop Falls Back To URL",
+			ua:           "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/123.0.0.0 Safari/537.36",
+			wantLocation: web,
+		},
+		{
+			name:         "No User-Agent",
+			wantLocation: web,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			urlGetterMock.On("GetLink", "", "app").Return(link, nil).Once()
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+				Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/app", nil)
+			req.Header.Set("User-Agent", tc.ua)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			assert.Equal(t, "User-Agent", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestRedirectSplit(t *testing.T) {
+	const (
+		a   = "https://example.com/a"
+		b   = "https://example.com/b"
+		app = "https://apps.apple.com/app/id1"
+	)
+
+	cases := []struct {
+		name    string
+		weights [2]int
+		cookie  string
+		// cookieLink is the ID of the link the cookie sent is for, 1 if zero.
+		cookieLink   int64
+		ua           string
+		wantLocation string
+		wantVariant  string
+		// wantCookie is the variant cookie set, empty if none.
+		wantCookie string
+	}{
+		{
+			name:         "New Visitor",
+			weights:      [2]int{0, 1},
+			wantLocation: b,
+			wantVariant:  b,
+			wantCookie:   "1",
+		},
+		{
+			name:         "Returning Visitor",
+			weights:      [2]int{1, 1},
+			cookie:       "0",
+			wantLocation: a,
+			wantVariant:  a,
+		},
+		{
+			name:         "Variant Paused",
+			weights:      [2]int{0, 1},
+			cookie:       "0",
+			wantLocation: b,
+			wantVariant:  b,
+			wantCookie:   "1",
+		},
+		{
+			name:         "Invalid Cookie",
+			weights:      [2]int{1, 0},
+			cookie:       "7",
+			wantLocation: a,
+			wantVariant:  a,
+			wantCookie:   "0",
+		},
+		{
+			name:         "Cookie Of Another Link",
+			weights:      [2]int{0, 1},
+			cookie:       "0",
+			cookieLink:   2,
+			wantLocation: b,
+			wantVariant:  b,
+			wantCookie:   "1",
+		},
+		{
+			name:         "Target Takes Precedence",
+			weights:      [2]int{1, 1},
+			ua:           "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) Mobile/15E148 Safari/604.1",
+			wantLocation: app,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			urlGetterMock.On("GetLink", "", "ab").
+				Return(storage.Link{
+					ID:      1,
+					Alias:   "ab",
+					URL:     "https://example.com",
+					Targets: []storage.Target{{Platform: "ios", URL: app}},
+					Destinations: []storage.Destination{
+						{URL: a, Weight: tc.weights[0]},
+						{URL: b, Weight: tc.weights[1]},
+					},
+				}, nil).
+				Once()
+			clickRecorderMock.On("RecordClick", mock.MatchedBy(func(click storage.Click) bool {
+				return click.Variant == tc.wantVariant
+			})).Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			req.Header.Set("User-Agent", tc.ua)
+			if tc.cookie != "" {
+				cookieLink := tc.cookieLink
+				if cookieLink == 0 {
+					cookieLink = 1
+				}
+				req.AddCookie(&http.Cookie{Name: redirect.VariantCookie(cookieLink), Value: tc.cookie})
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+
+			var cookie string
+			for _, c := range rr.Result().Cookies() {
+				if c.Name == redirect.VariantCookie(1) {
+					cookie = c.Value
+					assert.Equal(t, "/ab", c.Path)
+				}
+			}
+			assert.Equal(t, tc.wantCookie, cookie)
+		})
+	}
+}
+
+func TestRedirectSplitWeights(t *testing.T) {
+	const draws = 2000
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	clickRecorderMock := mocks.NewClickRecorder(t)
+
+	urlGetterMock.On("GetLink", "", "ab").
+		Return(storage.Link{
+			ID:    1,
+			Alias: "ab",
+			URL:   "https://example.com",
+			Destinations: []storage.Destination{
+				{URL: "https://example.com/a", Weight: 3},
+				{URL: "https://example.com/b", Weight: 1},
+			},
+		}, nil)
+	clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{}))
+
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ab", nil))
+		counts[rr.Header().Get("Location")]++
+	}
+
+	// 1500 expected; the bounds are more than ten standard deviations away.
+	assert.InDelta(t, 1500, counts["https://example.com/a"], 200)
+	assert.Equal(t, draws, counts["https://example.com/a"]+counts["https://example.com/b"])
+}
+
+func TestRedirectGeoTargets(t *testing.T) {
+	const (
+		web = "https://shop.example.com"
+		de  = "https://shop.example.de"
+		app = "https://apps.apple.com/app/id1"
+	)
+
+	cases := []struct {
+		name         string
+		country      string
+		locateError  error
+		ua           string
+		wantLocation string
+	}{
+		{
+			name:         "Matching Country",
+			country:      "DE",
+			wantLocation: de,
+		},
+		{
+			name:         "Other Country",
+			country:      "GB",
+			wantLocation: web,
+		},
+		{
+			name:         "Unknown Country",
+			wantLocation: web,
+		},
+		{
+			name:         "Lookup Error",
+			locateError:  errors.New("corrupt database"),
+			wantLocation: web,
+		},
+		{
+			name:         "Platform Target First",
+			country:      "DE",
+			ua:           "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) Mobile/15E148 Safari/604.1",
+			wantLocation: app,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			locatorMock := mocks.NewCountryLocator(t)
+
+			urlGetterMock.On("GetLink", "", "shop").
+				Return(storage.Link{
+					ID:             1,
+					Alias:          "shop",
+					URL:            web,
+					RedirectStatus: http.StatusMovedPermanently,
+					Targets:        []storage.Target{{Platform: "ios", URL: app}},
+					GeoTargets:     []storage.GeoTarget{{Country: "DE", URL: de}},
+				}, nil).
+				Once()
+			locatorMock.On("Country", mock.Anything).Return(tc.country, tc.locateError).Once()
+			clickRecorderMock.On("RecordClick", mock.MatchedBy(func(click storage.Click) bool {
+				return click.Country == tc.country
+			})).Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{
+				PermanentMaxAge: time.Hour,
+				CountryLocator:  locatorMock,
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/shop", nil)
+			req.Header.Set("User-Agent", tc.ua)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			assert.Equal(t, "private, max-age=3600", rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestRedirectQuery(t *testing.T) {
+	utm := []storage.QueryParam{
+		{Key: "utm_source", Value: "newsletter"},
+		{Key: "utm_campaign", Value: "{alias}"},
+		{Key: "utm_content", Value: "{country}"},
+	}
+
+	cases := []struct {
+		name         string
+		url          string
+		params       []storage.QueryParam
+		passthrough  bool
+		query        string
+		country      string
+		wantLocation string
+	}{
+		{
+			name:         "Template",
+			url:          "https://example.com/sale#terms",
+			params:       utm,
+			country:      "DE",
+			wantLocation: "https://example.com/sale?utm_campaign=spring&utm_content=DE&utm_source=newsletter#terms",
+		},
+		{
+			name:         "Empty Placeholder Dropped",
+			url:          "https://example.com/sale",
+			params:       utm,
+			wantLocation: "https://example.com/sale?utm_campaign=spring&utm_source=newsletter",
+		},
+		{
+			name:         "Destination Parameters Kept",
+			url:          "https://example.com/sale?utm_source=site",
+			params:       utm[:1],
+			wantLocation: "https://example.com/sale?utm_source=site",
+		},
+		{
+			name:         "Query Ignored Without Passthrough",
+			url:          "https://example.com/sale",
+			query:        "?ref=x",
+			wantLocation: "https://example.com/sale",
+		},
+		{
+			name:         "Passthrough",
+			url:          "https://example.com/sale?id=1",
+			passthrough:  true,
+			query:        "?ref=x&id=2",
+			wantLocation: "https://example.com/sale?id=1&ref=x",
+		},
+		{
+			name:         "Passthrough Overrides Template",
+			url:          "https://example.com/sale",
+			params:       utm[:1],
+			passthrough:  true,
+			query:        "?utm_source=twitter",
+			wantLocation: "https://example.com/sale?utm_source=twitter",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
 
-			// Check the final URL after redirection.
-			assert.Equal(t, tc.url, redirectedToURL)
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			locatorMock := mocks.NewCountryLocator(t)
+
+			urlGetterMock.On("GetLink", "", "spring").
+				Return(storage.Link{
+					ID:               1,
+					Alias:            "spring",
+					URL:              tc.url,
+					QueryParams:      tc.params,
+					QueryPassthrough: tc.passthrough,
+				}, nil).
+				Once()
+			locatorMock.On("Country", mock.Anything).Return(tc.country, nil).Once()
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{
+				CountryLocator: locatorMock,
+			}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/spring"+tc.query, nil))
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectPrefix(t *testing.T) {
+	cases := []struct {
+		name         string
+		url          string
+		prefix       bool
+		path         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Alias Only",
+			url:          "https://example.com/docs",
+			prefix:       true,
+			path:         "/docs",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs",
+		},
+		{
+			name:         "Suffix Appended",
+			url:          "https://example.com/docs",
+			prefix:       true,
+			path:         "/docs/api/v2",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs/api/v2",
+		},
+		{
+			name:         "Destination With Trailing Slash",
+			url:          "https://example.com/docs/",
+			prefix:       true,
+			path:         "/docs/api/",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs/api/",
+		},
+		{
+			name:         "Destination Query And Fragment Kept",
+			url:          "https://example.com/docs?lang=en#top",
+			prefix:       true,
+			path:         "/docs/api",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs/api?lang=en#top",
+		},
+		{
+			name:         "Extension Kept",
+			url:          "https://example.com/docs",
+			prefix:       true,
+			path:         "/docs/openapi.json",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs/openapi.json",
+		},
+		{
+			name:         "Escaped Segment",
+			url:          "https://example.com/docs",
+			prefix:       true,
+			path:         "/docs/a%20b",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.com/docs/a%20b",
+		},
+		{
+			name:     "Dot Segment",
+			url:      "https://example.com/docs",
+			prefix:   true,
+			path:     "/docs/../admin",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Encoded Dot Segment",
+			url:      "https://example.com/docs",
+			prefix:   true,
+			path:     "/docs/%2e%2e/admin",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Encoded Slash",
+			url:      "https://example.com/docs",
+			prefix:   true,
+			path:     "/docs/..%2F..%2Fadmin",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Encoded Backslash",
+			url:      "https://example.com/docs",
+			prefix:   true,
+			path:     "/docs/%5Cevil.com",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty Segment",
+			url:      "https://example.com/docs",
+			prefix:   true,
+			path:     "/docs//evil.com",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Not A Prefix Link",
+			url:      "https://example.com/docs",
+			path:     "/docs/api",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			urlGetterMock.On("GetLink", "", "docs").
+				Return(storage.Link{ID: 1, Alias: "docs", URL: tc.url, Prefix: tc.prefix}, nil).
+				Once()
+			if tc.wantCode == http.StatusFound {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+			}
+
+			h := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{})
+			r := chi.NewRouter()
+			r.Get("/{alias}", h)
+			r.Get("/{alias}/*", h)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			assert.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			if tc.wantCode == http.StatusOK {
+				assert.Contains(t, rr.Body.String(), "not found")
+			}
+		})
+	}
+}
+
+func TestRedirectPreview(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name         string
+		link         storage.Link
+		path         string
+		wantCode     int
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name:     "Plus Suffix",
+			link:     storage.Link{URL: "https://example.com/sale"},
+			path:     "/spring+",
+			wantCode: http.StatusOK,
+			wantContains: []string{
+				"https://example.com/sale",
+				`<img src="https://example.com/favicon.ico"`,
+				`<span>example.com</span>`,
+				`href="/spring"`,
+			},
+		},
+		{
+			name:         "Query Parameter",
+			link:         storage.Link{URL: "https://example.com/sale"},
+			path:         "/spring?preview=1&ref=x",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"https://example.com/sale", `href="/spring?ref=x"`},
+		},
+		{
+			name: "Suffix And Passthrough",
+			link: storage.Link{
+				URL:              "https://example.com/docs",
+				Prefix:           true,
+				QueryPassthrough: true,
+			},
+			path:         "/spring+/api?ref=x",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"https://example.com/docs/api?ref=x", `href="/spring/api?ref=x"`},
+		},
+		{
+			name: "Page Metadata",
+			link: storage.Link{
+				URL:         "https://example.com/sale",
+				Title:       "Spring Sale",
+				Description: "Everything half off.",
+				Image:       "https://example.com/sale.png",
+			},
+			path:     "/spring+",
+			wantCode: http.StatusOK,
+			wantContains: []string{
+				`<span>Spring Sale</span>`,
+				`<p class="description">Everything half off.</p>`,
+				`src="https://example.com/sale.png"`,
+			},
+		},
+		{
+			name: "Metadata Not Shown For Fallback",
+			link: storage.Link{
+				URL:         "https://example.com/sale",
+				Title:       "Spring Sale",
+				ActiveUntil: &past,
+				FallbackURL: "https://example.com/over",
+			},
+			path:         "/spring+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{`<span>example.com</span>`},
+			wantMissing:  []string{"Spring Sale"},
+		},
+		{
+			name:         "Password Hides Destination",
+			link:         storage.Link{URL: "https://example.com/secret", PasswordHash: string(hash)},
+			path:         "/spring+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"password protected"},
+			wantMissing:  []string{"example.com"},
+		},
+		{
+			name:         "One-Time Link Hides Destination",
+			link:         storage.Link{URL: "https://secret.example/s3cr3t", Title: "Secret", MaxClicks: 1},
+			path:         "/spring+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"secret.example", `href="/spring"`},
+			wantMissing:  []string{"s3cr3t", "Secret", "favicon"},
+		},
+		{
+			name: "Limited Split Link Hides Variants",
+			link: storage.Link{
+				URL:       "https://example.com/",
+				MaxClicks: 5,
+				Destinations: []storage.Destination{
+					{URL: "https://example.com/a", Weight: 1},
+					{URL: "https://example.com/b", Weight: 1},
+				},
+			},
+			path:        "/spring+",
+			wantCode:    http.StatusOK,
+			wantMissing: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name: "Variants Listed",
+			link: storage.Link{
+				URL: "https://example.com/",
+				Destinations: []storage.Destination{
+					{URL: "https://example.com/a", Weight: 1},
+					{URL: "https://example.com/b", Weight: 1},
+					{URL: "https://example.com/off", Weight: 0},
+				},
+			},
+			path:         "/spring+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"https://example.com/a", "https://example.com/b"},
+			wantMissing:  []string{"https://example.com/off"},
+		},
+		{
+			name:         "Inactive With Fallback",
+			link:         storage.Link{URL: "https://example.com/sale", ActiveUntil: &past, FallbackURL: "https://example.com/over"},
+			path:         "/spring+",
+			wantCode:     http.StatusOK,
+			wantContains: []string{"https://example.com/over"},
+		},
+		{
+			name:     "Inactive",
+			link:     storage.Link{URL: "https://example.com/sale", ActiveUntil: &past},
+			path:     "/spring+",
+			wantCode: http.StatusGone,
+		},
+		{
+			name:     "Exhausted",
+			link:     storage.Link{URL: "https://example.com/sale", MaxClicks: 1, UsedClicks: 1},
+			path:     "/spring+",
+			wantCode: http.StatusGone,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			link := tc.link
+			link.ID, link.Alias = 1, "spring"
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "", "spring").Return(link, nil).Once()
+
+			// Neither mock expects a call: a preview is not a click.
+			h := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, mocks.NewClickRecorder(t), mocks.NewClickConsumer(t), redirect.Options{})
+			r := chi.NewRouter()
+			r.Get("/{alias}", h)
+			r.Get("/{alias}/*", h)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			for _, s := range tc.wantContains {
+				assert.Contains(t, rr.Body.String(), s)
+			}
+			for _, s := range tc.wantMissing {
+				assert.NotContains(t, rr.Body.String(), s)
+			}
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestRedirectInterstitial(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		url         string
+		linkWarns   bool
+		globalWarns bool
+		maxClicks   int64
+		protected   bool
+		accept      string
+		query       string
+		wantWarning bool
+		// wantHidden is set if the warning must not reveal the destination.
+		wantHidden bool
+	}{
+		{
+			name:        "Link Option",
+			url:         "https://example.com/",
+			linkWarns:   true,
+			accept:      "text/html",
+			wantWarning: true,
+		},
+		{
+			name:        "Global Option",
+			url:         "https://example.com/",
+			globalWarns: true,
+			accept:      "text/html",
+			wantWarning: true,
+		},
+		{
+			name:   "Off",
+			url:    "https://example.com/",
+			accept: "text/html",
+		},
+		{
+			name:        "Internal Domain",
+			url:         "https://sho.rt/about",
+			globalWarns: true,
+			accept:      "text/html",
+		},
+		{
+			name:        "Internal Subdomain",
+			url:         "https://docs.Sho.rt/",
+			globalWarns: true,
+			accept:      "text/html",
+		},
+		{
+			name:        "Lookalike Domain",
+			url:         "https://evilsho.rt/",
+			globalWarns: true,
+			accept:      "text/html",
+			wantWarning: true,
+		},
+		{
+			name:      "API Client",
+			url:       "https://example.com/",
+			linkWarns: true,
+			accept:    "application/json",
+		},
+		{
+			name:        "One-Time Link Keeps Its Click",
+			url:         "https://example.com/s3cr3t",
+			linkWarns:   true,
+			maxClicks:   1,
+			accept:      "text/html",
+			query:       "?ref=x",
+			wantWarning: true,
+			wantHidden:  true,
+		},
+		{
+			name:        "Protected Link Hides Destination",
+			url:         "https://example.com/s3cr3t",
+			linkWarns:   true,
+			protected:   true,
+			accept:      "text/html",
+			wantWarning: true,
+			wantHidden:  true,
+		},
+		{
+			name:      "Confirmed",
+			url:       "https://example.com/",
+			linkWarns: true,
+			maxClicks: 1,
+			accept:    "text/html",
+			query:     "?confirmed=1",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickConsumerMock := mocks.NewClickConsumer(t)
+
+			link := storage.Link{ID: 1, Alias: "spring", URL: tc.url, Interstitial: tc.linkWarns, MaxClicks: tc.maxClicks}
+			if tc.protected {
+				link.PasswordHash = string(hash)
+			}
+			urlGetterMock.On("GetLink", "", "spring").Return(link, nil).Once()
+			// A visitor who backs out of the warning has made no click.
+			if !tc.wantWarning {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+				if tc.maxClicks > 0 {
+					clickConsumerMock.On("ConsumeClick", int64(1)).Return(nil).Once()
+				}
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, clickConsumerMock, redirect.Options{
+				Interstitial:    tc.globalWarns,
+				InternalDomains: []string{"sho.rt"},
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/spring"+tc.query, nil)
+			req.Header.Set("Accept", tc.accept)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if !tc.wantWarning {
+				require.Equal(t, http.StatusFound, rr.Code)
+				assert.Equal(t, tc.url, rr.Header().Get("Location"))
+				return
+			}
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Contains(t, rr.Body.String(), "You are leaving for another site")
+			switch {
+			case tc.protected:
+				assert.NotContains(t, rr.Body.String(), "example.com")
+				assert.Contains(t, rr.Body.String(), "password protected")
+			case tc.wantHidden:
+				assert.NotContains(t, rr.Body.String(), "s3cr3t")
+				assert.Contains(t, rr.Body.String(), "example.com")
+			default:
+				assert.Contains(t, rr.Body.String(), tc.url)
+			}
+
+			// Continuing follows the short URL, keeping the query.
+			query := "?confirmed=1"
+			if tc.query != "" {
+				query = tc.query + "&amp;confirmed=1"
+			}
+			assert.Contains(t, rr.Body.String(), `href="/spring`+query+`"`)
 		})
 	}
 }
+
+func TestRedirectUnfurl(t *testing.T) {
+	const (
+		slackbot = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+		browser  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"
+	)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	fetched := storage.Link{
+		URL:         "https://example.com/login",
+		Title:       "Sign in",
+		Description: "Sign in to continue.",
+		Image:       "https://example.com/logo.png",
+	}
+
+	cases := []struct {
+		name         string
+		link         storage.Link
+		ua           string
+		off          bool
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name: "Fetched Tags",
+			link: fetched,
+			ua:   slackbot,
+			wantContains: []string{
+				`<meta property="og:title" content="Sign in">`,
+				`<meta property="og:description" content="Sign in to continue.">`,
+				`<meta property="og:image" content="https://example.com/logo.png">`,
+				`<meta property="og:site_name" content="example.com">`,
+			},
+		},
+		{
+			name: "Custom Tags",
+			link: func() storage.Link {
+				l := fetched
+				l.Unfurl = &storage.PageMeta{Title: "Team Wiki", Image: "https://cdn.example.com/wiki.png"}
+				return l
+			}(),
+			ua: slackbot,
+			wantContains: []string{
+				`<meta property="og:title" content="Team Wiki">`,
+				`<meta property="og:description" content="Sign in to continue.">`,
+				`<meta property="og:image" content="https://cdn.example.com/wiki.png">`,
+			},
+		},
+		{
+			name:         "Nothing Fetched",
+			link:         storage.Link{URL: "https://example.com/login"},
+			ua:           slackbot,
+			wantContains: []string{`<meta property="og:title" content="example.com">`, `content="summary"`},
+			wantMissing:  []string{"og:image", "og:description"},
+		},
+		{
+			name: "Protected",
+			link: func() storage.Link {
+				l := fetched
+				l.PasswordHash = string(hash)
+				return l
+			}(),
+			ua:           slackbot,
+			wantContains: []string{`<meta property="og:title" content="spring">`},
+			wantMissing:  []string{"example.com", "Sign in"},
+		},
+		{
+			name: "One-Time Link",
+			link: func() storage.Link {
+				l := fetched
+				l.MaxClicks = 1
+				return l
+			}(),
+			ua:           slackbot,
+			wantContains: []string{`<meta property="og:title" content="spring">`},
+			wantMissing:  []string{"example.com", "Sign in", "og:image", "og:description"},
+		},
+		{
+			name: "Browser",
+			link: fetched,
+			ua:   browser,
+		},
+		{
+			name: "Off",
+			link: fetched,
+			ua:   slackbot,
+			off:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			link := tc.link
+			link.ID, link.Alias = 1, "spring"
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetLink", "", "spring").Return(link, nil).Once()
+
+			// Crawlers are not visitors: their visits are not counted.
+			unfurled := tc.wantContains != nil
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if !unfurled {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{
+				Unfurl: !tc.off,
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/spring", nil)
+			req.Header.Set("User-Agent", tc.ua)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if !unfurled {
+				require.Equal(t, http.StatusFound, rr.Code)
+				assert.Equal(t, link.URL, rr.Header().Get("Location"))
+				if !tc.off {
+					assert.Equal(t, "User-Agent", rr.Header().Get("Vary"))
+				}
+				return
+			}
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+			for _, s := range tc.wantContains {
+				assert.Contains(t, rr.Body.String(), s)
+			}
+			for _, s := range tc.wantMissing {
+				assert.NotContains(t, rr.Body.String(), s)
+			}
+		})
+	}
+}
+
+func newDomains(t *testing.T) *shortdomain.Set {
+	t.Helper()
+
+	page := filepath.Join(t.TempDir(), "404.html")
+	require.NoError(t, os.WriteFile(page, []byte("<h1>No such Acme link</h1>"), 0o600))
+
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{
+		{Host: "go.acme.com", RootURL: "https://acme.com", NotFoundPage: page},
+	})
+	require.NoError(t, err)
+	return domains
+}
+
+func TestRedirectDomains(t *testing.T) {
+	const html = "text/html,application/xhtml+xml"
+
+	domains := newDomains(t)
+
+	cases := []struct {
+		name       string
+		host       string
+		accept     string
+		wantDomain string
+		found      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Default Domain",
+			host:       "sho.rt",
+			found:      true,
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "Other Domain",
+			host:       "GO.ACME.COM:443",
+			wantDomain: "go.acme.com",
+			found:      true,
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "Missing Page",
+			host:       "go.acme.com",
+			accept:     html,
+			wantDomain: "go.acme.com",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "<h1>No such Acme link</h1>",
+		},
+		{
+			name:       "Missing API Client",
+			host:       "go.acme.com",
+			wantDomain: "go.acme.com",
+			wantStatus: http.StatusOK,
+			wantBody:   `"code":"not_found"`,
+		},
+		{
+			name:       "Missing Without Page",
+			host:       "sho.rt",
+			accept:     html,
+			wantStatus: http.StatusOK,
+			wantBody:   `"code":"not_found"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.found {
+				urlGetterMock.On("GetLink", tc.wantDomain, "docs").
+					Return(storage.Link{ID: 1, Domain: tc.wantDomain, Alias: "docs", URL: "https://example.com/" + tc.wantDomain}, nil).
+					Once()
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+			} else {
+				urlGetterMock.On("GetLink", tc.wantDomain, "docs").Return(storage.Link{}, storage.ErrURLNotFound).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, mocks.NewClickConsumer(t), redirect.Options{
+				Domains: domains,
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			req.Host = tc.host
+			req.Header.Set("Accept", tc.accept)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			if tc.found {
+				assert.Equal(t, "https://example.com/"+tc.wantDomain, rr.Header().Get("Location"))
+			}
+			assert.Contains(t, rr.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestRedirectRoot(t *testing.T) {
+	handler := redirect.NewRoot(slogdiscard.NewDiscardLogger(), newDomains(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "go.acme.com"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://acme.com", rr.Header().Get("Location"))
+
+	// The default domain has no root URL.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "sho.rt"
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Empty(t, rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), `"code":"not_found"`)
+}
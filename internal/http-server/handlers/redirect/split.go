@@ -0,0 +1,84 @@
+package redirect
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"link-shortener/internal/storage"
+)
+
+// variantCookieMaxAge is how long a visitor keeps the variant of a split
+// link.
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// VariantCookie returns the name of the cookie that remembers which
+// destination of the split link with linkID a visitor was sent to. Aliases
+// can repeat across domains, so the name holds the ID.
+func VariantCookie(linkID int64) string {
+	return "lsv_" + strconv.FormatInt(linkID, 10)
+}
+
+// pickVariant returns the destination a visitor of a split link is sent to.
+// Returning visitors keep their variant as long as it is still served;
+// others get one at random by weight, and a cookie to remember it by. The
+// cookie holds the position of the destination, so reordering destinations
+// reassigns visitors.
+func pickVariant(w http.ResponseWriter, r *http.Request, link storage.Link) string {
+	if dest, ok := stickyVariant(r, link); ok {
+		return dest
+	}
+
+	i := weightedIndex(link.Destinations)
+	if i < 0 {
+		return link.URL
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     VariantCookie(link.ID),
+		Value:    strconv.Itoa(i),
+		Path:     "/" + link.Alias,
+		MaxAge:   int(variantCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return link.Destinations[i].URL
+}
+
+// stickyVariant returns the destination the variant cookie of r names, if
+// it is still served.
+func stickyVariant(r *http.Request, link storage.Link) (string, bool) {
+	c, err := r.Cookie(VariantCookie(link.ID))
+	if err != nil {
+		return "", false
+	}
+
+	i, err := strconv.Atoi(c.Value)
+	if err != nil || i < 0 || i >= len(link.Destinations) || link.Destinations[i].Weight <= 0 {
+		return "", false
+	}
+	return link.Destinations[i].URL, true
+}
+
+// weightedIndex picks a destination at random by weight, or returns -1 if
+// all weights are zero.
+func weightedIndex(destinations []storage.Destination) int {
+	total := 0
+	for _, d := range destinations {
+		total += max(d.Weight, 0)
+	}
+	if total == 0 {
+		return -1
+	}
+
+	n := rand.IntN(total)
+	for i, d := range destinations {
+		n -= max(d.Weight, 0)
+		if n < 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package redirect
+
+import (
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/storage"
+)
+
+//go:embed unfurl.html
+var unfurlPageHTML string
+
+var unfurlPage = template.Must(template.New("unfurl").Parse(unfurlPageHTML))
+
+// unfurlData fills in unfurl.html.
+type unfurlData struct {
+	Title       string
+	Description string
+	Image       string
+	// SiteName is the host of the destination, if it may be shown.
+	SiteName string
+}
+
+// renderUnfurl shows the crawler of a chat app the tags of link, rather
+// than letting it follow the redirect and preview whatever the destination
+// shows to visitors without a session, such as a login page. The tags the
+// link sets take precedence over those fetched from its page. A protected
+// or click-limited link only shows its own, as the fetched ones would give
+// away the destination its preview hides.
+func renderUnfurl(w http.ResponseWriter, log *slog.Logger, link storage.Link) {
+	var data unfurlData
+	if link.PasswordHash == "" && link.MaxClicks == 0 {
+		data.Title, data.Description, data.Image = link.Title, link.Description, link.Image
+		if u, err := url.Parse(link.URL); err == nil {
+			data.SiteName = u.Hostname()
+		}
+	}
+	if link.Unfurl != nil {
+		data.Title = first(link.Unfurl.Title, data.Title)
+		data.Description = first(link.Unfurl.Description, data.Description)
+		data.Image = first(link.Unfurl.Image, data.Image)
+	}
+	data.Title = first(data.Title, data.SiteName, link.Alias)
+
+	// Browsers must never be handed the page a crawler got, nor the
+	// other way around.
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Vary", "User-Agent")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := unfurlPage.Execute(w, data); err != nil {
+		log.Error("failed to render unfurl page", sl.Err(err))
+	}
+}
+
+// first returns the first of values that is not empty.
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
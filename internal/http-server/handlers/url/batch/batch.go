@@ -0,0 +1,298 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"link-shortener/internal/http-server/handlers/url/save"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// ModeBestEffort saves every valid link and reports the rest.
+	ModeBestEffort = "best_effort"
+	// ModeAllOrNothing saves the links only if all of them can be saved.
+	ModeAllOrNothing = "all_or_nothing"
+)
+
+// maxLinks bounds the size of a single batch.
+const maxLinks = 1000
+
+// resolveWorkers bounds how many redirect chains of a batch are resolved at
+// once.
+const resolveWorkers = 16
+
+var errResolveTimeout = errors.New("redirect check timed out")
+
+type Request struct {
+	Links []save.Request `json:"links"`
+	Mode  string         `json:"mode,omitempty"`
+}
+
+// Result is the outcome for the link at Index in the request.
+type Result struct {
+	Index int    `json:"index"`
+	Alias string `json:"alias,omitempty"`
+	ID    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Created int      `json:"created"`
+	Failed  int      `json:"failed"`
+	Results []Result `json:"results,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLBatchSaver
+type URLBatchSaver interface {
+	SaveURLs(links []storage.Link, atomic bool) ([]storage.SaveResult, error)
+}
+
+// New returns the handler creating links in bulk. Each link is validated
+// like save.New does, with policy and on one of domains, has its redirect
+// chains resolved by resolver, if it is not nil, and is handed to meta once
+// saved, if it is not nil. The chains of all links are resolved within
+// resolveTimeout together, if it is positive, so that a large batch is
+// answered in time; links whose chains are not resolved by then fail. Requests acting for a
+// workspace create links of the workspace, like save.New.
+func New(
+	log *slog.Logger,
+	saver URLBatchSaver,
+	policy save.URLPolicy,
+	resolver save.URLResolver,
+	resolveTimeout time.Duration,
+	meta save.MetadataQueue,
+	domains *shortdomain.Set,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.batch.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to parse request body", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "failed to decode request"))
+			return
+		}
+
+		if req.Mode == "" {
+			req.Mode = ModeBestEffort
+		}
+		if req.Mode != ModeBestEffort && req.Mode != ModeAllOrNothing {
+			log.Info("invalid mode", slog.String("mode", req.Mode))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid mode"))
+			return
+		}
+		if len(req.Links) == 0 || len(req.Links) > maxLinks {
+			log.Info("invalid batch size", slog.Int("size", len(req.Links)))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest,
+				fmt.Sprintf("batch must contain between 1 and %d links", maxLinks)))
+			return
+		}
+
+		atomic := req.Mode == ModeAllOrNothing
+		ws, inWorkspace := workspace.FromContext(r.Context())
+
+		results := make([]Result, len(req.Links))
+		items := make([]save.Request, len(req.Links))
+		var valid []int
+
+		for i, item := range req.Links {
+			results[i].Index = i
+
+			if item.Domain, err = domains.Normalize(item.Domain); err != nil {
+				results[i].Error = err.Error()
+				results[i].Code = response.CodeValidation
+				continue
+			}
+
+			if inWorkspace {
+				item.Alias, err = save.WorkspaceAlias(ws, domains, item)
+			} else {
+				err = workspace.CheckUnowned(item.Alias)
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+				results[i].Code = response.CodeValidation
+				continue
+			}
+
+			if err := save.Validate(item, policy); err != nil {
+				results[i].Error = err.Error()
+				results[i].Code = save.ErrorCode(err)
+				continue
+			}
+
+			items[i] = item
+			valid = append(valid, i)
+		}
+
+		if resolver != nil && len(valid) > 0 {
+			ctx := r.Context()
+			if resolveTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, resolveTimeout)
+				defer cancel()
+			}
+			errs := resolveAll(ctx, resolver, items, valid)
+
+			resolved := valid[:0]
+			for _, i := range valid {
+				if errs[i] != nil {
+					results[i].Error = errs[i].Error()
+					results[i].Code = save.ErrorCode(errs[i])
+					continue
+				}
+				// The page of the resolved URL is what metadata is fetched from.
+				req.Links[i].URL = items[i].URL
+				resolved = append(resolved, i)
+			}
+			valid = resolved
+		}
+
+		var (
+			links   []storage.Link
+			indexes []int
+		)
+
+		for _, i := range valid {
+			link, err := items[i].Link()
+			if err != nil {
+				log.Error("failed to hash password", slog.Int("index", i), sl.Err(err))
+				results[i].Error = "failed to save url"
+				results[i].Code = response.CodeInternal
+				continue
+			}
+			if link.Alias == "" {
+				link.Alias = save.NewAlias()
+			}
+			link.WorkspaceID = ws.ID
+			results[i].Alias = link.Alias
+
+			links = append(links, link)
+			indexes = append(indexes, i)
+		}
+
+		// In all-or-nothing mode an invalid link means nothing is saved, so
+		// there is no point in touching the storage.
+		if len(links) > 0 && (!atomic || len(links) == len(req.Links)) {
+			saved, err := saver.SaveURLs(links, atomic)
+			if err != nil {
+				log.Error("failed to save urls", sl.Err(err))
+				render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to save urls"))
+				return
+			}
+
+			for j, res := range saved {
+				i := indexes[j]
+				switch {
+				case errors.Is(res.Err, storage.ErrURLExist):
+					results[i].Error = "url already exists"
+					results[i].Code = response.CodeAliasExists
+				case errors.Is(res.Err, storage.ErrLinkQuota):
+					results[i].Error = "link quota exceeded"
+					results[i].Code = response.CodeLinkQuota
+				case errors.Is(res.Err, storage.ErrDailyQuota):
+					results[i].Error = "daily quota exceeded"
+					results[i].Code = response.CodeDailyQuota
+				case res.Err != nil:
+					log.Error("failed to save url", slog.Int("index", i), sl.Err(res.Err))
+					results[i].Error = "failed to save url"
+					results[i].Code = response.CodeInternal
+				default:
+					results[i].ID = res.ID
+				}
+			}
+		}
+
+		resp := Response{Response: response.OK(), Results: results}
+		for _, res := range results {
+			if res.Code != "" {
+				resp.Failed++
+			}
+		}
+
+		if atomic && resp.Failed > 0 {
+			for i := range results {
+				if results[i].Code == "" {
+					results[i].ID = 0
+					results[i].Error = "not saved because another link in the batch failed"
+					results[i].Code = response.CodeRolledBack
+				}
+			}
+			resp.Response = response.ErrorWithCode(response.CodeBatchFailed, "batch rolled back")
+		} else {
+			resp.Created = len(results) - resp.Failed
+			if meta != nil {
+				for i, res := range results {
+					if res.ID != 0 {
+						meta.Enqueue(res.ID, req.Links[i].URL)
+					}
+				}
+			}
+		}
+
+		log.Info("batch processed",
+			slog.String("mode", req.Mode),
+			slog.Int("created", resp.Created),
+			slog.Int("failed", resp.Failed),
+		)
+
+		render.JSON(w, r, resp)
+	}
+}
+
+// resolveAll resolves the redirect chains of the items at indexes, by at
+// most resolveWorkers at once, and returns the error of each by index.
+// Items not started before ctx ends fail with errResolveTimeout.
+func resolveAll(ctx context.Context, resolver save.URLResolver, items []save.Request, indexes []int) []error {
+	errs := make([]error, len(items))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(resolveWorkers, len(indexes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					errs[i] = errResolveTimeout
+					continue
+				}
+				errs[i] = save.Resolve(ctx, &items[i], resolver)
+			}
+		}()
+	}
+
+	for _, i := range indexes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,262 @@
+package batch_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/batch"
+	"link-shortener/internal/http-server/handlers/url/batch/mocks"
+	saveMocks "link-shortener/internal/http-server/handlers/url/save/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/storage"
+)
+
+func TestBatchHandler(t *testing.T) {
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{{Host: "go.acme.com"}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		body        string
+		saveAtomic  *bool
+		saveResults []storage.SaveResult
+		saveError   error
+		respError   string
+		created     int
+		failed      int
+		codes       []string
+	}{
+		{
+			name:        "Best Effort Success",
+			body:        `{"links": [{"url": "https://google.com", "alias": "g"}, {"url": "https://go.dev"}]}`,
+			saveAtomic:  boolPtr(false),
+			saveResults: []storage.SaveResult{{ID: 1}, {ID: 2}},
+			created:     2,
+			codes:       []string{"", ""},
+		},
+		{
+			name:        "Best Effort Partial",
+			body:        `{"links": [{"url": "bad"}, {"url": "https://google.com", "alias": "g"}, {"url": "https://go.dev", "alias": "d"}]}`,
+			saveAtomic:  boolPtr(false),
+			saveResults: []storage.SaveResult{{Err: storage.ErrURLExist}, {ID: 3}},
+			created:     1,
+			failed:      2,
+			codes:       []string{"validation_failed", "alias_exists", ""},
+		},
+		{
+			name:      "All Or Nothing Invalid Link",
+			body:      `{"mode": "all_or_nothing", "links": [{"url": "https://google.com"}, {"url": "https://go.dev", "alias": "!"}]}`,
+			respError: "batch rolled back",
+			failed:    1,
+			codes:     []string{"rolled_back", "validation_failed"},
+		},
+		{
+			name:        "All Or Nothing Conflict",
+			body:        `{"mode": "all_or_nothing", "links": [{"url": "https://google.com", "alias": "g"}, {"url": "https://go.dev", "alias": "d"}]}`,
+			saveAtomic:  boolPtr(true),
+			saveResults: []storage.SaveResult{{}, {Err: storage.ErrURLExist}},
+			respError:   "batch rolled back",
+			failed:      1,
+			codes:       []string{"rolled_back", "alias_exists"},
+		},
+		{
+			name:        "Domains",
+			body:        `{"links": [{"url": "https://google.com", "alias": "g", "domain": "Go.Acme.com"}, {"url": "https://go.dev", "domain": "sho.rt"}]}`,
+			saveAtomic:  boolPtr(false),
+			saveResults: []storage.SaveResult{{ID: 1}},
+			created:     1,
+			failed:      1,
+			codes:       []string{"", "validation_failed"},
+		},
+		{
+			name:        "Workspace Alias",
+			body:        `{"links": [{"url": "https://google.com", "alias": "acme~g"}, {"url": "https://go.dev"}]}`,
+			saveAtomic:  boolPtr(false),
+			saveResults: []storage.SaveResult{{ID: 1}},
+			created:     1,
+			failed:      1,
+			codes:       []string{"validation_failed", ""},
+		},
+		{
+			name:      "Invalid Mode",
+			body:      `{"mode": "sometimes", "links": [{"url": "https://google.com"}]}`,
+			respError: "invalid mode",
+		},
+		{
+			name:      "No Links",
+			body:      `{"links": []}`,
+			respError: "batch must contain between 1 and 1000 links",
+		},
+		{
+			name:      "Empty Body",
+			respError: "empty request",
+		},
+		{
+			name:       "SaveURLs Error",
+			body:       `{"links": [{"url": "https://google.com"}]}`,
+			saveAtomic: boolPtr(false),
+			saveError:  errors.New("unexpected error"),
+			respError:  "failed to save urls",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			saverMock := mocks.NewURLBatchSaver(t)
+
+			if tc.saveAtomic != nil {
+				saverMock.On("SaveURLs", mock.AnythingOfType("[]storage.Link"), *tc.saveAtomic).
+					Return(tc.saveResults, tc.saveError).
+					Once()
+			}
+
+			handler := batch.New(slogdiscard.NewDiscardLogger(), saverMock, nil, nil, 0, nil, domains)
+
+			req, err := http.NewRequest(http.MethodPost, "/url/batch", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp batch.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.created, resp.Created)
+			require.Equal(t, tc.failed, resp.Failed)
+
+			var codes []string
+			for i, res := range resp.Results {
+				require.Equal(t, i, res.Index)
+				codes = append(codes, res.Code)
+				if res.Code == "" {
+					require.NotEmpty(t, res.Alias)
+					require.NotZero(t, res.ID)
+				} else {
+					require.Zero(t, res.ID)
+				}
+			}
+			require.Equal(t, tc.codes, codes)
+		})
+	}
+}
+
+func TestBatchHandlerResolvesRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		}
+	}))
+	defer srv.Close()
+
+	// httptest servers listen on loopback.
+	resolver := redirectchain.New(redirectchain.Config{Flatten: true, AllowPrivate: true})
+
+	saverMock := mocks.NewURLBatchSaver(t)
+	saverMock.On("SaveURLs", mock.MatchedBy(func(links []storage.Link) bool {
+		return len(links) == 1 && links[0].URL == srv.URL+"/new"
+	}), false).
+		Return([]storage.SaveResult{{ID: 1}}, nil).
+		Once()
+
+	handler := batch.New(slogdiscard.NewDiscardLogger(), saverMock, nil, resolver, time.Minute, nil, nil)
+
+	body, err := json.Marshal(map[string]any{"links": []map[string]string{
+		{"url": srv.URL + "/loop", "alias": "loop"},
+		{"url": srv.URL + "/old", "alias": "old"},
+	}})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/url/batch", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp batch.Response
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, 1, resp.Failed)
+	require.Equal(t, "url_redirect_loop", resp.Results[0].Code)
+	require.Empty(t, resp.Results[1].Code)
+}
+
+func TestBatchHandlerBoundsResolveTime(t *testing.T) {
+	// Only the fast destination resolves before the batch deadline.
+	resolverMock := saveMocks.NewURLResolver(t)
+	resolverMock.On("Destination", mock.Anything, mock.AnythingOfType("string")).
+		Return(func(ctx context.Context, rawURL string) (string, error) {
+			if strings.Contains(rawURL, "fast") {
+				return rawURL, nil
+			}
+			select {
+			case <-time.After(time.Minute):
+				return rawURL, nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		})
+
+	saverMock := mocks.NewURLBatchSaver(t)
+	saverMock.On("SaveURLs", mock.MatchedBy(func(links []storage.Link) bool {
+		return len(links) == 1 && links[0].URL == "https://fast.example.com"
+	}), false).
+		Return([]storage.SaveResult{{ID: 1}}, nil).
+		Once()
+
+	handler := batch.New(slogdiscard.NewDiscardLogger(), saverMock, nil, resolverMock, 100*time.Millisecond, nil, nil)
+
+	links := []map[string]string{{"url": "https://fast.example.com"}}
+	for i := 0; i < 40; i++ {
+		links = append(links, map[string]string{"url": fmt.Sprintf("https://slow.example.com/%d", i)})
+	}
+	body, err := json.Marshal(map[string]any{"links": links})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/url/batch", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	var resp batch.Response
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, 40, resp.Failed)
+	for _, res := range resp.Results[1:] {
+		require.Equal(t, "validation_failed", res.Code)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
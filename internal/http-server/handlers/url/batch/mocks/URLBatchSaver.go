@@ -0,0 +1,55 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// URLBatchSaver is an autogenerated mock type for the URLBatchSaver type
+type URLBatchSaver struct {
+	mock.Mock
+}
+
+// SaveURLs provides a mock function with given fields: links, atomic
+func (_m *URLBatchSaver) SaveURLs(links []storage.Link, atomic bool) ([]storage.SaveResult, error) {
+	ret := _m.Called(links, atomic)
+
+	var r0 []storage.SaveResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]storage.Link, bool) ([]storage.SaveResult, error)); ok {
+		return rf(links, atomic)
+	}
+	if rf, ok := ret.Get(0).(func([]storage.Link, bool) []storage.SaveResult); ok {
+		r0 = rf(links, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]storage.SaveResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]storage.Link, bool) error); ok {
+		r1 = rf(links, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewURLBatchSaver interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewURLBatchSaver creates a new instance of URLBatchSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewURLBatchSaver(t mockConstructorTestingTNewURLBatchSaver) *URLBatchSaver {
+	mock := &URLBatchSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
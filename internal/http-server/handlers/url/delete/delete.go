@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/render"
 	"link-shortener/internal/lib/api/response"
 	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/workspace"
 	"link-shortener/internal/storage"
 	"log/slog"
 	"net/http"
@@ -15,9 +16,12 @@ import (
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLDeleter
 type URLDeleter interface {
+	GetLinkByID(id int64) (storage.Link, error)
 	DeleteURL(ID int64) error
 }
 
+// New returns the handler deleting links. Requests acting for a workspace
+// may only delete the links of the workspace.
 func New(log *slog.Logger, deleter URLDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
@@ -30,16 +34,33 @@ func New(log *slog.Logger, deleter URLDeleter) http.HandlerFunc {
 		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 		if err != nil {
 			log.Error("can't parse url id", sl.Err(err))
-			render.JSON(w, r, response.Error("invalid id"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid id"))
 			return
 		}
 
+		if ws, ok := workspace.FromContext(r.Context()); ok {
+			link, err := deleter.GetLinkByID(id)
+			if err == nil && link.WorkspaceID != ws.ID {
+				err = storage.ErrURLNotFound
+			}
+			if errors.Is(err, storage.ErrURLNotFound) {
+				log.Info("url id not found in workspace", slog.Int64("id", id), slog.String("workspace", ws.Name))
+				render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "url id not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get url", sl.Err(err))
+				render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to delete url"))
+				return
+			}
+		}
+
 		err = deleter.DeleteURL(id)
 
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url id not found", slog.Int64("id", id))
 
-			render.JSON(w, r, response.Error("url id not found"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "url id not found"))
 
 			return
 		}
@@ -47,7 +68,7 @@ func New(log *slog.Logger, deleter URLDeleter) http.HandlerFunc {
 		if err != nil {
 			log.Error("failed to delete url", sl.Err(err))
 
-			render.JSON(w, r, response.Error("failed to delete url"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to delete url"))
 
 			return
 		}
@@ -8,6 +8,7 @@ import (
 	"link-shortener/internal/http-server/handlers/url/delete/mocks"
 	"link-shortener/internal/lib/api/response"
 	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/workspace"
 	"link-shortener/internal/storage"
 	"net/http"
 	"net/http/httptest"
@@ -96,3 +97,53 @@ func TestDeleteHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteWorkspace(t *testing.T) {
+	cases := []struct {
+		name        string
+		workspaceID int64
+		respError   string
+	}{
+		{
+			name:        "Own Link",
+			workspaceID: 3,
+		},
+		{
+			name:        "Other Link",
+			workspaceID: 4,
+			respError:   "url id not found",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlDeleterMock := mocks.NewURLDeleter(t)
+			urlDeleterMock.On("GetLinkByID", int64(10)).
+				Return(storage.Link{ID: 10, WorkspaceID: tc.workspaceID}, nil).
+				Once()
+			if tc.respError == "" {
+				urlDeleterMock.On("DeleteURL", int64(10)).
+					Return(nil).
+					Once()
+			}
+
+			handler := chi.NewRouter()
+			handler.Delete("/url/{id}", delete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock))
+
+			req, err := http.NewRequest(http.MethodDelete, "/url/10", nil)
+			require.NoError(t, err)
+			req = req.WithContext(workspace.NewContext(req.Context(), storage.Workspace{ID: 3, Name: "acme"}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// URLDeleter is an autogenerated mock type for the URLDeleter type
+type URLDeleter struct {
+	mock.Mock
+}
+
+// DeleteURL provides a mock function with given fields: ID
+func (_m *URLDeleter) DeleteURL(ID int64) error {
+	ret := _m.Called(ID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(ID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLinkByID provides a mock function with given fields: id
+func (_m *URLDeleter) GetLinkByID(id int64) (storage.Link, error) {
+	ret := _m.Called(id)
+
+	var r0 storage.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (storage.Link, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(int64) storage.Link); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(storage.Link)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewURLDeleter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewURLDeleter creates a new instance of URLDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewURLDeleter(t mockConstructorTestingTNewURLDeleter) *URLDeleter {
+	mock := &URLDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
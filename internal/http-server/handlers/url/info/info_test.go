@@ -0,0 +1,85 @@
+package info_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/info"
+	"link-shortener/internal/http-server/handlers/url/info/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+)
+
+func TestInfoHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		link      storage.Link
+		respError string
+		mockError error
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			link:  storage.Link{ID: 1, Alias: "test_alias", URL: "https://google.com"},
+		},
+		{
+			name:  "Limited And Protected",
+			alias: "test_alias",
+			link: storage.Link{
+				ID: 1, Alias: "test_alias", URL: "https://google.com",
+				PasswordHash: "$2a$10$hash", MaxClicks: 5, UsedClicks: 2,
+			},
+		},
+		{
+			name:      "Not Found",
+			alias:     "missing",
+			respError: "not found",
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "GetLink Error",
+			alias:     "test_alias",
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkGetterMock := mocks.NewLinkGetter(t)
+			linkGetterMock.On("GetLink", "", tc.alias).
+				Return(tc.link, tc.mockError).
+				Once()
+
+			handler := chi.NewRouter()
+			handler.Get("/url/{alias}", info.New(slogdiscard.NewDiscardLogger(), linkGetterMock, nil))
+
+			req, err := http.NewRequest(http.MethodGet, "/url/"+tc.alias, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp info.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.link.Public(), resp.Link)
+			require.NotContains(t, rr.Body.String(), "password_hash")
+		})
+	}
+}
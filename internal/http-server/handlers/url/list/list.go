@@ -0,0 +1,113 @@
+package list
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Response struct {
+	response.Response
+	Links []storage.Link `json:"links"`
+}
+
+var (
+	errInvalidPaging    = errors.New("invalid limit or offset")
+	errInvalidWorkspace = errors.New("invalid workspace_id")
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkLister
+type LinkLister interface {
+	ListLinks(opts storage.ListOptions) ([]storage.Link, error)
+}
+
+// New returns the handler listing links. Requests acting for a workspace
+// only see the links of the workspace.
+func New(log *slog.Logger, linkLister LinkLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid list options", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, err.Error()))
+			return
+		}
+		if ws, ok := workspace.FromContext(r.Context()); ok {
+			opts.WorkspaceID = ws.ID
+		}
+
+		links, err := linkLister.ListLinks(opts)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to list urls"))
+			return
+		}
+
+		if links == nil {
+			links = []storage.Link{}
+		}
+		for i := range links {
+			links[i] = links[i].Public()
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Links:    links,
+		})
+	}
+}
+
+// parseOptions reads the limit, offset, title, tag, folder and
+// workspace_id query parameters.
+func parseOptions(r *http.Request) (storage.ListOptions, error) {
+	opts := storage.ListOptions{Limit: defaultLimit}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return opts, errInvalidPaging
+		}
+		opts.Limit = limit
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, errInvalidPaging
+		}
+		opts.Offset = offset
+	}
+
+	opts.Title = strings.TrimSpace(r.URL.Query().Get("title"))
+	opts.Tag = strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	opts.Folder = strings.TrimSpace(r.URL.Query().Get("folder"))
+
+	if v := r.URL.Query().Get("workspace_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return opts, errInvalidWorkspace
+		}
+		opts.WorkspaceID = id
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,112 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/list"
+	"link-shortener/internal/http-server/handlers/url/list/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	links := []storage.Link{
+		{ID: 1, Alias: "first", URL: "https://google.com"},
+		{ID: 2, Alias: "second", URL: "https://go.dev"},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		opts      storage.ListOptions
+		links     []storage.Link
+		respError string
+		mockError error
+	}{
+		{
+			name:  "Default Options",
+			opts:  storage.ListOptions{Limit: 100},
+			links: links,
+		},
+		{
+			name:  "Limit And Offset",
+			query: "?limit=1&offset=1",
+			opts:  storage.ListOptions{Limit: 1, Offset: 1},
+			links: links[1:],
+		},
+		{
+			name:  "Title Search",
+			query: "?title=%20Go%20",
+			opts:  storage.ListOptions{Limit: 100, Title: "Go"},
+			links: links[1:],
+		},
+		{
+			name:  "Tag And Folder",
+			query: "?tag=Launch&folder=Marketing",
+			opts:  storage.ListOptions{Limit: 100, Tag: "launch", Folder: "Marketing"},
+			links: links[1:],
+		},
+		{
+			name:  "Empty",
+			opts:  storage.ListOptions{Limit: 100},
+			links: []storage.Link{},
+		},
+		{
+			name:      "Invalid Limit",
+			query:     "?limit=abc",
+			respError: "invalid limit or offset",
+		},
+		{
+			name:      "Limit Too Large",
+			query:     "?limit=100000",
+			respError: "invalid limit or offset",
+		},
+		{
+			name:      "ListLinks Error",
+			opts:      storage.ListOptions{Limit: 100},
+			respError: "failed to list urls",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkListerMock := mocks.NewLinkLister(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				linkListerMock.On("ListLinks", tc.opts).
+					Return(tc.links, tc.mockError).
+					Once()
+			}
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), linkListerMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp list.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.links, resp.Links)
+			}
+		})
+	}
+}
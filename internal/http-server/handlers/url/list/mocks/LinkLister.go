@@ -0,0 +1,55 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// LinkLister is an autogenerated mock type for the LinkLister type
+type LinkLister struct {
+	mock.Mock
+}
+
+// ListLinks provides a mock function with given fields: opts
+func (_m *LinkLister) ListLinks(opts storage.ListOptions) ([]storage.Link, error) {
+	ret := _m.Called(opts)
+
+	var r0 []storage.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(storage.ListOptions) ([]storage.Link, error)); ok {
+		return rf(opts)
+	}
+	if rf, ok := ret.Get(0).(func(storage.ListOptions) []storage.Link); ok {
+		r0 = rf(opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]storage.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(storage.ListOptions) error); ok {
+		r1 = rf(opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewLinkLister interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewLinkLister creates a new instance of LinkLister. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewLinkLister(t mockConstructorTestingTNewLinkLister) *LinkLister {
+	mock := &LinkLister{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,30 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// MetadataQueue is an autogenerated mock type for the MetadataQueue type
+type MetadataQueue struct {
+	mock.Mock
+}
+
+// Enqueue provides a mock function with given fields: linkID, url
+func (_m *MetadataQueue) Enqueue(linkID int64, url string) {
+	_m.Called(linkID, url)
+}
+
+type mockConstructorTestingTNewMetadataQueue interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewMetadataQueue creates a new instance of MetadataQueue. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewMetadataQueue(t mockConstructorTestingTNewMetadataQueue) *MetadataQueue {
+	mock := &MetadataQueue{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,39 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// URLPolicy is an autogenerated mock type for the URLPolicy type
+type URLPolicy struct {
+	mock.Mock
+}
+
+// Check provides a mock function with given fields: rawURL
+func (_m *URLPolicy) Check(rawURL string) error {
+	ret := _m.Called(rawURL)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(rawURL)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewURLPolicy interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewURLPolicy creates a new instance of URLPolicy. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewURLPolicy(t mockConstructorTestingTNewURLPolicy) *URLPolicy {
+	mock := &URLPolicy{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
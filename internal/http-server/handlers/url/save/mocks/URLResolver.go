@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// URLResolver is an autogenerated mock type for the URLResolver type
+type URLResolver struct {
+	mock.Mock
+}
+
+// Destination provides a mock function with given fields: ctx, rawURL
+func (_m *URLResolver) Destination(ctx context.Context, rawURL string) (string, error) {
+	ret := _m.Called(ctx, rawURL)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, rawURL)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, rawURL)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, rawURL)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewURLResolver interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewURLResolver creates a new instance of URLResolver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewURLResolver(t mockConstructorTestingTNewURLResolver) *URLResolver {
+	mock := &URLResolver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
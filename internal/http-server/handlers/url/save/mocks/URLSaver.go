@@ -1,28 +1,35 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "link-shortener/internal/storage"
+)
 
+// URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
 	mock.Mock
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(URL string, alias string) (int64, error) {
-	ret := _m.Called(URL, alias)
+// SaveLink provides a mock function with given fields: link
+func (_m *URLSaver) SaveLink(link storage.Link) (int64, error) {
+	ret := _m.Called(link)
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(URL, alias)
+	if rf, ok := ret.Get(0).(func(storage.Link) (int64, error)); ok {
+		return rf(link)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(URL, alias)
+	if rf, ok := ret.Get(0).(func(storage.Link) int64); ok {
+		r0 = rf(link)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(URL, alias)
+	if rf, ok := ret.Get(1).(func(storage.Link) error); ok {
+		r1 = rf(link)
 	} else {
 		r1 = ret.Error(1)
 	}
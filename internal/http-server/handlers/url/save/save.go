@@ -1,44 +1,318 @@
 package save
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 	"io"
 	"link-shortener/internal/lib/api/response"
 	"link-shortener/internal/lib/logger/sl"
 	"link-shortener/internal/lib/random"
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/shorturl"
+	"link-shortener/internal/lib/urlpolicy"
+	"link-shortener/internal/lib/workspace"
 	"link-shortener/internal/storage"
 	"log/slog"
 	"net/http"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// Domain is the short domain the link is served on; empty for the
+	// default one. Aliases only need to be unique on their domain.
+	Domain         string `json:"domain,omitempty"`
+	RedirectStatus int    `json:"redirect_status,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// Folder, if set, files the link in a folder.
+	Folder string `json:"folder,omitempty" validate:"max=100"`
+	// Tags label the link. They are stored lowercase, once each.
+	Tags []string `json:"tags,omitempty" validate:"max=20,dive,max=32"`
+	// Password, if set, must be given before the link redirects.
+	Password string `json:"password,omitempty"`
+	// MaxClicks, if set, is the number of redirects after which the link
+	// stops working; 1 makes a one-time link.
+	MaxClicks int64 `json:"max_clicks,omitempty" validate:"gte=0"`
+	// ActiveFrom and ActiveUntil (RFC 3339, any offset) limit when the link
+	// redirects to URL. Outside of that time it redirects to FallbackURL,
+	// if set.
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+	FallbackURL string     `json:"fallback_url,omitempty" validate:"omitempty,url"`
+	// Targets send visitors on the given platforms to other URLs.
+	Targets []Target `json:"targets,omitempty" validate:"dive"`
+	// GeoTargets send visitors from the given countries to other URLs.
+	GeoTargets []GeoTarget `json:"geo_targets,omitempty" validate:"dive"`
+	// Destinations split the remaining visitors between URLs by weight.
+	Destinations []Destination `json:"destinations,omitempty" validate:"dive"`
+	// QueryParams are added to the destination query; values may use the
+	// placeholders {alias} and {country}. QueryPassthrough forwards the
+	// query of the short URL too.
+	QueryParams      []QueryParam `json:"query_params,omitempty" validate:"dive"`
+	QueryPassthrough bool         `json:"query_passthrough,omitempty"`
+	// Prefix makes the link match paths below the alias too, and append
+	// them to the destination.
+	Prefix bool `json:"prefix,omitempty"`
+	// Interstitial shows browsers a warning page before they leave for an
+	// external domain.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Unfurl replaces the fetched title, description and image in what
+	// chat apps show for the link.
+	Unfurl *Unfurl `json:"unfurl,omitempty"`
+}
+
+// Unfurl is what chat apps show for a link, see storage.Link.Unfurl.
+type Unfurl struct {
+	Title       string `json:"title,omitempty" validate:"max=300"`
+	Description string `json:"description,omitempty" validate:"max=1000"`
+	Image       string `json:"image,omitempty" validate:"omitempty,http_url,max=2048"`
+}
+
+// PageMeta returns u as stored in a link, nil if it is empty.
+func (u Unfurl) PageMeta() *storage.PageMeta {
+	meta := storage.PageMeta{Title: u.Title, Description: u.Description, Image: u.Image}
+	if meta.Empty() {
+		return nil
+	}
+	return &meta
+}
+
+// Target is a destination for one platform, see storage.Target.
+type Target struct {
+	Platform string `json:"platform" validate:"required,oneof=ios android windows macos linux mobile desktop"`
+	URL      string `json:"url" validate:"required,url"`
+}
+
+// GeoTarget is a destination for one country, see storage.GeoTarget.
+type GeoTarget struct {
+	Country string `json:"country" validate:"required,iso3166_1_alpha2"`
+	URL     string `json:"url" validate:"required,url"`
+}
+
+// Destination is one variant of a split link, see storage.Destination.
+type Destination struct {
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"gte=0"`
+}
+
+// QueryParam is a query parameter added to redirects, see
+// storage.QueryParam.
+type QueryParam struct {
+	Key   string `json:"key" validate:"required"`
+	Value string `json:"value"`
+}
+
+// LogValue keeps the password out of the logs.
+func (req Request) LogValue() slog.Value {
+	if req.Password != "" {
+		req.Password = "[redacted]"
+	}
+	type plain Request
+	return slog.AnyValue(plain(req))
+}
+
+// Link returns the link described by req, with the password hashed. The
+// alias is left empty if none was requested.
+func (req Request) Link() (storage.Link, error) {
+	link := storage.Link{
+		Alias:            req.Alias,
+		Domain:           req.Domain,
+		URL:              req.URL,
+		Folder:           strings.TrimSpace(req.Folder),
+		Tags:             NormalizeTags(req.Tags),
+		RedirectStatus:   req.RedirectStatus,
+		MaxClicks:        req.MaxClicks,
+		ActiveFrom:       utc(req.ActiveFrom),
+		ActiveUntil:      utc(req.ActiveUntil),
+		FallbackURL:      req.FallbackURL,
+		QueryPassthrough: req.QueryPassthrough,
+		Prefix:           req.Prefix,
+		Interstitial:     req.Interstitial,
+	}
+
+	for _, t := range req.Targets {
+		link.Targets = append(link.Targets, storage.Target{Platform: t.Platform, URL: t.URL})
+	}
+	for _, t := range req.GeoTargets {
+		link.GeoTargets = append(link.GeoTargets, storage.GeoTarget{Country: t.Country, URL: t.URL})
+	}
+	for _, d := range req.Destinations {
+		link.Destinations = append(link.Destinations, storage.Destination{URL: d.URL, Weight: d.Weight})
+	}
+	for _, p := range req.QueryParams {
+		link.QueryParams = append(link.QueryParams, storage.QueryParam{Key: p.Key, Value: p.Value})
+	}
+	if req.Unfurl != nil {
+		link.Unfurl = req.Unfurl.PageMeta()
+	}
+
+	if req.Password != "" {
+		hash, err := HashPassword(req.Password)
+		if err != nil {
+			return storage.Link{}, err
+		}
+		link.PasswordHash = hash
+	}
+
+	return link, nil
+}
+
+// requestFor returns the request that would have created link. Its password
+// cannot be recovered, so it is not validated again.
+func requestFor(link storage.Link) Request {
+	req := Request{
+		URL:              link.URL,
+		Alias:            link.Alias,
+		Domain:           link.Domain,
+		Folder:           link.Folder,
+		Tags:             link.Tags,
+		RedirectStatus:   link.RedirectStatus,
+		MaxClicks:        link.MaxClicks,
+		ActiveFrom:       link.ActiveFrom,
+		ActiveUntil:      link.ActiveUntil,
+		FallbackURL:      link.FallbackURL,
+		QueryPassthrough: link.QueryPassthrough,
+		Prefix:           link.Prefix,
+		Interstitial:     link.Interstitial,
+	}
+
+	for _, t := range link.Targets {
+		req.Targets = append(req.Targets, Target{Platform: t.Platform, URL: t.URL})
+	}
+	for _, t := range link.GeoTargets {
+		req.GeoTargets = append(req.GeoTargets, GeoTarget{Country: t.Country, URL: t.URL})
+	}
+	for _, d := range link.Destinations {
+		req.Destinations = append(req.Destinations, Destination{URL: d.URL, Weight: d.Weight})
+	}
+	for _, p := range link.QueryParams {
+		req.QueryParams = append(req.QueryParams, QueryParam{Key: p.Key, Value: p.Value})
+	}
+	if link.Unfurl != nil {
+		req.Unfurl = &Unfurl{Title: link.Unfurl.Title, Description: link.Unfurl.Description, Image: link.Unfurl.Image}
+	}
+
+	return req
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// HashPassword returns the hash stored in storage.Link.PasswordHash, or
+// ErrPasswordTooLong.
+func HashPassword(password string) (string, error) {
+	if len(password) > maxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
 }
 
 type Response struct {
 	response.Response
 	Alias string `json:"alias,omitempty"`
 	ID    int64  `json:"id,omitempty"`
+	// QRURL is where the QR code of the short URL is served.
+	QRURL string `json:"qr_url,omitempty"`
 	Error string `json:"error,omitempty"`
 }
 
 // ref to conf
 const aliasLength = 6
 
+// maxPasswordLength is the longest password bcrypt can hash, in bytes.
+const maxPasswordLength = 72
+
+var (
+	ErrInvalidAlias    = errors.New("invalid alias (special characters not allowed)")
+	ErrPasswordTooLong = errors.New("password is too long (at most 72 bytes)")
+	ErrInvalidWindow   = errors.New("field 'ActiveUntil' must be after 'ActiveFrom'")
+	ErrNoWeight        = errors.New("field 'Destinations' must have a positive total weight")
+	ErrInvalidTag      = errors.New("invalid tag (letters, digits, '-', '_' and '.' only)")
+	ErrInvalidFolder   = errors.New("invalid folder (control characters not allowed)")
+	ErrReservedAlias   = errors.New("alias is reserved, prefix links cannot have it")
+)
+
+// reservedPrefixAliases are the first path segments the server routes
+// itself, such as /qr/{alias} for QR codes. Prefix links with these aliases
+// could never forward the paths below them.
+var reservedPrefixAliases = []string{"qr", "url", "admin"}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
 type URLSaver interface {
-	SaveURL(URL string, alias string) (int64, error)
+	SaveLink(link storage.Link) (int64, error)
+}
+
+// URLPolicy decides which destinations links may have, see
+// urlpolicy.Policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLPolicy
+type URLPolicy interface {
+	Check(rawURL string) error
+}
+
+// URLResolver follows the redirects of destinations, see
+// redirectchain.Resolver.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLResolver
+type URLResolver interface {
+	Destination(ctx context.Context, rawURL string) (string, error)
+}
+
+// MetadataQueue fetches the metadata of link destinations in the
+// background, see linkmeta.Queue.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=MetadataQueue
+type MetadataQueue interface {
+	Enqueue(linkID int64, url string)
+}
+
+// Options configure New.
+type Options struct {
+	// PublicURL is what QR code URLs in responses are built on; if it is
+	// empty, the host of the request is used.
+	PublicURL string
+	// Policy, if set, must accept every URL of a link.
+	Policy URLPolicy
+	// Resolver, if set, follows the redirects of every URL of a link,
+	// which is refused if they loop or go on for too long, or saved with
+	// the URLs the resolver returns.
+	Resolver URLResolver
+	// Metadata, if set, is handed every saved link to fetch the title,
+	// description and image of its page.
+	Metadata MetadataQueue
+	// Domains are the short domains links may be created on besides the
+	// default one.
+	Domains *shortdomain.Set
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// New returns the handler creating links. Requests acting for a workspace
+// create links of the workspace, with aliases in its namespace, which fail
+// once the workspace has reached a quota.
+func New(log *slog.Logger, urlSaver URLSaver, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -48,60 +322,337 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
-			render.JSON(w, r, response.Error("empty request"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "empty request"))
 			return
 		}
 
 		if err != nil {
 			log.Error("failed to parse request body", sl.Err(err))
-			render.JSON(w, r, response.Error("failed to decode request"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "failed to decode request"))
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
-			var validateErr validator.ValidationErrors
-			errors.As(err, &validateErr)
-			log.Error("failed to validate request", sl.Err(err))
-			render.JSON(w, r, response.ValidationError(validateErr))
+		if req.Domain, err = opts.Domains.Normalize(req.Domain); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, err.Error()))
 			return
 		}
 
-		// Alias validation: ensure no special characters
-		if req.Alias != "" && !isValidAlias(req.Alias) {
-			log.Info("invalid alias", slog.String("alias", req.Alias))
-			render.JSON(w, r, response.Error("invalid alias (special characters not allowed)"))
+		ws, inWorkspace := workspace.FromContext(r.Context())
+		if inWorkspace {
+			req.Alias, err = WorkspaceAlias(ws, opts.Domains, req)
+		} else {
+			err = workspace.CheckUnowned(req.Alias)
+		}
+		if err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, err.Error()))
+			return
+		}
+
+		if err := Validate(req, opts.Policy); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(ErrorCode(err), err.Error()))
 			return
 		}
 
-		alias := req.Alias
-		if alias == "" {
-			alias = random.NewRandomString(aliasLength)
+		if opts.Resolver != nil {
+			if err := Resolve(r.Context(), &req, opts.Resolver); err != nil {
+				log.Info("refused redirect chain", sl.Err(err))
+				render.JSON(w, r, response.ErrorWithCode(ErrorCode(err), err.Error()))
+				return
+			}
+		}
+
+		link, err := req.Link()
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to save url"))
+			return
+		}
+		if link.Alias == "" {
+			link.Alias = NewAlias()
 		}
+		link.WorkspaceID = ws.ID
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+		id, err := urlSaver.SaveLink(link)
 		if errors.Is(err, storage.ErrURLExist) {
 			log.Info("url already exists", slog.String("url", req.URL))
-			render.JSON(w, r, response.Error("url already exists"))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeAliasExists, "url already exists"))
+			return
+		}
+		if errors.Is(err, storage.ErrLinkQuota) {
+			log.Info("link quota exceeded", slog.String("workspace", ws.Name))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeLinkQuota, "link quota exceeded"))
+			return
+		}
+		if errors.Is(err, storage.ErrDailyQuota) {
+			log.Info("daily quota exceeded", slog.String("workspace", ws.Name))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeDailyQuota, "daily quota exceeded"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save url", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to save url"))
 			return
 		}
 		log.Info("url saved", slog.Int64("id", id))
-		responseOK(w, r, alias, id)
+		if opts.Metadata != nil {
+			opts.Metadata.Enqueue(id, link.URL)
+		}
+		base := shorturl.OnDomain(shorturl.Base(r, opts.PublicURL), link.Domain)
+		responseOK(w, r, link.Alias, id, shorturl.QR(base, link.Alias))
+	}
+}
+
+// WorkspaceAlias returns the alias the link described by req is stored
+// under when ws creates it, see workspace.Alias. An alias is generated if
+// req has none. req.Domain must be normalized, see shortdomain.Normalize.
+func WorkspaceAlias(ws storage.Workspace, domains *shortdomain.Set, req Request) (string, error) {
+	domain, _ := domains.Get(req.Domain)
+
+	alias := req.Alias
+	if alias == "" {
+		alias = NewAlias()
+	}
+
+	return workspace.Alias(ws, domain, alias)
+}
+
+// Validate runs the same checks New applies to an incoming request, so that
+// other entry points (e.g. the admin CLI) accept exactly the same input.
+// Every URL of req must pass policy, unless it is nil. The returned error
+// message is suitable for showing to the user; ErrorCode classifies it.
+func Validate(req Request, policy URLPolicy) error {
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		if errors.As(err, &validateErr) {
+			return errors.New(response.ValidationError(validateErr).Error)
+		}
+		return err
+	}
+
+	// Alias validation: ensure no special characters
+	if req.Alias != "" && !isValidAlias(req.Alias) {
+		return ErrInvalidAlias
+	}
+
+	if req.Prefix && slices.Contains(reservedPrefixAliases, req.Alias) {
+		return ErrReservedAlias
+	}
+
+	if len(req.Password) > maxPasswordLength {
+		return ErrPasswordTooLong
+	}
+
+	for _, tag := range NormalizeTags(req.Tags) {
+		if !isValidTag(tag) {
+			return ErrInvalidTag
+		}
+	}
+
+	if !isValidFolder(req.Folder) {
+		return ErrInvalidFolder
+	}
+
+	if req.ActiveFrom != nil && req.ActiveUntil != nil && !req.ActiveUntil.After(*req.ActiveFrom) {
+		return ErrInvalidWindow
+	}
+
+	if len(req.Destinations) > 0 {
+		total := 0
+		for _, d := range req.Destinations {
+			total += d.Weight
+		}
+		if total == 0 {
+			return ErrNoWeight
+		}
+	}
+
+	if policy != nil {
+		return req.eachURL(func(u *string) error {
+			return policy.Check(*u)
+		})
+	}
+
+	return nil
+}
+
+// Resolve replaces every URL of req with the destination resolver returns
+// for it. The returned error message is suitable for showing to the user;
+// ErrorCode classifies it.
+func Resolve(ctx context.Context, req *Request, resolver URLResolver) error {
+	return req.eachURL(func(u *string) error {
+		dest, err := resolver.Destination(ctx, *u)
+		if err != nil {
+			return err
+		}
+		*u = dest
+		return nil
+	})
+}
+
+// ResolveLink applies Resolve to the URLs of a link that did not come
+// through save.New, such as one being changed.
+func ResolveLink(ctx context.Context, link storage.Link, resolver URLResolver) (storage.Link, error) {
+	req := requestFor(link)
+	if err := Resolve(ctx, &req, resolver); err != nil {
+		return link, err
+	}
+
+	link.URL, link.FallbackURL = req.URL, req.FallbackURL
+	// The lists are copied so that those of the caller stay as they were.
+	link.Targets = slices.Clone(link.Targets)
+	for i, t := range req.Targets {
+		link.Targets[i].URL = t.URL
+	}
+	link.GeoTargets = slices.Clone(link.GeoTargets)
+	for i, t := range req.GeoTargets {
+		link.GeoTargets[i].URL = t.URL
+	}
+	link.Destinations = slices.Clone(link.Destinations)
+	for i, d := range req.Destinations {
+		link.Destinations[i].URL = d.URL
+	}
+
+	return link, nil
+}
+
+// eachURL calls fn with every destination of req, stopping at the first
+// error, which is returned with the field the URL is in.
+func (req *Request) eachURL(fn func(u *string) error) error {
+	check := func(field string, u *string) error {
+		if err := fn(u); err != nil {
+			return fmt.Errorf("field '%s': %w", field, err)
+		}
+		return nil
+	}
+
+	if err := check("URL", &req.URL); err != nil {
+		return err
+	}
+	if req.FallbackURL != "" {
+		if err := check("FallbackURL", &req.FallbackURL); err != nil {
+			return err
+		}
+	}
+	for i := range req.Targets {
+		if err := check("Targets", &req.Targets[i].URL); err != nil {
+			return err
+		}
+	}
+	for i := range req.GeoTargets {
+		if err := check("GeoTargets", &req.GeoTargets[i].URL); err != nil {
+			return err
+		}
+	}
+	for i := range req.Destinations {
+		if err := check("Destinations", &req.Destinations[i].URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrorCode returns the response code for an error returned by Validate or
+// Resolve.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, redirectchain.ErrLoop):
+		return response.CodeURLLoop
+	case errors.Is(err, redirectchain.ErrTooManyHops):
+		return response.CodeURLChain
+	case errors.Is(err, urlpolicy.ErrScheme):
+		return response.CodeURLScheme
+	case errors.Is(err, urlpolicy.ErrPrivate):
+		return response.CodeURLPrivate
+	case errors.Is(err, urlpolicy.ErrSelf):
+		return response.CodeURLLoop
+	case errors.Is(err, urlpolicy.ErrBlocked), errors.Is(err, urlpolicy.ErrNotAllowed):
+		return response.CodeURLBlocked
+	default:
+		return response.CodeValidation
+	}
+}
+
+// ValidateLink applies Validate to a link that did not come through the API
+// (e.g. an import), generates an alias if it has none and writes its domain
+// the way stored links have it. The domain need not be configured: exports
+// keep links whose domain is no longer served. Only links of a workspace
+// may have aliases in a workspace namespace.
+func ValidateLink(link storage.Link, policy URLPolicy) (storage.Link, error) {
+	if err := Validate(requestFor(link), policy); err != nil {
+		return link, err
+	}
+	if link.WorkspaceID == 0 {
+		if err := workspace.CheckUnowned(link.Alias); err != nil {
+			return link, err
+		}
+	}
+
+	link.Domain = shortdomain.Host(link.Domain)
+	link.Folder = strings.TrimSpace(link.Folder)
+	link.Tags = NormalizeTags(link.Tags)
+
+	if link.Alias == "" {
+		link.Alias = NewAlias()
+	}
+
+	return link, nil
+}
+
+// LinkValidator returns ValidateLink bound to policy, as linkio.Import
+// wants it.
+func LinkValidator(policy URLPolicy) func(storage.Link) (storage.Link, error) {
+	return func(link storage.Link) (storage.Link, error) {
+		return ValidateLink(link, policy)
 	}
 }
 
 // Sends a successful response with a custom JSON payload.
-func responseOK(w http.ResponseWriter, r *http.Request, alias string, id int64) {
+func responseOK(w http.ResponseWriter, r *http.Request, alias string, id int64, qrURL string) {
 	render.JSON(w, r, Response{
 		Response: response.OK(),
 		Alias:    alias,
 		ID:       id,
+		QRURL:    qrURL,
 	})
 }
 
-// Custom validation for the alias to only allow alphanumeric characters, hyphens, and underscores
+// NewAlias generates a random alias of the default length.
+func NewAlias() string {
+	return random.NewRandomString(aliasLength)
+}
+
+// NormalizeTags returns tags trimmed, lowercased, sorted and without empty
+// ones or duplicates, the way links store them.
+func NormalizeTags(tags []string) []string {
+	var res []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			res = append(res, tag)
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// Tags are made of letters, digits, hyphens, underscores and dots, and start with a letter or digit
+func isValidTag(tag string) bool {
+	re := regexp.MustCompile(`^[\pL\pN][\pL\pN_.-]*$`)
+	return re.MatchString(tag)
+}
+
+// Folders are free text without control characters
+func isValidFolder(folder string) bool {
+	return !strings.ContainsFunc(folder, unicode.IsControl)
+}
+
+// Custom validation for the alias to only allow alphanumeric characters, hyphens, and underscores,
+// after the name of a workspace for aliases in its namespace
 func isValidAlias(alias string) bool {
-	re := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	re := regexp.MustCompile(`^([a-z0-9][a-z0-9-]*` + workspace.Separator + `)?[a-zA-Z0-9_-]+$`)
 	return re.MatchString(alias)
 }
@@ -7,14 +7,24 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"link-shortener/internal/http-server/handlers/url/save"
 	"link-shortener/internal/http-server/handlers/url/save/mocks"
+	"link-shortener/internal/lib/api/response"
 	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/urlpolicy"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -22,6 +32,8 @@ func TestSaveHandler(t *testing.T) {
 		name      string
 		alias     string
 		url       string
+		status    int
+		password  string
 		respError string
 		mockError error
 	}{
@@ -39,13 +51,45 @@ func TestSaveHandler(t *testing.T) {
 			name:      "Empty URL",
 			url:       "",
 			alias:     "some_alias",
-			respError: "field 'Url' is required",
+			respError: "field 'URL' is required",
 		},
 		{
 			name:      "Invalid URL",
 			url:       "some invalid URL",
 			alias:     "some_alias",
-			respError: "field 'Url' must be a valid URL",
+			respError: "field 'URL' must be a valid URL",
+		},
+		{
+			name:   "Permanent Redirect",
+			alias:  "test_alias",
+			url:    "https://google.com",
+			status: 308,
+		},
+		{
+			name:      "Invalid Redirect Status",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			status:    303,
+			respError: "field 'RedirectStatus' must be one of 301 302 307 308",
+		},
+		{
+			name:     "Password Protected",
+			alias:    "test_alias",
+			url:      "https://google.com",
+			password: "secret",
+		},
+		{
+			name:      "Password Too Long",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			password:  strings.Repeat("x", 73),
+			respError: "password is too long (at most 72 bytes)",
+		},
+		{
+			name:      "Workspace Alias",
+			alias:     "acme~docs",
+			url:       "https://google.com",
+			respError: "aliases with '~' are only for the links of workspaces",
 		},
 		{
 			name:      "SaveURL Error",
@@ -65,14 +109,23 @@ func TestSaveHandler(t *testing.T) {
 			urlSaverMock := mocks.NewURLSaver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					if link.URL != tc.url || link.Alias == "" || link.RedirectStatus != tc.status {
+						return false
+					}
+					if tc.password == "" {
+						return link.PasswordHash == ""
+					}
+					return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(tc.password)) == nil
+				})).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s", "redirect_status": %d, "password": "%s"}`,
+				tc.url, tc.alias, tc.status, tc.password)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
@@ -90,7 +143,744 @@ func TestSaveHandler(t *testing.T) {
 
 			require.Equal(t, tc.respError, resp.Error)
 
+			if tc.respError == "" && tc.alias != "" {
+				require.Equal(t, "https://sho.rt/qr/"+tc.alias, resp.QRURL)
+			}
+
 			// TODO: add more checks
 		})
 	}
 }
+
+func TestSaveActivationWindow(t *testing.T) {
+	from := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 3, 8, 7, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+	}{
+		{
+			name: "Offsets Normalized To UTC",
+			input: `{"url": "https://shop.example.com/sale",
+				"active_from": "2025-03-01T09:00:00+02:00",
+				"active_until": "2025-03-08T02:00:00-05:00",
+				"fallback_url": "https://shop.example.com"}`,
+		},
+		{
+			name: "Until Before From",
+			input: `{"url": "https://shop.example.com/sale",
+				"active_from": "2025-03-08T07:00:00Z",
+				"active_until": "2025-03-01T07:00:00Z"}`,
+			respError: "field 'ActiveUntil' must be after 'ActiveFrom'",
+		},
+		{
+			name: "Invalid Fallback",
+			input: `{"url": "https://shop.example.com/sale",
+				"active_from": "2025-03-01T07:00:00Z",
+				"fallback_url": "shop"}`,
+			respError: "field 'FallbackURL' must be a valid URL",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.ActiveFrom != nil && link.ActiveUntil != nil &&
+						*link.ActiveFrom == from && *link.ActiveUntil == until &&
+						link.FallbackURL == "https://shop.example.com"
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveTargets(t *testing.T) {
+	cases := []struct {
+		name      string
+		targets   string
+		respError string
+	}{
+		{
+			name:    "App Stores",
+			targets: `[{"platform": "ios", "url": "https://apps.apple.com/app/id1"}, {"platform": "android", "url": "https://play.google.com/store/apps/details?id=app"}]`,
+		},
+		{
+			name:      "Unknown Country",
+			targets:   `[{"platform": "ios", "url": "https://apps.apple.com/app/id1"}], "geo_targets": [{"country": "XX", "url": "https://example.com/xx"}]`,
+			respError: "field 'Country' must be a two-letter country code",
+		},
+		{
+			name:      "Unknown Platform",
+			targets:   `[{"platform": "symbian", "url": "https://example.com/nokia"}]`,
+			respError: "field 'Platform' must be one of ios android windows macos linux mobile desktop",
+		},
+		{
+			name:      "Invalid URL",
+			targets:   `[{"platform": "ios", "url": "app store"}]`,
+			respError: "field 'URL' must be a valid URL",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return len(link.Targets) == 2 &&
+						link.Targets[0] == storage.Target{Platform: "ios", URL: "https://apps.apple.com/app/id1"}
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			input := `{"url": "https://example.com", "targets": ` + tc.targets + `}`
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveDestinations(t *testing.T) {
+	cases := []struct {
+		name         string
+		destinations string
+		respError    string
+	}{
+		{
+			name:         "Weighted",
+			destinations: `[{"url": "https://example.com/a", "weight": 70}, {"url": "https://example.com/b", "weight": 30}]`,
+		},
+		{
+			name:         "No Weight",
+			destinations: `[{"url": "https://example.com/a"}, {"url": "https://example.com/b"}]`,
+			respError:    "field 'Destinations' must have a positive total weight",
+		},
+		{
+			name:         "Negative Weight",
+			destinations: `[{"url": "https://example.com/a", "weight": -1}, {"url": "https://example.com/b", "weight": 2}]`,
+			respError:    "field 'Weight' must be at least 0",
+		},
+		{
+			name:         "Invalid URL",
+			destinations: `[{"url": "variant a", "weight": 1}]`,
+			respError:    "field 'URL' must be a valid URL",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return len(link.Destinations) == 2 &&
+						link.Destinations[0] == storage.Destination{URL: "https://example.com/a", Weight: 70}
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			input := `{"url": "https://example.com", "destinations": ` + tc.destinations + `}`
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveUnfurl(t *testing.T) {
+	cases := []struct {
+		name      string
+		unfurl    string
+		want      *storage.PageMeta
+		respError string
+	}{
+		{
+			name:   "Custom",
+			unfurl: `{"title": "Team Wiki", "image": "https://example.com/wiki.png"}`,
+			want:   &storage.PageMeta{Title: "Team Wiki", Image: "https://example.com/wiki.png"},
+		},
+		{
+			name:   "Empty",
+			unfurl: `{}`,
+		},
+		{
+			name:      "Image Not HTTP",
+			unfurl:    `{"image": "javascript:alert(1)"}`,
+			respError: "field 'Image' must be a valid http or https URL",
+		},
+		{
+			name:      "Title Too Long",
+			unfurl:    `{"title": "` + strings.Repeat("x", 301) + `"}`,
+			respError: "field 'Title' must be at most 300 characters long",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return assert.ObjectsAreEqual(tc.want, link.Unfurl)
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			input := `{"url": "https://example.com", "unfurl": ` + tc.unfurl + `}`
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveTags(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      string
+		wantTags   []string
+		wantFolder string
+		respError  string
+	}{
+		{
+			name:       "Tags And Folder",
+			input:      `"tags": [" Launch ", "q3", "launch"], "folder": "Marketing"`,
+			wantTags:   []string{"launch", "q3"},
+			wantFolder: "Marketing",
+		},
+		{
+			name:  "None",
+			input: `"tags": []`,
+		},
+		{
+			name:      "Invalid Tag",
+			input:     `"tags": ["two words"]`,
+			respError: "invalid tag (letters, digits, '-', '_' and '.' only)",
+		},
+		{
+			name:      "Tag Too Long",
+			input:     `"tags": ["` + strings.Repeat("x", 33) + `"]`,
+			respError: "field 'Tags[0]' must be at most 32 characters long",
+		},
+		{
+			name:      "Invalid Folder",
+			input:     `"folder": "a\nb"`,
+			respError: "invalid folder (control characters not allowed)",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return assert.ObjectsAreEqual(tc.wantTags, link.Tags) && link.Folder == tc.wantFolder
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			input := `{"url": "https://example.com", ` + tc.input + `}`
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSavePrefix(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		respError string
+	}{
+		{
+			name:  "Prefix Link",
+			input: `"alias": "docs", "prefix": true`,
+		},
+		{
+			name:  "Reserved Alias Without Prefix",
+			input: `"alias": "qr"`,
+		},
+		{
+			name:      "Reserved Alias",
+			input:     `"alias": "qr", "prefix": true`,
+			respError: "alias is reserved, prefix links cannot have it",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.AnythingOfType("storage.Link")).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt"})
+
+			input := `{"url": "https://example.com", ` + tc.input + `}`
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+func TestSaveDomain(t *testing.T) {
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{{Host: "go.acme.com"}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		domain     string
+		wantDomain string
+		wantQRURL  string
+		respError  string
+	}{
+		{
+			name:      "Default",
+			wantQRURL: "https://sho.rt/qr/spring",
+		},
+		{
+			name:       "Other",
+			domain:     "Go.Acme.com.",
+			wantDomain: "go.acme.com",
+			wantQRURL:  "https://go.acme.com/qr/spring",
+		},
+		{
+			name:      "Unknown",
+			domain:    "go.other.com",
+			respError: "unknown domain: go.other.com",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.Domain == tc.wantDomain
+				})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt", Domains: domains})
+
+			input := fmt.Sprintf(`{"url": "https://example.com", "alias": "spring", "domain": %q}`, tc.domain)
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			assert.Equal(t, tc.wantQRURL, resp.QRURL)
+		})
+	}
+}
+
+func TestSaveURLPolicy(t *testing.T) {
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), urlpolicy.Config{OwnHosts: []string{"sho.rt"}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		body      string
+		respError string
+		respCode  string
+	}{
+		{
+			name: "Allowed",
+			body: `{"url": "https://example.com"}`,
+		},
+		{
+			name:      "Scheme",
+			body:      `{"url": "javascript:alert(1)"}`,
+			respError: "field 'URL': scheme is not allowed: javascript",
+			respCode:  response.CodeURLScheme,
+		},
+		{
+			name:      "Private Network",
+			body:      `{"url": "http://192.168.1.1/admin"}`,
+			respError: "field 'URL': host is in a private network: 192.168.1.1",
+			respCode:  response.CodeURLPrivate,
+		},
+		{
+			name:      "Own Domain",
+			body:      `{"url": "https://sho.rt/other"}`,
+			respError: "field 'URL': url points to this shortener: sho.rt",
+			respCode:  response.CodeURLLoop,
+		},
+		{
+			name:      "Fallback",
+			body:      `{"url": "https://example.com", "fallback_url": "http://localhost/"}`,
+			respError: "field 'FallbackURL': host is in a private network: localhost",
+			respCode:  response.CodeURLPrivate,
+		},
+		{
+			name:      "Destination",
+			body:      `{"url": "https://example.com", "destinations": [{"url": "https://sho.rt/b", "weight": 1}]}`,
+			respError: "field 'Destinations': url points to this shortener: sho.rt",
+			respCode:  response.CodeURLLoop,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.AnythingOfType("storage.Link")).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{PublicURL: "https://sho.rt", Policy: policy})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+		})
+	}
+}
+
+func TestSaveRedirectChain(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		resolved  map[string]string
+		mockError error
+		wantURL   string
+		wantFirst string
+		respError string
+		respCode  string
+	}{
+		{
+			name:     "Flattened",
+			body:     `{"url": "https://bit.example/abc"}`,
+			resolved: map[string]string{"https://bit.example/abc": "https://example.com/page"},
+			wantURL:  "https://example.com/page",
+		},
+		{
+			name: "Every URL",
+			body: `{"url": "https://example.com", "destinations": [{"url": "https://bit.example/b", "weight": 1}]}`,
+			resolved: map[string]string{
+				"https://example.com":   "https://example.com",
+				"https://bit.example/b": "https://example.com/b",
+			},
+			wantURL:   "https://example.com",
+			wantFirst: "https://example.com/b",
+		},
+		{
+			name:      "Loop",
+			body:      `{"url": "https://bit.example/loop"}`,
+			mockError: fmt.Errorf("%w back to https://bit.example/loop", redirectchain.ErrLoop),
+			respError: "field 'URL': redirect loop back to https://bit.example/loop",
+			respCode:  response.CodeURLLoop,
+		},
+		{
+			name:      "Too Many Hops",
+			body:      `{"url": "https://bit.example/long"}`,
+			mockError: fmt.Errorf("%w (more than 5)", redirectchain.ErrTooManyHops),
+			respError: "field 'URL': too many redirects (more than 5)",
+			respCode:  response.CodeURLChain,
+		},
+		{
+			name: "Refused Hop",
+			body: `{"url": "https://bit.example/in"}`,
+			mockError: fmt.Errorf("%w %s: %w", redirectchain.ErrRejected, "http://10.0.0.1/",
+				fmt.Errorf("%w: 10.0.0.1", urlpolicy.ErrPrivate)),
+			respError: "field 'URL': redirects to a refused url http://10.0.0.1/: host is in a private network: 10.0.0.1",
+			respCode:  response.CodeURLPrivate,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resolverMock := mocks.NewURLResolver(t)
+			if tc.mockError != nil {
+				resolverMock.On("Destination", mock.Anything, mock.AnythingOfType("string")).
+					Return("", tc.mockError).Once()
+			}
+			for from, to := range tc.resolved {
+				resolverMock.On("Destination", mock.Anything, from).Return(to, nil).Once()
+			}
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					if tc.wantFirst != "" && (len(link.Destinations) == 0 || link.Destinations[0].URL != tc.wantFirst) {
+						return false
+					}
+					return link.URL == tc.wantURL
+				})).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				PublicURL: "https://sho.rt",
+				Resolver:  resolverMock,
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+		})
+	}
+}
+
+func TestSaveMetadata(t *testing.T) {
+	cases := []struct {
+		name      string
+		mockError error
+		queued    bool
+	}{
+		{
+			name:   "Queued When Saved",
+			queued: true,
+		},
+		{
+			name:      "Not Queued When Not Saved",
+			mockError: storage.ErrURLExist,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveLink", mock.AnythingOfType("storage.Link")).
+				Return(int64(7), tc.mockError).Once()
+
+			// Any call the case does not expect fails the test.
+			metadataMock := mocks.NewMetadataQueue(t)
+			if tc.queued {
+				metadataMock.On("Enqueue", int64(7), "https://example.com/page").Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{Metadata: metadataMock})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(`{"url": "https://example.com/page", "alias": "page"}`))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+		})
+	}
+}
+
+func TestSaveWorkspace(t *testing.T) {
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{
+		{Host: "go.acme.com", Workspace: "acme"},
+		{Host: "go.other.com", Workspace: "other"},
+	})
+	require.NoError(t, err)
+
+	acme := storage.Workspace{ID: 3, Name: "acme"}
+
+	cases := []struct {
+		name      string
+		body      string
+		wantAlias string
+		mockError error
+		respError string
+		respCode  string
+	}{
+		{
+			name:      "Namespaced",
+			body:      `{"url": "https://example.com", "alias": "docs"}`,
+			wantAlias: "acme~docs",
+		},
+		{
+			name:      "Already Namespaced",
+			body:      `{"url": "https://example.com", "alias": "acme~docs"}`,
+			wantAlias: "acme~docs",
+		},
+		{
+			name:      "Own Domain",
+			body:      `{"url": "https://example.com", "alias": "docs", "domain": "go.acme.com"}`,
+			wantAlias: "docs",
+		},
+		{
+			name:      "Foreign Domain",
+			body:      `{"url": "https://example.com", "alias": "docs", "domain": "go.other.com"}`,
+			respError: "domain belongs to another workspace",
+			respCode:  response.CodeValidation,
+		},
+		{
+			name:      "Foreign Alias",
+			body:      `{"url": "https://example.com", "alias": "other~docs"}`,
+			respError: "alias is in the namespace of another workspace",
+			respCode:  response.CodeValidation,
+		},
+		{
+			name:      "Link Quota",
+			body:      `{"url": "https://example.com", "alias": "docs"}`,
+			wantAlias: "acme~docs",
+			mockError: storage.ErrLinkQuota,
+			respError: "link quota exceeded",
+			respCode:  response.CodeLinkQuota,
+		},
+		{
+			name:      "Daily Quota",
+			body:      `{"url": "https://example.com", "alias": "docs"}`,
+			wantAlias: "acme~docs",
+			mockError: storage.ErrDailyQuota,
+			respError: "daily quota exceeded",
+			respCode:  response.CodeDailyQuota,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.wantAlias != "" {
+				urlSaverMock.On("SaveLink", mock.MatchedBy(func(link storage.Link) bool {
+					return link.Alias == tc.wantAlias && link.WorkspaceID == acme.ID
+				})).
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{Domains: domains})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req = req.WithContext(workspace.NewContext(req.Context(), acme))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			if tc.respError == "" {
+				require.Equal(t, tc.wantAlias, resp.Alias)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "link-shortener/internal/storage"
+)
+
+// LinkSearcher is an autogenerated mock type for the LinkSearcher type
+type LinkSearcher struct {
+	mock.Mock
+}
+
+// SearchLinks provides a mock function with given fields: opts
+func (_m *LinkSearcher) SearchLinks(opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	ret := _m.Called(opts)
+
+	var r0 []storage.SearchResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(storage.SearchOptions) ([]storage.SearchResult, error)); ok {
+		return rf(opts)
+	}
+	if rf, ok := ret.Get(0).(func(storage.SearchOptions) []storage.SearchResult); ok {
+		r0 = rf(opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]storage.SearchResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(storage.SearchOptions) error); ok {
+		r1 = rf(opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewLinkSearcher interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewLinkSearcher creates a new instance of LinkSearcher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewLinkSearcher(t mockConstructorTestingTNewLinkSearcher) *LinkSearcher {
+	mock := &LinkSearcher{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Response struct {
+	response.Response
+	Results []storage.SearchResult `json:"results"`
+}
+
+var (
+	errMissingQuery  = errors.New("missing q")
+	errLongQuery     = errors.New("q is too long")
+	errInvalidPaging = errors.New("invalid limit or offset")
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	maxQueryLen  = 200
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkSearcher
+type LinkSearcher interface {
+	SearchLinks(opts storage.SearchOptions) ([]storage.SearchResult, error)
+}
+
+// New returns the handler searching the aliases, destinations, titles and
+// tags of links for the words in the q query parameter, best matches
+// first. Requests acting for a workspace only find the links of the
+// workspace.
+func New(log *slog.Logger, linkSearcher LinkSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.search.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid search options", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, err.Error()))
+			return
+		}
+		if ws, ok := workspace.FromContext(r.Context()); ok {
+			opts.WorkspaceID = ws.ID
+		}
+
+		results, err := linkSearcher.SearchLinks(opts)
+		if err != nil {
+			log.Error("failed to search urls", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to search urls"))
+			return
+		}
+
+		if results == nil {
+			results = []storage.SearchResult{}
+		}
+		for i := range results {
+			results[i].Link = results[i].Link.Public()
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Results:  results,
+		})
+	}
+}
+
+// parseOptions reads the q, limit and offset query parameters.
+func parseOptions(r *http.Request) (storage.SearchOptions, error) {
+	opts := storage.SearchOptions{Limit: defaultLimit}
+
+	opts.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+	if opts.Query == "" {
+		return opts, errMissingQuery
+	}
+	if len(opts.Query) > maxQueryLen {
+		return opts, errLongQuery
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return opts, errInvalidPaging
+		}
+		opts.Limit = limit
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, errInvalidPaging
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,119 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/search"
+	"link-shortener/internal/http-server/handlers/url/search/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+)
+
+func TestSearchHandler(t *testing.T) {
+	results := []storage.SearchResult{
+		{
+			Link:       storage.Link{ID: 1, Alias: "launch", URL: "https://example.com"},
+			Score:      2.5,
+			Highlights: storage.Highlights{Alias: "<mark>launch</mark>"},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		workspace *storage.Workspace
+		opts      storage.SearchOptions
+		results   []storage.SearchResult
+		respError string
+		mockError error
+	}{
+		{
+			name:    "Default Options",
+			query:   "?q=%20launch%20",
+			opts:    storage.SearchOptions{Query: "launch", Limit: 20},
+			results: results,
+		},
+		{
+			name:    "Limit And Offset",
+			query:   "?q=launch&limit=5&offset=10",
+			opts:    storage.SearchOptions{Query: "launch", Limit: 5, Offset: 10},
+			results: []storage.SearchResult{},
+		},
+		{
+			name:      "Workspace",
+			query:     "?q=launch",
+			workspace: &storage.Workspace{ID: 3, Name: "acme"},
+			opts:      storage.SearchOptions{Query: "launch", Limit: 20, WorkspaceID: 3},
+			results:   results,
+		},
+		{
+			name:      "Missing Query",
+			query:     "?q=%20",
+			respError: "missing q",
+		},
+		{
+			name:      "Query Too Long",
+			query:     "?q=" + strings.Repeat("x", 201),
+			respError: "q is too long",
+		},
+		{
+			name:      "Limit Too Large",
+			query:     "?q=launch&limit=1000",
+			respError: "invalid limit or offset",
+		},
+		{
+			name:      "SearchLinks Error",
+			query:     "?q=launch",
+			opts:      storage.SearchOptions{Query: "launch", Limit: 20},
+			respError: "failed to search urls",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkSearcherMock := mocks.NewLinkSearcher(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				linkSearcherMock.On("SearchLinks", tc.opts).
+					Return(tc.results, tc.mockError).
+					Once()
+			}
+
+			handler := search.New(slogdiscard.NewDiscardLogger(), linkSearcherMock)
+
+			ctx := context.Background()
+			if tc.workspace != nil {
+				ctx = workspace.NewContext(ctx, *tc.workspace)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/url/search"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp search.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.results, resp.Results)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "link-shortener/internal/storage"
+)
+
+// StatsGetter is an autogenerated mock type for the StatsGetter type
+type StatsGetter struct {
+	mock.Mock
+}
+
+// GetStats provides a mock function with given fields: domain, alias
+func (_m *StatsGetter) GetStats(domain string, alias string) (storage.Stats, error) {
+	ret := _m.Called(domain, alias)
+
+	var r0 storage.Stats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (storage.Stats, error)); ok {
+		return rf(domain, alias)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) storage.Stats); ok {
+		r0 = rf(domain, alias)
+	} else {
+		r0 = ret.Get(0).(storage.Stats)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(domain, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewStatsGetter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewStatsGetter creates a new instance of StatsGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewStatsGetter(t mockConstructorTestingTNewStatsGetter) *StatsGetter {
+	mock := &StatsGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
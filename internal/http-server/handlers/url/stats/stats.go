@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	response.Response
+	storage.Stats
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=StatsGetter
+type StatsGetter interface {
+	GetStats(domain, alias string) (storage.Stats, error)
+}
+
+// New returns the handler summarizing the clicks of the link with an alias.
+// Requests acting for a workspace name links like in info.New.
+func New(log *slog.Logger, statsGetter StatsGetter, domains *shortdomain.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		// The domain query parameter picks a link on another short domain.
+		domain := shortdomain.Host(r.URL.Query().Get("domain"))
+
+		if ws, ok := workspace.FromContext(r.Context()); ok {
+			var err error
+			if domain, alias, err = workspace.Resolve(ws, domains, domain, alias); err != nil {
+				log.Info("url not in workspace", slog.String("workspace", ws.Name), sl.Err(err))
+				render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "not found"))
+				return
+			}
+		}
+
+		stats, err := statsGetter.GetStats(domain, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("domain", domain), slog.String("alias", alias))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get stats", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Stats:    stats,
+		})
+	}
+}
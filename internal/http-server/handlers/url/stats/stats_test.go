@@ -0,0 +1,83 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/stats"
+	"link-shortener/internal/http-server/handlers/url/stats/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/storage"
+)
+
+func TestStatsHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		alias     string
+		stats     storage.Stats
+		respError string
+		mockError error
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			stats: storage.Stats{
+				Clicks: 3,
+				Daily:  []storage.DailyClicks{{Date: "2025-01-01", Clicks: 3}},
+			},
+		},
+		{
+			name:  "No Clicks",
+			alias: "test_alias",
+		},
+		{
+			name:      "Not Found",
+			alias:     "missing",
+			respError: "not found",
+			mockError: storage.ErrURLNotFound,
+		},
+		{
+			name:      "GetStats Error",
+			alias:     "test_alias",
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewStatsGetter(t)
+			statsGetterMock.On("GetStats", "", tc.alias).
+				Return(tc.stats, tc.mockError).
+				Once()
+
+			handler := chi.NewRouter()
+			handler.Get("/url/{alias}/stats", stats.New(slogdiscard.NewDiscardLogger(), statsGetterMock, nil))
+
+			req, err := http.NewRequest(http.MethodGet, "/url/"+tc.alias+"/stats", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp stats.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.stats, resp.Stats)
+		})
+	}
+}
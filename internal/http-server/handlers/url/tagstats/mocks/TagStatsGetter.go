@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// TagStatsGetter is an autogenerated mock type for the TagStatsGetter type
+type TagStatsGetter struct {
+	mock.Mock
+}
+
+// GetTagStats provides a mock function with given fields: workspaceID, tag
+func (_m *TagStatsGetter) GetTagStats(workspaceID int64, tag string) (storage.TagStats, error) {
+	ret := _m.Called(workspaceID, tag)
+
+	var r0 storage.TagStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string) (storage.TagStats, error)); ok {
+		return rf(workspaceID, tag)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string) storage.TagStats); ok {
+		r0 = rf(workspaceID, tag)
+	} else {
+		r0 = ret.Get(0).(storage.TagStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(workspaceID, tag)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewTagStatsGetter interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewTagStatsGetter creates a new instance of TagStatsGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewTagStatsGetter(t mockConstructorTestingTNewTagStatsGetter) *TagStatsGetter {
+	mock := &TagStatsGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,62 @@
+package tagstats
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type Response struct {
+	response.Response
+	storage.TagStats
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=TagStatsGetter
+type TagStatsGetter interface {
+	GetTagStats(workspaceID int64, tag string) (storage.TagStats, error)
+}
+
+// New returns the handler summarizing the clicks of all links with a tag.
+// Requests acting for a workspace only count the links of the workspace.
+func New(log *slog.Logger, statsGetter TagStatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.tagstats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		tag := strings.ToLower(chi.URLParam(r, "tag"))
+
+		var workspaceID int64
+		if ws, ok := workspace.FromContext(r.Context()); ok {
+			workspaceID = ws.ID
+		}
+
+		stats, err := statsGetter.GetTagStats(workspaceID, tag)
+		if errors.Is(err, storage.ErrTagNotFound) {
+			log.Info("tag not found", slog.String("tag", tag))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get tag stats", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "internal error"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			TagStats: stats,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package tagstats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/tagstats"
+	"link-shortener/internal/http-server/handlers/url/tagstats/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+)
+
+func TestTagStatsHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		tag         string
+		workspace   *storage.Workspace
+		wantTag     string
+		workspaceID int64
+		stats       storage.TagStats
+		respError   string
+		mockError   error
+	}{
+		{
+			name:    "Success",
+			tag:     "Launch",
+			wantTag: "launch",
+			stats: storage.TagStats{
+				Tag:   "launch",
+				Links: 2,
+				Stats: storage.Stats{Clicks: 3, Daily: []storage.DailyClicks{{Date: "2025-01-01", Clicks: 3}}},
+			},
+		},
+		{
+			name:        "Workspace",
+			tag:         "launch",
+			wantTag:     "launch",
+			workspace:   &storage.Workspace{ID: 3, Name: "acme"},
+			workspaceID: 3,
+			stats:       storage.TagStats{Tag: "launch", Links: 1},
+		},
+		{
+			name:      "Not Found",
+			tag:       "missing",
+			wantTag:   "missing",
+			respError: "not found",
+			mockError: storage.ErrTagNotFound,
+		},
+		{
+			name:      "GetTagStats Error",
+			tag:       "launch",
+			wantTag:   "launch",
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewTagStatsGetter(t)
+			statsGetterMock.On("GetTagStats", tc.workspaceID, tc.wantTag).
+				Return(tc.stats, tc.mockError).
+				Once()
+
+			handler := chi.NewRouter()
+			handler.Get("/url/tags/{tag}/stats", tagstats.New(slogdiscard.NewDiscardLogger(), statsGetterMock))
+
+			ctx := context.Background()
+			if tc.workspace != nil {
+				ctx = workspace.NewContext(ctx, *tc.workspace)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/url/tags/"+tc.tag+"/stats", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp tagstats.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.stats, resp.TagStats)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// LinkUpdater is an autogenerated mock type for the LinkUpdater type
+type LinkUpdater struct {
+	mock.Mock
+}
+
+// GetLinkByID provides a mock function with given fields: id
+func (_m *LinkUpdater) GetLinkByID(id int64) (storage.Link, error) {
+	ret := _m.Called(id)
+
+	var r0 storage.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64) (storage.Link, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(int64) storage.Link); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(storage.Link)
+	}
+
+	if rf, ok := ret.Get(1).(func(int64) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLink provides a mock function with given fields: link
+func (_m *LinkUpdater) UpdateLink(link storage.Link) error {
+	ret := _m.Called(link)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(storage.Link) error); ok {
+		r0 = rf(link)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewLinkUpdater interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewLinkUpdater creates a new instance of LinkUpdater. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewLinkUpdater(t mockConstructorTestingTNewLinkUpdater) *LinkUpdater {
+	mock := &LinkUpdater{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
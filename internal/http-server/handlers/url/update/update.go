@@ -0,0 +1,290 @@
+package update
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"link-shortener/internal/http-server/handlers/url/save"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Request contains the fields to change; omitted fields are left as is.
+type Request struct {
+	URL            *string `json:"url,omitempty"`
+	Alias          *string `json:"alias,omitempty"`
+	RedirectStatus *int    `json:"redirect_status,omitempty"`
+	// Folder moves the link to another folder; an empty one takes it out
+	// of its folder.
+	Folder *string `json:"folder,omitempty"`
+	// Tags replaces all tags; an empty list removes them.
+	Tags *[]string `json:"tags,omitempty"`
+	// Password replaces the link password; an empty one removes it.
+	Password *string `json:"password,omitempty"`
+	// MaxClicks changes the click limit; clicks already used still count.
+	MaxClicks *int64 `json:"max_clicks,omitempty"`
+	// ActiveFrom and ActiveUntil change the activation window (RFC 3339);
+	// an empty string removes the bound. An empty FallbackURL removes it.
+	ActiveFrom  *string `json:"active_from,omitempty"`
+	ActiveUntil *string `json:"active_until,omitempty"`
+	FallbackURL *string `json:"fallback_url,omitempty"`
+	// Targets replaces all platform targets; an empty list removes them.
+	Targets *[]save.Target `json:"targets,omitempty"`
+	// GeoTargets replaces all country targets; an empty list removes them.
+	GeoTargets *[]save.GeoTarget `json:"geo_targets,omitempty"`
+	// Destinations replaces all weighted destinations; an empty list
+	// removes them.
+	Destinations *[]save.Destination `json:"destinations,omitempty"`
+	// QueryParams replaces all query parameters; an empty list removes
+	// them.
+	QueryParams      *[]save.QueryParam `json:"query_params,omitempty"`
+	QueryPassthrough *bool              `json:"query_passthrough,omitempty"`
+	Prefix           *bool              `json:"prefix,omitempty"`
+	Interstitial     *bool              `json:"interstitial,omitempty"`
+	// Unfurl replaces what chat apps show for the link; an empty object
+	// goes back to the fetched metadata.
+	Unfurl *save.Unfurl `json:"unfurl,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	storage.Link
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=LinkUpdater
+type LinkUpdater interface {
+	GetLinkByID(id int64) (storage.Link, error)
+	UpdateLink(link storage.Link) error
+}
+
+// New returns the handler changing links. The result is validated like
+// save.New does, with policy, and its redirect chains resolved by resolver,
+// if it is not nil and a destination changed. Links given a new URL are
+// handed to meta, if it is not nil, for the metadata of their new page.
+// Requests acting for a workspace may only change the links of the
+// workspace, and give them aliases in its namespace on domains, see
+// workspace.Alias.
+func New(
+	log *slog.Logger,
+	linkUpdater LinkUpdater,
+	policy save.URLPolicy,
+	resolver save.URLResolver,
+	meta save.MetadataQueue,
+	domains *shortdomain.Set,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("can't parse url id", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "invalid id"))
+			return
+		}
+
+		var req Request
+
+		err = render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to parse request body", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInvalidRequest, "failed to decode request"))
+			return
+		}
+
+		ws, inWorkspace := workspace.FromContext(r.Context())
+
+		link, err := linkUpdater.GetLinkByID(id)
+		if err == nil && inWorkspace && link.WorkspaceID != ws.ID {
+			err = storage.ErrURLNotFound
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url id not found", slog.Int64("id", id))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "url id not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to update url"))
+			return
+		}
+		oldURL := link.URL
+
+		if req.URL != nil {
+			link.URL = *req.URL
+		}
+		if req.Alias != nil {
+			if *req.Alias == "" {
+				log.Info("empty alias")
+				render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, "field 'Alias' is required"))
+				return
+			}
+			link.Alias = *req.Alias
+			if inWorkspace {
+				domain, _ := domains.Get(link.Domain)
+				if link.Alias, err = workspace.Alias(ws, domain, link.Alias); err != nil {
+					log.Info("invalid request", sl.Err(err))
+					render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, err.Error()))
+					return
+				}
+			}
+		}
+		if req.RedirectStatus != nil {
+			link.RedirectStatus = *req.RedirectStatus
+		}
+		if req.Folder != nil {
+			link.Folder = *req.Folder
+		}
+		if req.Tags != nil {
+			link.Tags = *req.Tags
+		}
+		if req.MaxClicks != nil {
+			link.MaxClicks = *req.MaxClicks
+		}
+		if req.FallbackURL != nil {
+			link.FallbackURL = *req.FallbackURL
+		}
+		if req.Targets != nil {
+			link.Targets = nil
+			for _, t := range *req.Targets {
+				link.Targets = append(link.Targets, storage.Target{Platform: t.Platform, URL: t.URL})
+			}
+		}
+		if req.GeoTargets != nil {
+			link.GeoTargets = nil
+			for _, t := range *req.GeoTargets {
+				link.GeoTargets = append(link.GeoTargets, storage.GeoTarget{Country: t.Country, URL: t.URL})
+			}
+		}
+		if req.Destinations != nil {
+			link.Destinations = nil
+			for _, d := range *req.Destinations {
+				link.Destinations = append(link.Destinations, storage.Destination{URL: d.URL, Weight: d.Weight})
+			}
+		}
+		if link.ActiveFrom, err = updateTime(link.ActiveFrom, req.ActiveFrom); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, "field 'ActiveFrom' must be an RFC 3339 time"))
+			return
+		}
+		if link.ActiveUntil, err = updateTime(link.ActiveUntil, req.ActiveUntil); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, "field 'ActiveUntil' must be an RFC 3339 time"))
+			return
+		}
+		if req.QueryParams != nil {
+			link.QueryParams = nil
+			for _, p := range *req.QueryParams {
+				link.QueryParams = append(link.QueryParams, storage.QueryParam{Key: p.Key, Value: p.Value})
+			}
+		}
+		if req.QueryPassthrough != nil {
+			link.QueryPassthrough = *req.QueryPassthrough
+		}
+		if req.Prefix != nil {
+			link.Prefix = *req.Prefix
+		}
+		if req.Interstitial != nil {
+			link.Interstitial = *req.Interstitial
+		}
+		if req.Unfurl != nil {
+			link.Unfurl = req.Unfurl.PageMeta()
+		}
+		if req.Password != nil {
+			link.PasswordHash = ""
+			if *req.Password != "" {
+				link.PasswordHash, err = save.HashPassword(*req.Password)
+				if errors.Is(err, save.ErrPasswordTooLong) {
+					log.Info("invalid request", sl.Err(err))
+					render.JSON(w, r, response.ErrorWithCode(response.CodeValidation, err.Error()))
+					return
+				}
+				if err != nil {
+					log.Error("failed to hash password", sl.Err(err))
+					render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to update url"))
+					return
+				}
+			}
+		}
+
+		// The result must still be something save.New would have accepted.
+		if link, err = save.ValidateLink(link, policy); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(save.ErrorCode(err), err.Error()))
+			return
+		}
+
+		destChanged := req.URL != nil || req.FallbackURL != nil || req.Targets != nil ||
+			req.GeoTargets != nil || req.Destinations != nil
+		if resolver != nil && destChanged {
+			if link, err = save.ResolveLink(r.Context(), link, resolver); err != nil {
+				log.Info("refused redirect chain", sl.Err(err))
+				render.JSON(w, r, response.ErrorWithCode(save.ErrorCode(err), err.Error()))
+				return
+			}
+		}
+
+		err = linkUpdater.UpdateLink(link)
+		if errors.Is(err, storage.ErrURLExist) {
+			log.Info("alias already exists", slog.String("alias", link.Alias))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeAliasExists, "url already exists"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url id not found", slog.Int64("id", id))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeNotFound, "url id not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "failed to update url"))
+			return
+		}
+
+		log.Info("url updated", slog.Int64("id", id))
+		if meta != nil && link.URL != oldURL {
+			meta.Enqueue(link.ID, link.URL)
+		}
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Link:     link.Public(),
+		})
+	}
+}
+
+// updateTime applies a time field of Request to the current value: nil
+// keeps it, an empty string clears it and anything else must be RFC 3339.
+func updateTime(current *time.Time, value *string) (*time.Time, error) {
+	if value == nil {
+		return current, nil
+	}
+	if *value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return current, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
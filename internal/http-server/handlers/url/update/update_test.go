@@ -0,0 +1,288 @@
+package update_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/handlers/url/update"
+	"link-shortener/internal/http-server/handlers/url/update/mocks"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	existing := storage.Link{ID: 10, Alias: "old_alias", URL: "https://google.com"}
+	from := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		uri         string
+		body        string
+		getError    error
+		updated     *storage.Link
+		updateError error
+		respError   string
+	}{
+		{
+			name:    "Update URL",
+			uri:     "/url/10",
+			body:    `{"url": "https://go.dev"}`,
+			updated: &storage.Link{ID: 10, Alias: "old_alias", URL: "https://go.dev"},
+		},
+		{
+			name:    "Update Alias",
+			uri:     "/url/10",
+			body:    `{"alias": "new_alias"}`,
+			updated: &storage.Link{ID: 10, Alias: "new_alias", URL: "https://google.com"},
+		},
+		{
+			name:    "Set Tags And Folder",
+			uri:     "/url/10",
+			body:    `{"tags": ["Q3", "launch", "q3"], "folder": " Marketing "}`,
+			updated: &storage.Link{ID: 10, Alias: "old_alias", URL: "https://google.com", Folder: "Marketing", Tags: []string{"launch", "q3"}},
+		},
+		{
+			name: "Set Activation Window",
+			uri:  "/url/10",
+			body: `{"active_from": "2025-03-01T09:00:00+02:00", "fallback_url": "https://go.dev"}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com",
+				ActiveFrom: &from, FallbackURL: "https://go.dev",
+			},
+		},
+		{
+			name: "Set Targets",
+			uri:  "/url/10",
+			body: `{"targets": [{"platform": "ios", "url": "https://apps.apple.com/app/id1"}]}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com",
+				Targets: []storage.Target{{Platform: "ios", URL: "https://apps.apple.com/app/id1"}},
+			},
+		},
+		{
+			name: "Set Destinations",
+			uri:  "/url/10",
+			body: `{"destinations": [{"url": "https://go.dev", "weight": 1}, {"url": "https://go.dev/doc", "weight": 1}]}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com",
+				Destinations: []storage.Destination{{URL: "https://go.dev", Weight: 1}, {URL: "https://go.dev/doc", Weight: 1}},
+			},
+		},
+		{
+			name: "Set Geo Targets",
+			uri:  "/url/10",
+			body: `{"geo_targets": [{"country": "DE", "url": "https://google.de"}]}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com",
+				GeoTargets: []storage.GeoTarget{{Country: "DE", URL: "https://google.de"}},
+			},
+		},
+		{
+			name:      "Invalid Country",
+			uri:       "/url/10",
+			body:      `{"geo_targets": [{"country": "Germany", "url": "https://google.de"}]}`,
+			respError: "field 'Country' must be a two-letter country code",
+		},
+		{
+			name: "Set Query Params",
+			uri:  "/url/10",
+			body: `{"query_params": [{"key": "utm_source", "value": "news"}], "query_passthrough": true}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com",
+				QueryParams:      []storage.QueryParam{{Key: "utm_source", Value: "news"}},
+				QueryPassthrough: true,
+			},
+		},
+		{
+			name: "Set Prefix",
+			uri:  "/url/10",
+			body: `{"prefix": true}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com", Prefix: true,
+			},
+		},
+		{
+			name: "Set Interstitial",
+			uri:  "/url/10",
+			body: `{"interstitial": true}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com", Interstitial: true,
+			},
+		},
+		{
+			name: "Set Unfurl",
+			uri:  "/url/10",
+			body: `{"unfurl": {"title": "Team Wiki"}}`,
+			updated: &storage.Link{
+				ID: 10, Alias: "old_alias", URL: "https://google.com", Unfurl: &storage.PageMeta{Title: "Team Wiki"},
+			},
+		},
+		{
+			name:      "Invalid Unfurl Image",
+			uri:       "/url/10",
+			body:      `{"unfurl": {"image": "wiki.png"}}`,
+			respError: "field 'Image' must be a valid http or https URL",
+		},
+		{
+			name:      "Empty Query Param Key",
+			uri:       "/url/10",
+			body:      `{"query_params": [{"value": "news"}]}`,
+			respError: "field 'Key' is required",
+		},
+		{
+			name:      "Invalid Target",
+			uri:       "/url/10",
+			body:      `{"targets": [{"platform": "ios", "url": "app store"}]}`,
+			respError: "field 'URL' must be a valid URL",
+		},
+		{
+			name:      "Invalid Activation Time",
+			uri:       "/url/10",
+			body:      `{"active_until": "next week"}`,
+			respError: "field 'ActiveUntil' must be an RFC 3339 time",
+		},
+		{
+			name:      "Invalid ID",
+			uri:       "/url/XXX",
+			body:      `{"url": "https://go.dev"}`,
+			respError: "invalid id",
+		},
+		{
+			name:      "Empty Body",
+			uri:       "/url/10",
+			respError: "empty request",
+		},
+		{
+			name:      "ID Not Found",
+			uri:       "/url/10",
+			body:      `{"url": "https://go.dev"}`,
+			getError:  storage.ErrURLNotFound,
+			respError: "url id not found",
+		},
+		{
+			name:      "Invalid URL",
+			uri:       "/url/10",
+			body:      `{"url": "not a url"}`,
+			respError: "field 'URL' must be a valid URL",
+		},
+		{
+			name:      "Invalid Alias",
+			uri:       "/url/10",
+			body:      `{"alias": "!@#"}`,
+			respError: "invalid alias (special characters not allowed)",
+		},
+		{
+			name:      "Empty Alias",
+			uri:       "/url/10",
+			body:      `{"alias": ""}`,
+			respError: "field 'Alias' is required",
+		},
+		{
+			name:      "Workspace Alias",
+			uri:       "/url/10",
+			body:      `{"alias": "acme~docs"}`,
+			respError: "aliases with '~' are only for the links of workspaces",
+		},
+		{
+			name:        "Alias Exists",
+			uri:         "/url/10",
+			body:        `{"alias": "taken"}`,
+			updated:     &storage.Link{ID: 10, Alias: "taken", URL: "https://google.com"},
+			updateError: storage.ErrURLExist,
+			respError:   "url already exists",
+		},
+		{
+			name:        "UpdateLink Error",
+			uri:         "/url/10",
+			body:        `{"url": "https://go.dev"}`,
+			updated:     &storage.Link{ID: 10, Alias: "old_alias", URL: "https://go.dev"},
+			updateError: errors.New("unexpected error"),
+			respError:   "failed to update url",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			linkUpdaterMock := mocks.NewLinkUpdater(t)
+
+			if tc.uri == "/url/10" && tc.body != "" {
+				linkUpdaterMock.On("GetLinkByID", int64(10)).
+					Return(existing, tc.getError).
+					Once()
+			}
+			if tc.updated != nil {
+				linkUpdaterMock.On("UpdateLink", *tc.updated).
+					Return(tc.updateError).
+					Once()
+			}
+
+			handler := chi.NewRouter()
+			handler.Patch("/url/{id}", update.New(slogdiscard.NewDiscardLogger(), linkUpdaterMock, nil, nil, nil, nil))
+
+			req, err := http.NewRequest(http.MethodPatch, tc.uri, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp update.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, *tc.updated, resp.Link)
+			}
+		})
+	}
+}
+
+func TestUpdateHandlerResolvesRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	// httptest servers listen on loopback.
+	resolver := redirectchain.New(redirectchain.Config{AllowPrivate: true})
+
+	linkUpdaterMock := mocks.NewLinkUpdater(t)
+	linkUpdaterMock.On("GetLinkByID", int64(10)).
+		Return(storage.Link{ID: 10, Alias: "old_alias", URL: "https://google.com"}, nil).
+		Once()
+
+	handler := chi.NewRouter()
+	handler.Patch("/url/{id}", update.New(slogdiscard.NewDiscardLogger(), linkUpdaterMock, nil, resolver, nil, nil))
+
+	body, err := json.Marshal(map[string]string{"url": srv.URL + "/loop"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPatch, "/url/10", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp update.Response
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Equal(t, "url_redirect_loop", resp.Code)
+	linkUpdaterMock.AssertNotCalled(t, "UpdateLink", mock.Anything)
+}
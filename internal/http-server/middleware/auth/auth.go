@@ -0,0 +1,88 @@
+// Package auth lets requests to the API in as the admin, with BasicAuth,
+// or on behalf of a workspace, with one of its API keys as a bearer token.
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/lib/apikey"
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const realm = "link-shortener"
+
+// KeyLookup finds the workspace of an API key by the hash of the key.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyLookup
+type KeyLookup interface {
+	WorkspaceByKey(hash string) (storage.Workspace, error)
+}
+
+// New returns middleware passing on requests with the admin credentials
+// user and password, or with an API key keys knows. The workspace of the
+// key is put in the request context, see workspace.FromContext. Other
+// requests are refused with 401.
+func New(log *slog.Logger, user, password string, keys KeyLookup) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.New"
+
+			if key, ok := bearerToken(r); ok {
+				ws, err := keys.WorkspaceByKey(apikey.Hash(key))
+				if errors.Is(err, storage.ErrAPIKeyNotFound) {
+					unauthorized(w, r)
+					return
+				}
+				if err != nil {
+					log.Error("failed to look up api key",
+						slog.String("op", op),
+						slog.String("request_id", middleware.GetReqID(r.Context())),
+						sl.Err(err),
+					)
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, response.ErrorWithCode(response.CodeInternal, "internal error"))
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(workspace.NewContext(r.Context(), ws)))
+				return
+			}
+
+			u, p, ok := r.BasicAuth()
+			if !ok || !equal(u, user) || !equal(p, password) {
+				unauthorized(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, response.ErrorWithCode(response.CodeUnauthorized, "unauthorized"))
+}
@@ -0,0 +1,106 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/http-server/middleware/auth"
+	"link-shortener/internal/http-server/middleware/auth/mocks"
+	"link-shortener/internal/lib/apikey"
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+)
+
+func TestAuth(t *testing.T) {
+	acme := storage.Workspace{ID: 3, Name: "acme"}
+
+	cases := []struct {
+		name          string
+		user, pass    string
+		key           string
+		mockWorkspace storage.Workspace
+		mockError     error
+		wantCode      int
+		wantWorkspace string
+	}{
+		{
+			name:     "Admin",
+			user:     "user",
+			pass:     "pass",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Wrong Password",
+			user:     "user",
+			pass:     "nope",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "No Credentials",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "API Key",
+			key:           "lsk_good",
+			mockWorkspace: acme,
+			wantCode:      http.StatusOK,
+			wantWorkspace: "acme",
+		},
+		{
+			name:      "Unknown API Key",
+			key:       "lsk_bad",
+			mockError: storage.ErrAPIKeyNotFound,
+			wantCode:  http.StatusUnauthorized,
+		},
+		{
+			name:      "Storage Error",
+			key:       "lsk_good",
+			mockError: errors.New("unexpected error"),
+			wantCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys := mocks.NewKeyLookup(t)
+			if tc.key != "" {
+				keys.On("WorkspaceByKey", apikey.Hash(tc.key)).
+					Return(tc.mockWorkspace, tc.mockError).
+					Once()
+			}
+
+			var gotWorkspace string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ws, ok := workspace.FromContext(r.Context()); ok {
+					gotWorkspace = ws.Name
+				}
+			})
+			handler := auth.New(slogdiscard.NewDiscardLogger(), "user", "pass", keys)(next)
+
+			req, err := http.NewRequest(http.MethodGet, "/url", nil)
+			require.NoError(t, err)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantCode, rr.Code)
+			assert.Equal(t, tc.wantWorkspace, gotWorkspace)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// KeyLookup is an autogenerated mock type for the KeyLookup type
+type KeyLookup struct {
+	mock.Mock
+}
+
+// WorkspaceByKey provides a mock function with given fields: hash
+func (_m *KeyLookup) WorkspaceByKey(hash string) (storage.Workspace, error) {
+	ret := _m.Called(hash)
+
+	var r0 storage.Workspace
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (storage.Workspace, error)); ok {
+		return rf(hash)
+	}
+	if rf, ok := ret.Get(0).(func(string) storage.Workspace); ok {
+		r0 = rf(hash)
+	} else {
+		r0 = ret.Get(0).(storage.Workspace)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewKeyLookup interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewKeyLookup creates a new instance of KeyLookup. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewKeyLookup(t mockConstructorTestingTNewKeyLookup) *KeyLookup {
+	mock := &KeyLookup{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
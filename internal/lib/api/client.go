@@ -0,0 +1,602 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"link-shortener/internal/lib/api/response"
+)
+
+// Errors a Client call can be matched against with errors.Is.
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrValidation     = errors.New("validation failed")
+	ErrNotFound       = errors.New("not found")
+	ErrAliasExists    = errors.New("alias already exists")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrServer         = errors.New("server error")
+	ErrBatchFailed    = errors.New("batch rolled back")
+	ErrQuotaExceeded  = errors.New("workspace quota exceeded")
+)
+
+// Error is returned when the server rejects a request. It wraps one of the
+// sentinel errors above, chosen by the code in response.Response.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.Code == response.CodeInvalidRequest:
+		return ErrInvalidRequest
+	case e.Code == response.CodeValidation:
+		return ErrValidation
+	case e.Code == response.CodeNotFound:
+		return ErrNotFound
+	case e.Code == response.CodeAliasExists:
+		return ErrAliasExists
+	case e.Code == response.CodeBatchFailed:
+		return ErrBatchFailed
+	case e.Code == response.CodeLinkQuota, e.Code == response.CodeDailyQuota:
+		return ErrQuotaExceeded
+	case e.Code == response.CodeInternal, e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
+
+type Link struct {
+	ID    int64  `json:"id"`
+	Alias string `json:"alias"`
+	URL   string `json:"url"`
+	// Domain is the short domain the link is on, empty for the default one.
+	Domain         string `json:"domain,omitempty"`
+	RedirectStatus int    `json:"redirect_status,omitempty"`
+	Protected      bool   `json:"protected,omitempty"`
+	MaxClicks      int64  `json:"max_clicks,omitempty"`
+	// RemainingClicks is only set for links with MaxClicks.
+	RemainingClicks  *int64        `json:"remaining_clicks,omitempty"`
+	ActiveFrom       *time.Time    `json:"active_from,omitempty"`
+	ActiveUntil      *time.Time    `json:"active_until,omitempty"`
+	FallbackURL      string        `json:"fallback_url,omitempty"`
+	Targets          []Target      `json:"targets,omitempty"`
+	GeoTargets       []GeoTarget   `json:"geo_targets,omitempty"`
+	Destinations     []Destination `json:"destinations,omitempty"`
+	QueryParams      []QueryParam  `json:"query_params,omitempty"`
+	QueryPassthrough bool          `json:"query_passthrough,omitempty"`
+	Prefix           bool          `json:"prefix,omitempty"`
+	Interstitial     bool          `json:"interstitial,omitempty"`
+	// Title, Description and Image describe the destination page, once
+	// the server has fetched it.
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Unfurl, if set, is what chat apps show for the link instead.
+	Unfurl *Unfurl  `json:"unfurl,omitempty"`
+	Folder string   `json:"folder,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// Unfurl is the title, description and image chat apps show when the link
+// is shared. Empty fields fall back to those fetched from the destination.
+type Unfurl struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// Target sends the visitors on one platform (ios, android, windows, macos,
+// linux, mobile or desktop) to URL.
+type Target struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// GeoTarget sends the visitors from one country, given as an ISO 3166-1
+// alpha-2 code such as "DE", to URL.
+type GeoTarget struct {
+	Country string `json:"country"`
+	URL     string `json:"url"`
+}
+
+// QueryParam is added to the query of redirects. Its value may contain the
+// placeholders {alias} and {country}.
+type QueryParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Destination is one variant of a split link, chosen with a probability of
+// Weight over the total weight.
+type Destination struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+type CreateRequest struct {
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"`
+	// Domain is one of the short domains the server is configured with;
+	// the link is on the default one if it is empty.
+	Domain         string `json:"domain,omitempty"`
+	RedirectStatus int    `json:"redirect_status,omitempty"`
+	Password       string `json:"password,omitempty"`
+	MaxClicks      int64  `json:"max_clicks,omitempty"`
+	// ActiveFrom and ActiveUntil limit when the link redirects to URL;
+	// outside of that time it redirects to FallbackURL, if set.
+	ActiveFrom   *time.Time    `json:"active_from,omitempty"`
+	ActiveUntil  *time.Time    `json:"active_until,omitempty"`
+	FallbackURL  string        `json:"fallback_url,omitempty"`
+	Targets      []Target      `json:"targets,omitempty"`
+	GeoTargets   []GeoTarget   `json:"geo_targets,omitempty"`
+	Destinations []Destination `json:"destinations,omitempty"`
+	// QueryParams are added to the query of redirects; QueryPassthrough
+	// forwards the query of the short URL too.
+	QueryParams      []QueryParam `json:"query_params,omitempty"`
+	QueryPassthrough bool         `json:"query_passthrough,omitempty"`
+	// Prefix makes the link match paths below the alias too.
+	Prefix bool `json:"prefix,omitempty"`
+	// Interstitial warns browsers before they leave for an external domain.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Unfurl sets what chat apps show for the link.
+	Unfurl *Unfurl `json:"unfurl,omitempty"`
+	// Folder files the link; Tags label it. The server lowercases tags.
+	Folder string   `json:"folder,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// UpdateRequest contains the fields to change; nil fields are left as is.
+// An empty Password removes the password. ActiveFrom and ActiveUntil are
+// RFC 3339 times; empty ones remove the bound, as does an empty FallbackURL.
+// Targets, GeoTargets, Destinations and QueryParams replace the whole list;
+// an empty, non-nil list removes it, as it does for Tags. An empty, non-nil
+// Unfurl goes back to the fetched metadata, and an empty Folder unfiles the
+// link.
+type UpdateRequest struct {
+	URL              *string        `json:"url,omitempty"`
+	Alias            *string        `json:"alias,omitempty"`
+	RedirectStatus   *int           `json:"redirect_status,omitempty"`
+	Password         *string        `json:"password,omitempty"`
+	MaxClicks        *int64         `json:"max_clicks,omitempty"`
+	ActiveFrom       *string        `json:"active_from,omitempty"`
+	ActiveUntil      *string        `json:"active_until,omitempty"`
+	FallbackURL      *string        `json:"fallback_url,omitempty"`
+	Targets          *[]Target      `json:"targets,omitempty"`
+	GeoTargets       *[]GeoTarget   `json:"geo_targets,omitempty"`
+	Destinations     *[]Destination `json:"destinations,omitempty"`
+	QueryParams      *[]QueryParam  `json:"query_params,omitempty"`
+	QueryPassthrough *bool          `json:"query_passthrough,omitempty"`
+	Prefix           *bool          `json:"prefix,omitempty"`
+	Interstitial     *bool          `json:"interstitial,omitempty"`
+	Unfurl           *Unfurl        `json:"unfurl,omitempty"`
+	Folder           *string        `json:"folder,omitempty"`
+	Tags             *[]string      `json:"tags,omitempty"`
+}
+
+// Batch modes, see CreateBatch.
+const (
+	BatchBestEffort   = "best_effort"
+	BatchAllOrNothing = "all_or_nothing"
+)
+
+type BatchRequest struct {
+	Links []CreateRequest `json:"links"`
+	Mode  string          `json:"mode,omitempty"`
+}
+
+type BatchResult struct {
+	Index int    `json:"index"`
+	Alias string `json:"alias,omitempty"`
+	ID    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+type BatchResponse struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Title, if set, only lists links whose page title contains it.
+	Title string
+	// Tag and Folder, if set, only list the links with that tag and in
+	// that folder.
+	Tag    string
+	Folder string
+}
+
+// SearchOptions are the query and paging of a search. A zero Limit uses
+// the server default.
+type SearchOptions struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SearchResult is a link found by a search. Highlights holds the fields
+// that matched, HTML-escaped and with the matching words in <mark> tags.
+type SearchResult struct {
+	Link       Link       `json:"link"`
+	Score      float64    `json:"score"`
+	Highlights Highlights `json:"highlights"`
+}
+
+type Highlights struct {
+	Alias string   `json:"alias,omitempty"`
+	URL   string   `json:"url,omitempty"`
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type Stats struct {
+	Clicks      int64           `json:"clicks"`
+	LastClickAt *time.Time      `json:"last_click_at,omitempty"`
+	Daily       []DailyClicks   `json:"daily,omitempty"`
+	Variants    []VariantClicks `json:"variants,omitempty"`
+	Countries   []CountryClicks `json:"countries,omitempty"`
+}
+
+// TagStats sums up the clicks of all links with a tag.
+type TagStats struct {
+	Tag   string `json:"tag"`
+	Links int64  `json:"links"`
+	Stats
+}
+
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// CountryClicks counts the clicks from one country.
+type CountryClicks struct {
+	Country string `json:"country"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// VariantClicks counts the clicks sent to one destination of a split link.
+type VariantClicks struct {
+	URL    string `json:"url"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Client talks to the link-shortener HTTP API.
+type Client struct {
+	BaseURL  string
+	User     string
+	Password string
+	// APIKey, if set, is sent instead of User and Password, to act for the
+	// workspace of the key.
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the server at baseURL using BasicAuth.
+func NewClient(baseURL, user, password string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		User:       user,
+		Password:   password,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ShortURL returns the public short URL for alias.
+func (c *Client) ShortURL(alias string) string {
+	return c.BaseURL + "/" + url.PathEscape(alias)
+}
+
+// ShortURLOn returns the short URL for alias on the short domain host,
+// served with the scheme of BaseURL. An empty host is the default domain.
+func (c *Client) ShortURLOn(host, alias string) string {
+	if host == "" {
+		return c.ShortURL(alias)
+	}
+
+	scheme := "https"
+	if u, err := url.Parse(c.BaseURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + host + "/" + url.PathEscape(alias)
+}
+
+func (c *Client) Create(ctx context.Context, req CreateRequest) (Link, error) {
+	const op = "api.Client.Create"
+
+	var resp struct {
+		ID    int64  `json:"id"`
+		Alias string `json:"alias"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/url", nil, req, &resp); err != nil {
+		return Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := Link{
+		ID:               resp.ID,
+		Alias:            resp.Alias,
+		URL:              req.URL,
+		Domain:           req.Domain,
+		RedirectStatus:   req.RedirectStatus,
+		Protected:        req.Password != "",
+		MaxClicks:        req.MaxClicks,
+		ActiveFrom:       req.ActiveFrom,
+		ActiveUntil:      req.ActiveUntil,
+		FallbackURL:      req.FallbackURL,
+		Targets:          req.Targets,
+		GeoTargets:       req.GeoTargets,
+		Destinations:     req.Destinations,
+		QueryParams:      req.QueryParams,
+		QueryPassthrough: req.QueryPassthrough,
+		Prefix:           req.Prefix,
+		Interstitial:     req.Interstitial,
+		Unfurl:           req.Unfurl,
+		Folder:           req.Folder,
+		Tags:             req.Tags,
+	}
+	if link.MaxClicks > 0 {
+		remaining := link.MaxClicks
+		link.RemainingClicks = &remaining
+	}
+
+	return link, nil
+}
+
+// CreateBatch creates many links in one call. When an all-or-nothing batch
+// is rolled back, the per-link results are returned along with the error.
+func (c *Client) CreateBatch(ctx context.Context, req BatchRequest) (BatchResponse, error) {
+	const op = "api.Client.CreateBatch"
+
+	var resp BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/url/batch", nil, req, &resp); err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) Get(ctx context.Context, alias string) (Link, error) {
+	return c.GetOn(ctx, "", alias)
+}
+
+// GetOn returns the link with alias on the short domain host, or on the
+// default domain if host is empty.
+func (c *Client) GetOn(ctx context.Context, host, alias string) (Link, error) {
+	const op = "api.Client.GetOn"
+
+	var link Link
+	if err := c.do(ctx, http.MethodGet, "/url/"+url.PathEscape(alias), domainQuery(host), nil, &link); err != nil {
+		return Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]Link, error) {
+	const op = "api.Client.List"
+
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Title != "" {
+		query.Set("title", opts.Title)
+	}
+	if opts.Tag != "" {
+		query.Set("tag", opts.Tag)
+	}
+	if opts.Folder != "" {
+		query.Set("folder", opts.Folder)
+	}
+
+	var resp struct {
+		Links []Link `json:"links"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/url", query, nil, &resp); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.Links, nil
+}
+
+// Search returns the links whose alias, destination, title or tags match
+// the words of opts.Query, best matches first.
+func (c *Client) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	const op = "api.Client.Search"
+
+	query := url.Values{"q": {opts.Query}}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var resp struct {
+		Results []SearchResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/url/search", query, nil, &resp); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.Results, nil
+}
+
+func (c *Client) Update(ctx context.Context, id int64, req UpdateRequest) (Link, error) {
+	const op = "api.Client.Update"
+
+	var link Link
+	if err := c.do(ctx, http.MethodPatch, "/url/"+strconv.FormatInt(id, 10), nil, req, &link); err != nil {
+		return Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (c *Client) Delete(ctx context.Context, id int64) error {
+	const op = "api.Client.Delete"
+
+	if err := c.do(ctx, http.MethodDelete, "/url/"+strconv.FormatInt(id, 10), nil, nil, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Client) Stats(ctx context.Context, alias string) (Stats, error) {
+	return c.StatsOn(ctx, "", alias)
+}
+
+// StatsOn returns the statistics of the link with alias on the short domain
+// host, or on the default domain if host is empty.
+func (c *Client) StatsOn(ctx context.Context, host, alias string) (Stats, error) {
+	const op = "api.Client.StatsOn"
+
+	var stats Stats
+	if err := c.do(ctx, http.MethodGet, "/url/"+url.PathEscape(alias)+"/stats", domainQuery(host), nil, &stats); err != nil {
+		return Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// TagStats returns the statistics of all links with tag that the client
+// can see: those of its workspace when it uses an API key.
+func (c *Client) TagStats(ctx context.Context, tag string) (TagStats, error) {
+	const op = "api.Client.TagStats"
+
+	var stats TagStats
+	if err := c.do(ctx, http.MethodGet, "/url/tags/"+url.PathEscape(tag)+"/stats", nil, nil, &stats); err != nil {
+		return TagStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// domainQuery names the short domain host in the query of a request, if it
+// is not the default one.
+func domainQuery(host string) url.Values {
+	if host == "" {
+		return nil
+	}
+	return url.Values{"domain": {host}}
+}
+
+// do sends a JSON request and decodes a successful response into out.
+// Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.APIKey != "":
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	case c.User != "":
+		req.SetBasicAuth(c.User, c.Password)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	var envelope response.Response
+	if err := json.Unmarshal(raw, &envelope); err != nil || resp.StatusCode >= http.StatusBadRequest {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       envelope.Code,
+			Message:    statusMessage(resp.StatusCode, envelope, raw),
+		}
+	}
+
+	if envelope.Status != response.StatusOK {
+		// Some error responses still carry details (e.g. per-link batch
+		// results), so decode them for the caller as well.
+		if out != nil {
+			_ = json.Unmarshal(raw, out)
+		}
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       envelope.Code,
+			Message:    envelope.Error,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// statusMessage picks the most useful description of a non-JSON or non-2xx
+// response.
+func statusMessage(statusCode int, envelope response.Response, raw []byte) string {
+	if envelope.Error != "" {
+		return envelope.Error
+	}
+	if text := strings.TrimSpace(string(raw)); text != "" && len(text) < 200 {
+		return fmt.Sprintf("%s: %s", http.StatusText(statusCode), text)
+	}
+	return http.StatusText(statusCode)
+}
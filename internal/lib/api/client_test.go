@@ -0,0 +1,265 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/api"
+)
+
+func TestClient(t *testing.T) {
+	cases := []struct {
+		name       string
+		call       func(c *api.Client) (any, error)
+		wantMethod string
+		wantURI    string
+		wantBody   string
+		status     int
+		respBody   string
+		want       any
+		wantErr    error
+	}{
+		{
+			name: "Create",
+			call: func(c *api.Client) (any, error) {
+				return c.Create(context.Background(), api.CreateRequest{URL: "https://google.com", Alias: "g"})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/url",
+			wantBody:   `{"url":"https://google.com","alias":"g"}`,
+			respBody:   `{"status":"OK","alias":"g","id":7}`,
+			want:       api.Link{ID: 7, Alias: "g", URL: "https://google.com"},
+		},
+		{
+			name: "Create Alias Exists",
+			call: func(c *api.Client) (any, error) {
+				return c.Create(context.Background(), api.CreateRequest{URL: "https://google.com", Alias: "g"})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/url",
+			wantBody:   `{"url":"https://google.com","alias":"g"}`,
+			respBody:   `{"status":"Error","error":"url already exists","code":"alias_exists"}`,
+			wantErr:    api.ErrAliasExists,
+		},
+		{
+			name: "Create Quota Exceeded",
+			call: func(c *api.Client) (any, error) {
+				return c.Create(context.Background(), api.CreateRequest{URL: "https://google.com"})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/url",
+			wantBody:   `{"url":"https://google.com"}`,
+			respBody:   `{"status":"Error","error":"daily quota exceeded","code":"daily_quota_exceeded"}`,
+			wantErr:    api.ErrQuotaExceeded,
+		},
+		{
+			name: "CreateBatch Rolled Back",
+			call: func(c *api.Client) (any, error) {
+				return c.CreateBatch(context.Background(), api.BatchRequest{
+					Links: []api.CreateRequest{{URL: "https://google.com", Alias: "g"}},
+					Mode:  api.BatchAllOrNothing,
+				})
+			},
+			wantMethod: http.MethodPost,
+			wantURI:    "/url/batch",
+			wantBody:   `{"links":[{"url":"https://google.com","alias":"g"}],"mode":"all_or_nothing"}`,
+			respBody:   `{"status":"Error","error":"batch rolled back","code":"batch_failed","created":0,"failed":1,"results":[{"index":0,"alias":"g","error":"url already exists","code":"alias_exists"}]}`,
+			wantErr:    api.ErrBatchFailed,
+		},
+		{
+			name: "Get",
+			call: func(c *api.Client) (any, error) {
+				return c.Get(context.Background(), "g")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/g",
+			respBody:   `{"status":"OK","id":7,"alias":"g","url":"https://google.com"}`,
+			want:       api.Link{ID: 7, Alias: "g", URL: "https://google.com"},
+		},
+		{
+			name: "Get Not Found",
+			call: func(c *api.Client) (any, error) {
+				return c.Get(context.Background(), "missing")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/missing",
+			respBody:   `{"status":"Error","error":"not found","code":"not_found"}`,
+			wantErr:    api.ErrNotFound,
+		},
+		{
+			name: "List",
+			call: func(c *api.Client) (any, error) {
+				return c.List(context.Background(), api.ListOptions{Limit: 2, Offset: 4, Title: "go blog"})
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url?limit=2&offset=4&title=go+blog",
+			respBody:   `{"status":"OK","links":[{"id":5,"alias":"a","url":"https://a.com"}]}`,
+			want:       []api.Link{{ID: 5, Alias: "a", URL: "https://a.com"}},
+		},
+		{
+			name: "List By Tag",
+			call: func(c *api.Client) (any, error) {
+				return c.List(context.Background(), api.ListOptions{Tag: "launch", Folder: "Marketing"})
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url?folder=Marketing&tag=launch",
+			respBody:   `{"status":"OK","links":[{"id":5,"alias":"a","url":"https://a.com","folder":"Marketing","tags":["launch"]}]}`,
+			want:       []api.Link{{ID: 5, Alias: "a", URL: "https://a.com", Folder: "Marketing", Tags: []string{"launch"}}},
+		},
+		{
+			name: "Search",
+			call: func(c *api.Client) (any, error) {
+				return c.Search(context.Background(), api.SearchOptions{Query: "go blog", Limit: 5})
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/search?limit=5&q=go+blog",
+			respBody:   `{"status":"OK","results":[{"link":{"id":5,"alias":"blog","url":"https://go.dev/blog"},"score":1.5,"highlights":{"alias":"<mark>blog</mark>"}}]}`,
+			want: []api.SearchResult{{
+				Link:       api.Link{ID: 5, Alias: "blog", URL: "https://go.dev/blog"},
+				Score:      1.5,
+				Highlights: api.Highlights{Alias: "<mark>blog</mark>"},
+			}},
+		},
+		{
+			name: "Update Validation Error",
+			call: func(c *api.Client) (any, error) {
+				u := "bad"
+				return c.Update(context.Background(), 3, api.UpdateRequest{URL: &u})
+			},
+			wantMethod: http.MethodPatch,
+			wantURI:    "/url/3",
+			wantBody:   `{"url":"bad"}`,
+			respBody:   `{"status":"Error","error":"field 'URL' must be a valid URL","code":"validation_failed"}`,
+			wantErr:    api.ErrValidation,
+		},
+		{
+			name: "Delete",
+			call: func(c *api.Client) (any, error) {
+				return nil, c.Delete(context.Background(), 3)
+			},
+			wantMethod: http.MethodDelete,
+			wantURI:    "/url/3",
+			respBody:   `{"status":"OK"}`,
+		},
+		{
+			name: "Stats",
+			call: func(c *api.Client) (any, error) {
+				return c.Stats(context.Background(), "g")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/g/stats",
+			respBody:   `{"status":"OK","clicks":2,"daily":[{"date":"2025-01-01","clicks":2}]}`,
+			want:       api.Stats{Clicks: 2, Daily: []api.DailyClicks{{Date: "2025-01-01", Clicks: 2}}},
+		},
+		{
+			name: "Tag Stats",
+			call: func(c *api.Client) (any, error) {
+				return c.TagStats(context.Background(), "launch")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/tags/launch/stats",
+			respBody:   `{"status":"OK","tag":"launch","links":2,"clicks":3}`,
+			want:       api.TagStats{Tag: "launch", Links: 2, Stats: api.Stats{Clicks: 3}},
+		},
+		{
+			name: "Get On Domain",
+			call: func(c *api.Client) (any, error) {
+				return c.GetOn(context.Background(), "go.acme.com", "g")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/g?domain=go.acme.com",
+			respBody:   `{"status":"OK","id":8,"alias":"g","url":"https://acme.com","domain":"go.acme.com"}`,
+			want:       api.Link{ID: 8, Alias: "g", URL: "https://acme.com", Domain: "go.acme.com"},
+		},
+		{
+			name: "Stats On Domain",
+			call: func(c *api.Client) (any, error) {
+				return c.StatsOn(context.Background(), "go.acme.com", "g")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/g/stats?domain=go.acme.com",
+			respBody:   `{"status":"OK","clicks":1}`,
+			want:       api.Stats{Clicks: 1},
+		},
+		{
+			name: "Unauthorized",
+			call: func(c *api.Client) (any, error) {
+				return c.Get(context.Background(), "g")
+			},
+			wantMethod: http.MethodGet,
+			wantURI:    "/url/g",
+			status:     http.StatusUnauthorized,
+			respBody:   "Unauthorized",
+			wantErr:    api.ErrUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tc.wantMethod, r.Method)
+				assert.Equal(t, tc.wantURI, r.URL.RequestURI())
+
+				user, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "user", user)
+				assert.Equal(t, "pass", password)
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantBody, string(body))
+
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+				}
+				_, _ = w.Write([]byte(tc.respBody))
+			}))
+			defer ts.Close()
+
+			got, err := tc.call(api.NewClient(ts.URL, "user", "pass"))
+
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				var apiErr *api.Error
+				require.True(t, errors.As(err, &apiErr))
+				return
+			}
+
+			require.NoError(t, err)
+			if tc.want != nil {
+				require.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClientAPIKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, ok := r.BasicAuth()
+		assert.False(t, ok)
+		assert.Equal(t, "Bearer lsk_secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"status":"OK","id":7,"alias":"acme~g","url":"https://google.com","workspace_id":2}`))
+	}))
+	defer ts.Close()
+
+	c := api.NewClient(ts.URL, "user", "pass")
+	c.APIKey = "lsk_secret"
+
+	link, err := c.Get(context.Background(), "g")
+	require.NoError(t, err)
+	require.Equal(t, "acme~g", link.Alias)
+}
+
+func TestShortURLOn(t *testing.T) {
+	c := api.NewClient("http://localhost:8087", "user", "pass")
+	assert.Equal(t, "http://localhost:8087/g", c.ShortURLOn("", "g"))
+	assert.Equal(t, "http://go.acme.com/g", c.ShortURLOn("go.acme.com", "g"))
+}
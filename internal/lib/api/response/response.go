@@ -9,6 +9,7 @@ import (
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
 }
 
 const (
@@ -16,6 +17,34 @@ const (
 	StatusError = "Error"
 )
 
+// Machine-readable error codes, so that clients don't have to match on
+// human-readable messages.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeValidation     = "validation_failed"
+	CodeNotFound       = "not_found"
+	CodeAliasExists    = "alias_exists"
+	CodeInternal       = "internal_error"
+	CodeBatchFailed    = "batch_failed"
+	CodeRolledBack     = "rolled_back"
+	CodePasswordNeeded = "password_required"
+	CodeWrongPassword  = "wrong_password"
+	CodeRateLimited    = "rate_limited"
+	CodeLinkExhausted  = "link_exhausted"
+	CodeLinkInactive   = "link_inactive"
+	CodeUnauthorized   = "unauthorized"
+	// Workspace quotas, see storage.Workspace.
+	CodeLinkQuota  = "link_quota_exceeded"
+	CodeDailyQuota = "daily_quota_exceeded"
+	// URL policy violations, see urlpolicy.
+	CodeURLScheme  = "url_scheme_not_allowed"
+	CodeURLPrivate = "url_private_network"
+	CodeURLLoop    = "url_redirect_loop"
+	CodeURLBlocked = "url_blocked"
+	// Destinations redirecting too many times, see redirectchain.
+	CodeURLChain = "url_redirect_chain"
+)
+
 func OK() Response {
 	return Response{
 		Status: StatusOK,
@@ -29,6 +58,14 @@ func Error(msg string) Response {
 	}
 }
 
+func ErrorWithCode(code, msg string) Response {
+	return Response{
+		Status: StatusError,
+		Error:  msg,
+		Code:   code,
+	}
+}
+
 func ValidationError(errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
@@ -39,6 +76,16 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' is required", err.Field()))
 		case "url":
 			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be a valid URL", err.Field()))
+		case "http_url":
+			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be a valid http or https URL", err.Field()))
+		case "max":
+			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be at most %s characters long", err.Field(), err.Param()))
+		case "gte":
+			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be at least %s", err.Field(), err.Param()))
+		case "oneof":
+			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be one of %s", err.Field(), err.Param()))
+		case "iso3166_1_alpha2":
+			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' must be a two-letter country code", err.Field()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field '%s' is not valid", err.Field()))
 		}
@@ -47,5 +94,6 @@ func ValidationError(errs validator.ValidationErrors) Response {
 	return Response{
 		Status: StatusError,
 		Error:  strings.Join(errMsgs, ", "),
+		Code:   CodeValidation,
 	}
 }
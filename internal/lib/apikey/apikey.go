@@ -0,0 +1,32 @@
+// Package apikey creates the keys workspaces use to call the API, and the
+// hashes they are stored and looked up by.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Prefix starts every key, so that leaked keys are easy to recognize.
+const Prefix = "lsk_"
+
+// New returns a new random key and its hash.
+func New() (key, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("apikey.New: %w", err)
+	}
+
+	key = Prefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, Hash(key), nil
+}
+
+// Hash returns the hash key is stored under. Keys are random enough for a
+// plain SHA-256 to be safe, and it lets a key be looked up by its hash.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package apikey_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/apikey"
+)
+
+func TestNew(t *testing.T) {
+	key, hash, err := apikey.New()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apikey.Prefix))
+	assert.Equal(t, apikey.Hash(key), hash)
+	assert.NotContains(t, hash, key)
+
+	other, _, err := apikey.New()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
@@ -0,0 +1,78 @@
+// Package clientip finds the address of the client behind an HTTP request,
+// believing X-Forwarded-For only where trusted proxies set it.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds client addresses. The zero value trusts no proxy.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver returns a resolver that trusts the proxies at the given IP
+// addresses or CIDR ranges.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	const op = "clientip.NewResolver"
+
+	var r Resolver
+	for _, s := range trustedProxies {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%s: invalid proxy %q: %w", op, s, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return &r, nil
+}
+
+// IP returns the address of the client that sent req. If the peer is a
+// trusted proxy, X-Forwarded-For is followed from the right until an
+// address that is not a trusted proxy; anything left of it could have
+// been made up by the client. The zero Addr is returned if no address is
+// known.
+func (r *Resolver) IP(req *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+
+	var hops []string
+	for _, h := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0 && r.isTrusted(addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+
+	return addr
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package clientip_test
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/clientip"
+)
+
+func TestResolverIP(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "89.160.20.115:5123",
+			want:       "89.160.20.115",
+		},
+		{
+			name:       "Untrusted Peer",
+			remoteAddr: "89.160.20.115:5123",
+			forwarded:  []string{"81.2.69.160"},
+			want:       "89.160.20.115",
+		},
+		{
+			name:       "Trusted Proxy",
+			remoteAddr: "10.1.2.3:5123",
+			forwarded:  []string{"81.2.69.160"},
+			want:       "81.2.69.160",
+		},
+		{
+			name:       "Spoofed Hop Ignored",
+			remoteAddr: "10.1.2.3:5123",
+			forwarded:  []string{"1.1.1.1, 81.2.69.160"},
+			want:       "81.2.69.160",
+		},
+		{
+			name:       "Chain Of Proxies",
+			remoteAddr: "192.0.2.1:5123",
+			forwarded:  []string{"81.2.69.160", "10.9.9.9"},
+			want:       "81.2.69.160",
+		},
+		{
+			name:       "Garbage Hop",
+			remoteAddr: "10.1.2.3:5123",
+			forwarded:  []string{"81.2.69.160, unknown"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "IPv6",
+			remoteAddr: "[2001:218::1]:5123",
+			want:       "2001:218::1",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, h := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", h)
+			}
+
+			assert.Equal(t, netip.MustParseAddr(tc.want), resolver.IP(req))
+		})
+	}
+}
+
+func TestNewResolverRejectsInvalidProxy(t *testing.T) {
+	_, err := clientip.NewResolver([]string{"proxy.internal"})
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+// Package geoip looks up the country of IP addresses in a local MaxMind
+// database (GeoLite2 or GeoIP2 Country or City), so that no request leaves
+// the server.
+package geoip
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"link-shortener/internal/lib/clientip"
+)
+
+// DB is an open MaxMind database. It is safe for concurrent use.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+func Open(path string) (*DB, error) {
+	const op = "geoip.Open"
+
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &DB{reader: reader}, nil
+}
+
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country addr is in, or
+// an empty string if the database does not know.
+func (db *DB) Country(addr netip.Addr) (string, error) {
+	const op = "geoip.DB.Country"
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+
+	if !addr.IsValid() {
+		return "", nil
+	}
+	if err := db.reader.Lookup(addr.AsSlice(), &record); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return record.Country.ISOCode, nil
+}
+
+// Locator finds the country of HTTP clients.
+type Locator struct {
+	db  *DB
+	ips *clientip.Resolver
+}
+
+func NewLocator(db *DB, ips *clientip.Resolver) *Locator {
+	return &Locator{db: db, ips: ips}
+}
+
+// Country returns the country code of the client that sent r, or an empty
+// string if it is unknown.
+func (l *Locator) Country(r *http.Request) (string, error) {
+	return l.db.Country(l.ips.IP(r))
+}
@@ -0,0 +1,64 @@
+package geoip_test
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/clientip"
+	"link-shortener/internal/lib/geoip"
+)
+
+const testDB = "testdata/GeoLite2-Country-Test.mmdb"
+
+func TestCountry(t *testing.T) {
+	db, err := geoip.Open(testDB)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	cases := []struct {
+		addr string
+		want string
+	}{
+		{addr: "81.2.69.160", want: "GB"},
+		{addr: "89.160.20.115", want: "SE"},
+		{addr: "216.160.83.58", want: "US"},
+		{addr: "2001:218::1", want: "JP"},
+		{addr: "127.0.0.1", want: ""},
+	}
+
+	for _, tc := range cases {
+		got, err := db.Country(netip.MustParseAddr(tc.addr))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc.addr)
+	}
+
+	got, err := db.Country(netip.Addr{})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestLocator(t *testing.T) {
+	db, err := geoip.Open(testDB)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ips, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:5123"
+	req.Header.Set("X-Forwarded-For", "89.160.20.115")
+
+	country, err := geoip.NewLocator(db, ips).Country(req)
+	require.NoError(t, err)
+	assert.Equal(t, "SE", country)
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	_, err := geoip.Open("testdata/missing.mmdb")
+	require.Error(t, err)
+}
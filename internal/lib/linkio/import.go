@@ -0,0 +1,123 @@
+package linkio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"link-shortener/internal/lib/api/response"
+	"link-shortener/internal/storage"
+)
+
+// Policy decides what happens when an imported alias already exists.
+type Policy string
+
+const (
+	// PolicySkip keeps the existing link and reports the row as skipped.
+	PolicySkip Policy = "skip"
+	// PolicyOverwrite replaces the existing link with the imported one.
+	PolicyOverwrite Policy = "overwrite"
+	// PolicyFail aborts the whole import.
+	PolicyFail Policy = "fail"
+)
+
+var (
+	ErrUnknownPolicy = errors.New("unknown policy")
+	// ErrAborted is returned when PolicyFail stopped the import. The
+	// caller is expected to roll back whatever was stored so far.
+	ErrAborted = errors.New("import aborted")
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicySkip, PolicyOverwrite, PolicyFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownPolicy, s)
+	}
+}
+
+// Store receives the imported links, usually inside a transaction.
+type Store interface {
+	SaveLink(link storage.Link) (int64, error)
+	OverwriteLink(link storage.Link) (int64, error)
+}
+
+// Validator checks an imported link the same way the API would and fills
+// in defaults, e.g. a generated alias.
+type Validator func(link storage.Link) (storage.Link, error)
+
+type Report struct {
+	Created     int        `json:"created"`
+	Overwritten int        `json:"overwritten"`
+	Skipped     int        `json:"skipped"`
+	Failed      int        `json:"failed"`
+	Errors      []RowError `json:"errors,omitempty"`
+}
+
+// RowError describes why a row was skipped or not imported.
+type RowError struct {
+	Line  int    `json:"line"`
+	Alias string `json:"alias,omitempty"`
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Import stores every link read from r. Invalid rows are reported and
+// skipped; alias collisions are handled according to policy. Imported ids
+// are ignored, links always get a new id (or keep the one of the link they
+// overwrite).
+func Import(r Reader, store Store, policy Policy, validate Validator) (Report, error) {
+	var report Report
+
+	fail := func(alias, code string, err error) {
+		report.Failed++
+		report.Errors = append(report.Errors, RowError{Line: r.Line(), Alias: alias, Error: err.Error(), Code: code})
+	}
+
+	for {
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if errors.Is(err, ErrInvalidRow) {
+			fail("", response.CodeInvalidRequest, err)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		link, err = validate(link)
+		if err != nil {
+			fail(link.Alias, response.CodeValidation, err)
+			continue
+		}
+
+		_, err = store.SaveLink(link)
+		if errors.Is(err, storage.ErrURLExist) {
+			switch policy {
+			case PolicySkip:
+				report.Skipped++
+				report.Errors = append(report.Errors, RowError{
+					Line: r.Line(), Alias: link.Alias, Error: "alias already exists, skipped", Code: response.CodeAliasExists,
+				})
+				continue
+			case PolicyOverwrite:
+				if _, err := store.OverwriteLink(link); err != nil {
+					return report, fmt.Errorf("line %d: %w", r.Line(), err)
+				}
+				report.Overwritten++
+				continue
+			default:
+				fail(link.Alias, response.CodeAliasExists, errors.New("alias already exists"))
+				return report, fmt.Errorf("%w: line %d: alias %q already exists", ErrAborted, r.Line(), link.Alias)
+			}
+		}
+		if err != nil {
+			return report, fmt.Errorf("line %d: %w", r.Line(), err)
+		}
+
+		report.Created++
+	}
+}
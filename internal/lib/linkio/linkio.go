@@ -0,0 +1,86 @@
+// Package linkio reads and writes links in the CSV and NDJSON formats used
+// for moving links between environments.
+//
+// Both formats carry every field storage.Link exposes in JSON, so new link
+// metadata is exported and imported without changes here. Fields tagged
+// `linkio:"-"` are derived from others and left out. In CSV, strings (and
+// values JSON encodes as strings, such as times) are written as is and all
+// other values as JSON.
+package linkio
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"link-shortener/internal/storage"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrInvalidRow is wrapped by read errors that only affect one row;
+	// reading can continue after them.
+	ErrInvalidRow = errors.New("invalid row")
+)
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// FormatFromPath guesses the format from a file name, defaulting to NDJSON.
+func FormatFromPath(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatNDJSON
+}
+
+func checkFormat(format string) error {
+	if format != FormatCSV && format != FormatNDJSON {
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return nil
+}
+
+type field struct {
+	name     string
+	isString bool
+}
+
+// fields are the JSON fields of storage.Link, in declaration order.
+var fields = linkFields()
+
+func linkFields() []field {
+	t := reflect.TypeOf(storage.Link{})
+
+	var res []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "" || name == "-" || f.Tag.Get("linkio") == "-" {
+			continue
+		}
+		res = append(res, field{name: name, isString: f.Type.Kind() == reflect.String})
+	}
+
+	return res
+}
+
+func lookupField(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
@@ -0,0 +1,229 @@
+package linkio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/linkio"
+	"link-shortener/internal/storage"
+)
+
+func TestRoundTrip(t *testing.T) {
+	from := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	links := []storage.Link{
+		{ID: 1, Alias: "first", URL: "https://google.com"},
+		{ID: 2, Alias: "second", URL: "https://go.dev/doc?a=1,b=2"},
+		{
+			ID: 3, Alias: "app", URL: "https://example.com",
+			ActiveFrom: &from, FallbackURL: "https://example.com/soon",
+			Targets: []storage.Target{{Platform: "ios", URL: "https://apps.apple.com/app/id1"}},
+		},
+	}
+
+	for _, format := range []string{linkio.FormatCSV, linkio.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := linkio.NewWriter(&buf, format)
+			require.NoError(t, err)
+			for _, link := range links {
+				require.NoError(t, w.Write(link))
+			}
+			require.NoError(t, w.Flush())
+
+			r, err := linkio.NewReader(&buf, format)
+			require.NoError(t, err)
+
+			var got []storage.Link
+			for {
+				link, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, link)
+			}
+
+			assert.Equal(t, links, got)
+		})
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := linkio.NewWriter(&buf, linkio.FormatCSV)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	assert.True(t, strings.HasPrefix(buf.String(), "id,alias,url"))
+}
+
+func TestCSVReader(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		links   []storage.Link
+		rowErrs int
+		err     bool
+	}{
+		{
+			name:  "Reordered And Unknown Columns",
+			input: "url,comment,alias\nhttps://google.com,hello,g\n",
+			links: []storage.Link{{Alias: "g", URL: "https://google.com"}},
+		},
+		{
+			name:  "Without Alias",
+			input: "url\nhttps://google.com\n",
+			links: []storage.Link{{URL: "https://google.com"}},
+		},
+		{
+			name:    "Wrong Field Count",
+			input:   "alias,url\ng\nd,https://go.dev\n",
+			links:   []storage.Link{{Alias: "d", URL: "https://go.dev"}},
+			rowErrs: 1,
+		},
+		{
+			name:    "Invalid ID",
+			input:   "id,alias,url\nabc,g,https://google.com\n",
+			rowErrs: 1,
+		},
+		{
+			name:  "Missing URL Column",
+			input: "alias\ng\n",
+			err:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := linkio.NewReader(strings.NewReader(tc.input), linkio.FormatCSV)
+			require.NoError(t, err)
+
+			var (
+				links   []storage.Link
+				rowErrs int
+			)
+			for {
+				link, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if errors.Is(err, linkio.ErrInvalidRow) {
+					rowErrs++
+					continue
+				}
+				if tc.err {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				links = append(links, link)
+			}
+
+			require.False(t, tc.err, "expected an error")
+			assert.Equal(t, tc.links, links)
+			assert.Equal(t, tc.rowErrs, rowErrs)
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := linkio.NewWriter(io.Discard, "xml")
+	require.ErrorIs(t, err, linkio.ErrUnknownFormat)
+
+	_, err = linkio.NewReader(strings.NewReader(""), "xml")
+	require.ErrorIs(t, err, linkio.ErrUnknownFormat)
+}
+
+// memStore is an in-memory linkio.Store keyed by alias.
+type memStore map[string]string
+
+func (m memStore) SaveLink(link storage.Link) (int64, error) {
+	if _, ok := m[link.Alias]; ok {
+		return 0, storage.ErrURLExist
+	}
+	m[link.Alias] = link.URL
+	return int64(len(m)), nil
+}
+
+func (m memStore) OverwriteLink(link storage.Link) (int64, error) {
+	m[link.Alias] = link.URL
+	return 1, nil
+}
+
+func TestImport(t *testing.T) {
+	const input = `{"alias": "taken", "url": "https://new.com"}
+{"alias": "fresh", "url": "https://fresh.com"}
+{"alias": "bad", "url": ""}
+not json
+`
+
+	validate := func(link storage.Link) (storage.Link, error) {
+		if link.URL == "" {
+			return link, errors.New("field 'URL' is required")
+		}
+		return link, nil
+	}
+
+	cases := []struct {
+		name    string
+		policy  linkio.Policy
+		report  linkio.Report
+		store   memStore
+		aborted bool
+	}{
+		{
+			name:   "Skip",
+			policy: linkio.PolicySkip,
+			report: linkio.Report{Created: 1, Skipped: 1, Failed: 2},
+			store:  memStore{"taken": "https://old.com", "fresh": "https://fresh.com"},
+		},
+		{
+			name:   "Overwrite",
+			policy: linkio.PolicyOverwrite,
+			report: linkio.Report{Created: 1, Overwritten: 1, Failed: 2},
+			store:  memStore{"taken": "https://new.com", "fresh": "https://fresh.com"},
+		},
+		{
+			name:    "Fail",
+			policy:  linkio.PolicyFail,
+			report:  linkio.Report{Failed: 1},
+			store:   memStore{"taken": "https://old.com"},
+			aborted: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := memStore{"taken": "https://old.com"}
+
+			r, err := linkio.NewReader(strings.NewReader(input), linkio.FormatNDJSON)
+			require.NoError(t, err)
+
+			report, err := linkio.Import(r, store, tc.policy, validate)
+			if tc.aborted {
+				require.ErrorIs(t, err, linkio.ErrAborted)
+			} else {
+				require.NoError(t, err)
+			}
+
+			errs := report.Errors
+			report.Errors = nil
+			assert.Equal(t, tc.report, report)
+			assert.Equal(t, tc.store, store)
+
+			for _, e := range errs {
+				assert.NotZero(t, e.Line)
+				assert.NotEmpty(t, e.Code)
+			}
+		})
+	}
+}
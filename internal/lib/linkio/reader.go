@@ -0,0 +1,160 @@
+package linkio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"link-shortener/internal/storage"
+)
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+// Reader reads links in one of the supported formats. Read returns io.EOF
+// after the last link; errors wrapping ErrInvalidRow only affect the
+// current row.
+type Reader interface {
+	Read() (storage.Link, error)
+	// Line is the line number of the row last returned by Read.
+	Line() int
+}
+
+func NewReader(r io.Reader, format string) (Reader, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+
+	if format == FormatCSV {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		return &csvReader{r: cr}, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &ndjsonReader{scanner: scanner}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Read() (storage.Link, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var link storage.Link
+		if err := json.Unmarshal(data, &link); err != nil {
+			return storage.Link{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+		}
+		return link, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return storage.Link{}, err
+	}
+	return storage.Link{}, io.EOF
+}
+
+func (r *ndjsonReader) Line() int {
+	return r.line
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header []field
+	line   int
+}
+
+func (r *csvReader) Read() (storage.Link, error) {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return storage.Link{}, err
+		}
+	}
+
+	record, err := r.r.Read()
+	r.line, _ = r.r.FieldPos(0)
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			r.line = parseErr.Line
+			return storage.Link{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+		}
+		return storage.Link{}, err
+	}
+
+	if len(record) != len(r.header) {
+		return storage.Link{}, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidRow, len(r.header), len(record))
+	}
+
+	values := make(map[string]json.RawMessage, len(record))
+	for i, v := range record {
+		f := r.header[i]
+		if v == "" || f.name == "" {
+			continue
+		}
+
+		// Anything that is not JSON is taken as a string, which also covers
+		// values that are encoded as strings (e.g. times).
+		if f.isString || !json.Valid([]byte(v)) {
+			values[f.name], _ = json.Marshal(v)
+			continue
+		}
+		values[f.name] = json.RawMessage(v)
+	}
+
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+	}
+
+	var link storage.Link
+	if err := json.Unmarshal(raw, &link); err != nil {
+		return storage.Link{}, fmt.Errorf("%w: %v", ErrInvalidRow, err)
+	}
+
+	return link, nil
+}
+
+func (r *csvReader) Line() int {
+	return r.line
+}
+
+// readHeader maps the header columns to link fields. Unknown columns are
+// ignored; the url column is required.
+func (r *csvReader) readHeader() error {
+	header, err := r.r.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+
+	r.header = make([]field, len(header))
+	hasURL := false
+	for i, name := range header {
+		if f, ok := lookupField(name); ok {
+			r.header[i] = f
+			hasURL = hasURL || name == "url"
+		}
+	}
+
+	if !hasURL {
+		return errors.New("read header: missing url column")
+	}
+
+	return nil
+}
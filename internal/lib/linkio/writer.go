@@ -0,0 +1,102 @@
+package linkio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"link-shortener/internal/storage"
+)
+
+// Writer writes links in one of the supported formats.
+type Writer interface {
+	Write(link storage.Link) error
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+
+	if format == FormatCSV {
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	}
+	return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(link storage.Link) error {
+	return w.enc.Encode(link)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) Write(link storage.Link) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("encode link %q: %w", link.Alias, err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return fmt.Errorf("encode link %q: %w", link.Alias, err)
+	}
+
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		v, ok := values[f.name]
+		if !ok || string(v) == "null" {
+			continue
+		}
+		// Strings, including encoded values such as times, are written
+		// without JSON quotes.
+		if f.isString || v[0] == '"' {
+			if err := json.Unmarshal(v, &record[i]); err != nil {
+				return fmt.Errorf("encode link %q: %w", link.Alias, err)
+			}
+			continue
+		}
+		record[i] = string(v)
+	}
+
+	return w.w.Write(record)
+}
+
+// Flush also writes the header, so an empty export still has one.
+func (w *csvWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	return w.w.Write(header)
+}
@@ -0,0 +1,314 @@
+// Package opengraph fetches the title, description and preview image of a
+// web page from its OpenGraph tags, or from the plain HTML ones if it has
+// none, honouring what the page's robots directives allow.
+package opengraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"link-shortener/internal/lib/urlpolicy"
+)
+
+var (
+	ErrStatus  = errors.New("unexpected status")
+	ErrNotHTML = errors.New("not an html page")
+)
+
+const (
+	defaultTimeout  = 5 * time.Second
+	defaultMaxBytes = 512 << 10
+	userAgent       = "link-shortener (page preview)"
+
+	// Longer values are cut, as no preview shows more.
+	maxTitle       = 300
+	maxDescription = 1000
+	maxImageURL    = 2048
+)
+
+// Meta describes a page. Fields the page does not have, or does not want
+// shown, are empty.
+type Meta struct {
+	Title       string
+	Description string
+	// Image is an absolute http or https URL.
+	Image string
+}
+
+// Config configures a Fetcher.
+type Config struct {
+	// Timeout bounds a fetch, redirects included. Zero means 5 seconds.
+	Timeout time.Duration
+	// MaxBytes is how much of a page is read. Zero means 512 KiB; the
+	// tags are in the head, so the rest does not matter.
+	MaxBytes int64
+	// AllowPrivate lets the fetcher connect to private addresses.
+	AllowPrivate bool
+}
+
+// Fetcher fetches page metadata. It is safe for concurrent use.
+type Fetcher struct {
+	client   *http.Client
+	timeout  time.Duration
+	maxBytes int64
+}
+
+// New returns a Fetcher for cfg.
+func New(cfg Config) *Fetcher {
+	f := &Fetcher{timeout: cfg.Timeout, maxBytes: cfg.MaxBytes}
+	if f.timeout <= 0 {
+		f.timeout = defaultTimeout
+	}
+	if f.maxBytes <= 0 {
+		f.maxBytes = defaultMaxBytes
+	}
+
+	dialer := &net.Dialer{Timeout: f.timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = urlpolicy.RefusePrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	f.client = &http.Client{Transport: transport}
+
+	return f
+}
+
+// Fetch requests the page at rawURL and returns its metadata. Pages that
+// are not HTML fail with ErrNotHTML, error responses with ErrStatus.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Meta, error) {
+	const op = "opengraph.Fetcher.Fetch"
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Meta{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Meta{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Meta{}, fmt.Errorf("%s: %w: %d", op, ErrStatus, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Meta{}, fmt.Errorf("%s: %w: %q", op, ErrNotHTML, contentType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return Meta{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Redirects may have moved the page; relative image URLs are relative
+	// to where it ended up.
+	meta, err := Parse(body, resp.Request.URL)
+	if err != nil {
+		return Meta{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return meta.allowed(robots(resp.Header.Values("X-Robots-Tag"))), nil
+}
+
+// Parse reads the metadata of the UTF-8 HTML page in r, which was served
+// from base. OpenGraph tags take precedence over Twitter card tags, and
+// those over <title> and <meta name="description">. Robots meta tags are
+// applied.
+func Parse(r io.Reader, base *url.URL) (Meta, error) {
+	var (
+		tags    = make(map[string]string)
+		title   string
+		inTitle bool
+		rules   directives
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) || errors.Is(z.Err(), io.ErrUnexpectedEOF) {
+				// A page cut at the size limit still has its head.
+				break loop
+			}
+			return Meta{}, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = title == ""
+			case "meta":
+				key, content := metaTag(tok)
+				if key == "robots" {
+					rules = rules.merge(parseDirectives(content))
+				} else if _, seen := tags[key]; key != "" && !seen {
+					tags[key] = content
+				}
+			case "body":
+				// Metadata belongs in the head.
+				break loop
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			if tok := z.Token(); tok.Data == "title" {
+				inTitle = false
+			} else if tok.Data == "head" {
+				break loop
+			}
+		}
+	}
+
+	meta := Meta{
+		Title:       clean(first(tags["og:title"], tags["twitter:title"], title), maxTitle),
+		Description: clean(first(tags["og:description"], tags["twitter:description"], tags["description"]), maxDescription),
+		Image:       imageURL(first(tags["og:image"], tags["og:image:url"], tags["og:image:secure_url"], tags["twitter:image"]), base),
+	}
+	return meta.allowed(rules), nil
+}
+
+// metaTag returns the lowercased property or name of a <meta> tag and its
+// content.
+func metaTag(tok html.Token) (key, content string) {
+	var name, property string
+	for _, a := range tok.Attr {
+		switch strings.ToLower(a.Key) {
+		case "property":
+			property = a.Val
+		case "name":
+			name = a.Val
+		case "content":
+			content = a.Val
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(first(property, name))), content
+}
+
+// directives are the robots directives that limit what is kept of a page.
+type directives struct {
+	noIndex, noSnippet, noImage bool
+}
+
+// parseDirectives reads a robots meta tag or X-Robots-Tag value. Directives
+// for particular crawlers ("googlebot: noindex") do not apply.
+func parseDirectives(value string) directives {
+	var d directives
+	for _, part := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "noindex", "none":
+			d.noIndex = true
+		case "nosnippet", "max-snippet:0":
+			d.noSnippet = true
+		case "noimageindex":
+			d.noImage = true
+		}
+	}
+	return d
+}
+
+// robots reads X-Robots-Tag headers. Unlike meta tags, they name the
+// crawler they are for in front of the directives, if any.
+func robots(headers []string) directives {
+	var d directives
+	for _, h := range headers {
+		if agent, _, found := strings.Cut(h, ":"); found && !strings.Contains(agent, ",") {
+			switch strings.ToLower(strings.TrimSpace(agent)) {
+			case "max-snippet", "max-image-preview", "max-video-preview", "unavailable_after":
+			default:
+				continue
+			}
+		}
+		d = d.merge(parseDirectives(h))
+	}
+	return d
+}
+
+func (d directives) merge(other directives) directives {
+	return directives{
+		noIndex:   d.noIndex || other.noIndex,
+		noSnippet: d.noSnippet || other.noSnippet,
+		noImage:   d.noImage || other.noImage,
+	}
+}
+
+// allowed returns what d lets be kept of m. A page that is not to be
+// indexed keeps nothing.
+func (m Meta) allowed(d directives) Meta {
+	if d.noIndex {
+		return Meta{}
+	}
+	if d.noSnippet {
+		m.Description = ""
+	}
+	if d.noImage {
+		m.Image = ""
+	}
+	return m
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses the whitespace of s and cuts it to limit runes.
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
+
+// imageURL resolves raw against base, or returns "" if the result is not
+// an http or https URL of a sensible length.
+func imageURL(raw string, base *url.URL) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	s := u.String()
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(s) > maxImageURL {
+		return ""
+	}
+	return s
+}
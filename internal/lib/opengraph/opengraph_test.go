@@ -0,0 +1,202 @@
+package opengraph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/opengraph"
+	"link-shortener/internal/lib/urlpolicy"
+)
+
+func TestParse(t *testing.T) {
+	base, err := url.Parse("https://news.example.com/2025/rivers")
+	require.NoError(t, err)
+
+	cases := []struct {
+		fixture string
+		want    opengraph.Meta
+	}{
+		{
+			fixture: "article.html",
+			want: opengraph.Meta{
+				Title:       "Rivers are rising & falling",
+				Description: "What the OpenGraph description says.",
+				Image:       "https://news.example.com/img/rivers.png",
+			},
+		},
+		{
+			fixture: "plain.html",
+			want: opengraph.Meta{
+				Title:       "Only a title",
+				Description: "And a description.",
+				Image:       "https://cdn.example.com/card.jpg",
+			},
+		},
+		{fixture: "noindex.html", want: opengraph.Meta{}},
+		{fixture: "nosnippet.html", want: opengraph.Meta{Title: "Snippet free"}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(filepath.Join("testdata", tc.fixture))
+			require.NoError(t, err)
+			defer func() { _ = f.Close() }()
+
+			meta, err := opengraph.Parse(f, base)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, meta)
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	page := "<title>" + strings.Repeat("long ", 100) + "</title>" +
+		`<meta property="og:image" content="https://example.com/` + strings.Repeat("x", 3000) + `">`
+
+	meta, err := opengraph.Parse(strings.NewReader(page), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 300, len([]rune(meta.Title)))
+	assert.True(t, strings.HasSuffix(meta.Title, "…"))
+	assert.Empty(t, meta.Image)
+}
+
+// newServer serves the fixtures in testdata, as text/html unless the query
+// has another type, with the X-Robots-Tag of the query if it has one.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	files := http.FileServer(http.Dir("testdata"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/news/article.html", http.StatusFound)
+			return
+		case "/news/article.html":
+			r.URL.Path = "/article.html"
+		}
+
+		contentType := r.URL.Query().Get("type")
+		if contentType == "" {
+			contentType = "text/html"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if tag := r.URL.Query().Get("robots"); tag != "" {
+			w.Header().Set("X-Robots-Tag", tag)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestFetch(t *testing.T) {
+	srv := newServer(t)
+	f := opengraph.New(opengraph.Config{AllowPrivate: true})
+
+	cases := []struct {
+		name    string
+		path    string
+		want    opengraph.Meta
+		wantErr error
+	}{
+		{
+			name: "Redirected",
+			path: "/moved",
+			want: opengraph.Meta{
+				Title:       "Rivers are rising & falling",
+				Description: "What the OpenGraph description says.",
+				Image:       srv.URL + "/img/rivers.png",
+			},
+		},
+		{
+			name: "Charset",
+			path: "/latin1.html?type=" + url.QueryEscape("text/html; charset=windows-1252"),
+			want: opengraph.Meta{Title: "Café crème"},
+		},
+		{
+			name: "Charset In Page",
+			path: "/latin1.html",
+			want: opengraph.Meta{Title: "Café crème"},
+		},
+		{
+			name: "Robots Header",
+			path: "/plain.html?robots=noimageindex",
+			want: opengraph.Meta{Title: "Only a title", Description: "And a description."},
+		},
+		{
+			name: "Robots Header Noindex",
+			path: "/plain.html?robots=none",
+			want: opengraph.Meta{},
+		},
+		{
+			name: "Robots Header For Another Crawler",
+			path: "/plain.html?robots=" + url.QueryEscape("otherbot: noindex, nosnippet"),
+			want: opengraph.Meta{
+				Title:       "Only a title",
+				Description: "And a description.",
+				Image:       "https://cdn.example.com/card.jpg",
+			},
+		},
+		{
+			name:    "Not HTML",
+			path:    "/plain.html?type=application/pdf",
+			wantErr: opengraph.ErrNotHTML,
+		},
+		{
+			name:    "Not Found",
+			path:    "/missing.html",
+			wantErr: opengraph.ErrStatus,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			meta, err := f.Fetch(context.Background(), srv.URL+tc.path)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, meta)
+		})
+	}
+}
+
+func TestFetchMaxBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Big page</title>"))
+		_, _ = w.Write([]byte(`<meta name="description" content="` + strings.Repeat("x", 4096) + `">`))
+	}))
+	t.Cleanup(srv.Close)
+
+	meta, err := opengraph.New(opengraph.Config{AllowPrivate: true, MaxBytes: 1024}).
+		Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, opengraph.Meta{Title: "Big page"}, meta, "the cut tag is dropped")
+}
+
+func TestFetchRefusesPrivate(t *testing.T) {
+	srv := newServer(t)
+
+	_, err := opengraph.New(opengraph.Config{}).Fetch(context.Background(), srv.URL+"/plain.html")
+	require.ErrorIs(t, err, urlpolicy.ErrPrivate)
+}
@@ -0,0 +1,137 @@
+// Package qrcode draws QR codes as PNG or SVG images.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+var (
+	ErrInvalidLevel = errors.New("error correction level must be L, M, Q or H")
+	ErrInvalidColor = errors.New("color must be hex RGB, RRGGBB or RRGGBBAA")
+)
+
+// Options describe how a code is drawn.
+type Options struct {
+	// Size is the width and height of the image in pixels. Modules are
+	// scaled by a whole number, so the image may come out smaller, but
+	// never with less than one pixel per module.
+	Size int
+	// Margin is the quiet zone around the code, in modules.
+	Margin int
+	// Level is the error correction level: L, M, Q or H, from least to
+	// most tolerant of damage. Empty means M.
+	Level      string
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// PNG draws text as a QR code in PNG format.
+func PNG(text string, opts Options) ([]byte, error) {
+	const op = "qrcode.PNG"
+
+	code, err := encode(text, opts.Level)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	modules := code.Size + 2*opts.Margin
+	scale := max(opts.Size/modules, 1)
+
+	img := image.NewPaletted(image.Rect(0, 0, modules*scale, modules*scale),
+		color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+			px, py := (x+opts.Margin)*scale, (y+opts.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(px+dx, py+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG draws text as a QR code in SVG format. The drawing is measured in
+// modules and scales freely; Size only sets its initial width and height.
+func SVG(text string, opts Options) ([]byte, error) {
+	const op = "qrcode.SVG"
+
+	code, err := encode(text, opts.Level)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	modules := code.Size + 2*opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%"%s/>`, fill(opts.Background))
+	fmt.Fprintf(&buf, `<path%s d="`, fill(opts.Foreground))
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// ParseColor parses a color in hex notation, with or without a leading
+// "#": RGB, RRGGBB or RRGGBBAA.
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+func encode(text, level string) (*qr.Code, error) {
+	l, ok := map[string]qr.Level{"": qr.M, "L": qr.L, "M": qr.M, "Q": qr.Q, "H": qr.H}[strings.ToUpper(level)]
+	if !ok {
+		return nil, ErrInvalidLevel
+	}
+	return qr.Encode(text, l)
+}
+
+// fill returns the SVG fill attributes for c.
+func fill(c color.NRGBA) string {
+	attr := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		attr += fmt.Sprintf(` fill-opacity="%.3g"`, float64(c.A)/0xff)
+	}
+	return attr
+}
@@ -0,0 +1,100 @@
+package qrcode_test
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/qrcode"
+)
+
+var (
+	black = color.NRGBA{A: 0xff}
+	white = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+func TestPNG(t *testing.T) {
+	data, err := qrcode.PNG("https://sho.rt/spring", qrcode.Options{
+		Size: 256, Margin: 4, Foreground: black, Background: white,
+	})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// 25 modules plus the margin fit 7 pixels each into 256.
+	bounds := img.Bounds()
+	assert.Equal(t, 33*7, bounds.Dx())
+	assert.Equal(t, bounds.Dx(), bounds.Dy())
+
+	assert.Equal(t, white, color.NRGBAModel.Convert(img.At(0, 0)), "margin")
+	assert.Equal(t, black, color.NRGBAModel.Convert(img.At(4*7, 4*7)), "finder pattern")
+}
+
+func TestPNGTooSmall(t *testing.T) {
+	data, err := qrcode.PNG("https://sho.rt/spring", qrcode.Options{Size: 10, Foreground: black, Background: white})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 25, img.Bounds().Dx())
+}
+
+func TestSVG(t *testing.T) {
+	data, err := qrcode.SVG("https://sho.rt/spring", qrcode.Options{
+		Size:       300,
+		Margin:     2,
+		Level:      "h",
+		Foreground: color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0x80},
+		Background: white,
+	})
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 33 33"`), svg)
+	assert.Contains(t, svg, `<rect width="100%" height="100%" fill="#ffffff"/>`)
+	assert.Contains(t, svg, `<path fill="#123456" fill-opacity="0.502" d="M2 2h1v1h-1z`)
+}
+
+func TestInvalidLevel(t *testing.T) {
+	_, err := qrcode.PNG("x", qrcode.Options{Level: "X"})
+	require.ErrorIs(t, err, qrcode.ErrInvalidLevel)
+}
+
+func TestParseColor(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{in: "000", want: black},
+		{in: "#fff", want: white},
+		{in: "1a2B3c", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{in: "#1a2b3c80", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0x80}},
+		{in: "", wantErr: true},
+		{in: "red", wantErr: true},
+		{in: "12345", wantErr: true},
+		{in: "+12345", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := qrcode.ParseColor(tc.in)
+			if tc.wantErr {
+				require.ErrorIs(t, err, qrcode.ErrInvalidColor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
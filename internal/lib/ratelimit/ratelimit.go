@@ -0,0 +1,81 @@
+// Package ratelimit counts events per key in fixed time windows.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepSize is the number of tracked keys above which expired entries are
+// dropped, so that one-off keys do not accumulate forever.
+const sweepSize = 1024
+
+// Limiter allows at most max hits per key within each window. It is safe
+// for concurrent use.
+type Limiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	hits  int
+	reset time.Time
+}
+
+func New(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:     max,
+		window:  window,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Allow reports whether key is below the limit. If it is not, it also
+// returns how long until the window ends.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.reset) {
+		return true, 0
+	}
+	if e.hits < l.max {
+		return true, 0
+	}
+
+	return false, e.reset.Sub(now)
+}
+
+// Hit records an event for key.
+func (l *Limiter) Hit(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	e, ok := l.entries[key]
+	if !ok || !now.Before(e.reset) {
+		if len(l.entries) >= sweepSize {
+			l.sweep(now)
+		}
+		e = &entry{reset: now.Add(l.window)}
+		l.entries[key] = e
+	}
+	e.hits++
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if !now.Before(e.reset) {
+			delete(l.entries, key)
+		}
+	}
+}
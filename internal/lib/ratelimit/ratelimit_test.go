@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	allowed, _ := l.Allow("a")
+	require.True(t, allowed)
+
+	l.Hit("a")
+	l.Hit("a")
+
+	allowed, retry := l.Allow("a")
+	require.False(t, allowed)
+	require.Equal(t, time.Minute, retry)
+
+	allowed, _ = l.Allow("b")
+	require.True(t, allowed, "keys are limited independently")
+
+	now = now.Add(time.Minute)
+	allowed, _ = l.Allow("a")
+	require.True(t, allowed, "the window has passed")
+}
@@ -0,0 +1,241 @@
+// Package redirectchain follows the redirects of a URL one hop at a time,
+// so that links to other short links (or to themselves) can be caught
+// before they are saved.
+package redirectchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"link-shortener/internal/lib/urlpolicy"
+)
+
+var (
+	ErrLoop        = errors.New("redirect loop")
+	ErrTooManyHops = errors.New("too many redirects")
+	// ErrRejected is returned, along with the reason, when a chain passes
+	// through a URL that Config.Check refuses.
+	ErrRejected = errors.New("redirects to a refused url")
+)
+
+const (
+	defaultMaxHops = 5
+	defaultTimeout = 5 * time.Second
+	userAgent      = "link-shortener (redirect check)"
+)
+
+// Config configures a Resolver.
+type Config struct {
+	// MaxHops is how many redirects a chain may have. Zero means 5.
+	MaxHops int
+	// Timeout bounds the whole resolution. Zero means 5 seconds.
+	Timeout time.Duration
+	// Flatten makes Destination return the end of the chain rather than
+	// its start.
+	Flatten bool
+	// AllowPrivate lets the resolver connect to hosts with private
+	// addresses. Without it, such hosts end the chain with
+	// urlpolicy.ErrPrivate even if their names look public.
+	AllowPrivate bool
+	// Check, if set, vets every URL the chain redirects to.
+	Check func(rawURL string) error
+}
+
+// Resolver follows redirect chains. It is safe for concurrent use.
+type Resolver struct {
+	client  *http.Client
+	maxHops int
+	timeout time.Duration
+	flatten bool
+	check   func(rawURL string) error
+}
+
+// Chain is where a URL leads.
+type Chain struct {
+	// URLs are the start of the chain and every URL it redirected to.
+	URLs []string
+}
+
+// Hops returns the number of redirects in c.
+func (c Chain) Hops() int {
+	return max(len(c.URLs)-1, 0)
+}
+
+// Final returns the last URL of c.
+func (c Chain) Final() string {
+	if len(c.URLs) == 0 {
+		return ""
+	}
+	return c.URLs[len(c.URLs)-1]
+}
+
+// New returns a Resolver for cfg.
+func New(cfg Config) *Resolver {
+	r := &Resolver{
+		maxHops: cfg.MaxHops,
+		timeout: cfg.Timeout,
+		flatten: cfg.Flatten,
+		check:   cfg.Check,
+	}
+	if r.maxHops <= 0 {
+		r.maxHops = defaultMaxHops
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: r.timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = urlpolicy.RefusePrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	r.client = &http.Client{
+		Transport: transport,
+		// Every hop is looked at before it is followed.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return r
+}
+
+// Resolve follows rawURL until it stops redirecting. The chain so far is
+// returned with any error.
+func (r *Resolver) Resolve(ctx context.Context, rawURL string) (Chain, error) {
+	const op = "redirectchain.Resolver.Resolve"
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	chain := Chain{URLs: []string{rawURL}}
+	seen := map[string]bool{key(rawURL): true}
+
+	for current := rawURL; ; {
+		next, err := r.hop(ctx, current)
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && errors.Is(err, urlpolicy.ErrPrivate) {
+			// Said better by urlpolicy.RefusePrivate than by the layers around it.
+			return chain, opErr.Err
+		}
+		if err != nil {
+			return chain, fmt.Errorf("%s: %w", op, err)
+		}
+		if next == "" {
+			return chain, nil
+		}
+
+		if chain.Hops() == r.maxHops {
+			return chain, fmt.Errorf("%w (more than %d)", ErrTooManyHops, r.maxHops)
+		}
+		if seen[key(next)] {
+			return chain, fmt.Errorf("%w back to %s", ErrLoop, next)
+		}
+		if r.check != nil {
+			if err := r.check(next); err != nil {
+				return chain, fmt.Errorf("%w %s: %w", ErrRejected, next, err)
+			}
+		}
+
+		chain.URLs = append(chain.URLs, next)
+		seen[key(next)] = true
+		current = next
+	}
+}
+
+// Destination returns the URL to save for rawURL: rawURL itself, or with
+// Config.Flatten the end of its chain. Loops, long chains and refused or
+// private URLs are errors; a chain that cannot be followed to its end (say
+// the site is down) is not, and rawURL is kept.
+func (r *Resolver) Destination(ctx context.Context, rawURL string) (string, error) {
+	chain, err := r.Resolve(ctx, rawURL)
+	switch {
+	case errors.Is(err, ErrLoop), errors.Is(err, ErrTooManyHops),
+		errors.Is(err, ErrRejected), errors.Is(err, urlpolicy.ErrPrivate):
+		return "", err
+	case err != nil:
+		return rawURL, nil
+	}
+
+	if !r.flatten || chain.Hops() == 0 {
+		return rawURL, nil
+	}
+
+	final, err := url.Parse(chain.Final())
+	if err != nil {
+		return rawURL, nil
+	}
+	// Browsers carry the fragment along redirects that have none.
+	if final.Fragment == "" {
+		if start, err := url.Parse(rawURL); err == nil {
+			final.Fragment, final.RawFragment = start.Fragment, start.RawFragment
+		}
+	}
+	return final.String(), nil
+}
+
+// hop requests rawURL and returns where it redirects to, or an empty
+// string if it does not. HEAD is tried first; GET only for servers that
+// refuse it.
+func (r *Resolver) hop(ctx context.Context, rawURL string) (string, error) {
+	resp, err := r.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = r.do(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return "", nil
+	}
+
+	location, err := resp.Location()
+	if errors.Is(err, http.ErrNoLocation) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return location.String(), nil
+}
+
+func (r *Resolver) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Only the status and headers matter.
+	_ = resp.Body.Close()
+
+	return resp, nil
+}
+
+// key identifies a URL for loop detection. Fragments never reach the
+// server, so they do not make a URL different.
+func key(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
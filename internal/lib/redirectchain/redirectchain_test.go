@@ -0,0 +1,159 @@
+package redirectchain_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/redirectchain"
+	"link-shortener/internal/lib/urlpolicy"
+)
+
+// newServer serves redirects from the paths of routes to their values
+// (relative to the server unless absolute), and 200 for other paths.
+func newServer(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if to, ok := routes[r.URL.Path]; ok {
+			http.Redirect(w, r, to, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newResolver(cfg redirectchain.Config) *redirectchain.Resolver {
+	// httptest servers listen on loopback.
+	cfg.AllowPrivate = true
+	return redirectchain.New(cfg)
+}
+
+func TestResolve(t *testing.T) {
+	srv := newServer(t, map[string]string{
+		"/a":     "/b",
+		"/b":     "c",
+		"/loop":  "/loop2",
+		"/loop2": "/loop#again",
+	})
+
+	chain, err := newResolver(redirectchain.Config{}).Resolve(context.Background(), srv.URL+"/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}, chain.URLs)
+	assert.Equal(t, 2, chain.Hops())
+	assert.Equal(t, srv.URL+"/c", chain.Final())
+
+	chain, err = newResolver(redirectchain.Config{}).Resolve(context.Background(), srv.URL+"/c")
+	require.NoError(t, err)
+	assert.Equal(t, 0, chain.Hops())
+
+	_, err = newResolver(redirectchain.Config{}).Resolve(context.Background(), srv.URL+"/loop")
+	require.ErrorIs(t, err, redirectchain.ErrLoop)
+
+	chain, err = newResolver(redirectchain.Config{MaxHops: 1}).Resolve(context.Background(), srv.URL+"/a")
+	require.ErrorIs(t, err, redirectchain.ErrTooManyHops)
+	assert.Equal(t, 1, chain.Hops())
+}
+
+func TestResolveAcrossServers(t *testing.T) {
+	var other *httptest.Server
+	first := newServer(t, map[string]string{})
+	other = newServer(t, map[string]string{"/x": first.URL + "/end"})
+	start := newServer(t, map[string]string{"/s": other.URL + "/x"})
+
+	chain, err := newResolver(redirectchain.Config{}).Resolve(context.Background(), start.URL+"/s")
+	require.NoError(t, err)
+	assert.Equal(t, []string{start.URL + "/s", other.URL + "/x", first.URL + "/end"}, chain.URLs)
+}
+
+func TestResolveHeadNotAllowed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path == "/a" {
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	chain, err := newResolver(redirectchain.Config{}).Resolve(context.Background(), srv.URL+"/a")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/b", chain.Final())
+}
+
+func TestResolveCheck(t *testing.T) {
+	errNope := errors.New("nope")
+	srv := newServer(t, map[string]string{"/a": "/b", "/b": "/blocked"})
+
+	r := newResolver(redirectchain.Config{Check: func(rawURL string) error {
+		if rawURL == srv.URL+"/blocked" {
+			return errNope
+		}
+		return nil
+	}})
+
+	_, err := r.Resolve(context.Background(), srv.URL+"/a")
+	require.ErrorIs(t, err, redirectchain.ErrRejected)
+	require.ErrorIs(t, err, errNope)
+}
+
+func TestResolvePrivateRefused(t *testing.T) {
+	srv := newServer(t, map[string]string{})
+
+	_, err := redirectchain.New(redirectchain.Config{}).Resolve(context.Background(), srv.URL)
+	require.ErrorIs(t, err, urlpolicy.ErrPrivate)
+	assert.Equal(t, "host is in a private network: 127.0.0.1", err.Error())
+}
+
+func TestDestination(t *testing.T) {
+	srv := newServer(t, map[string]string{
+		"/a":    "/b?x=1",
+		"/b":    "/c",
+		"/frag": "/d#kept",
+		"/loop": "/loop",
+	})
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cases := []struct {
+		name    string
+		flatten bool
+		url     string
+		want    string
+		wantErr error
+	}{
+		{name: "Kept", url: srv.URL + "/a", want: srv.URL + "/a"},
+		{name: "Flattened", flatten: true, url: srv.URL + "/a", want: srv.URL + "/c"},
+		{name: "No Redirect", flatten: true, url: srv.URL + "/c?q=1", want: srv.URL + "/c?q=1"},
+		{name: "Fragment Carried", flatten: true, url: srv.URL + "/a#top", want: srv.URL + "/c#top"},
+		{name: "Fragment Replaced", flatten: true, url: srv.URL + "/frag#top", want: srv.URL + "/d#kept"},
+		{name: "Unreachable", flatten: true, url: down.URL + "/a", want: down.URL + "/a"},
+		{name: "Loop", url: srv.URL + "/loop", wantErr: redirectchain.ErrLoop},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := newResolver(redirectchain.Config{Flatten: tc.flatten}).Destination(context.Background(), tc.url)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
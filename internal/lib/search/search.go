@@ -0,0 +1,106 @@
+// Package search splits free-text queries into terms and highlights them in
+// links. Storages rank links with their full-text index, such as the SQLite
+// one, and use this package for the rest, so that every storage marks
+// matches the same way.
+package search
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"link-shortener/internal/storage"
+)
+
+// Matching words are wrapped in these tags by Highlight.
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+// Terms splits query into the distinct lowercase words it searches for.
+// Anything but letters and digits separates words.
+func Terms(query string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// matches reports whether word starts with one of terms, ignoring case.
+func matches(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// Highlight HTML-escapes text and wraps the words starting with one of
+// terms in MarkStart and MarkEnd. It reports whether any word matched.
+func Highlight(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	marked := false
+
+	for rest := text; rest != ""; {
+		start := strings.IndexFunc(rest, func(r rune) bool { return !isSeparator(r) })
+		if start < 0 {
+			b.WriteString(html.EscapeString(rest))
+			break
+		}
+		b.WriteString(html.EscapeString(rest[:start]))
+		rest = rest[start:]
+
+		end := strings.IndexFunc(rest, isSeparator)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := html.EscapeString(rest[:end])
+		if matches(rest[:end], terms) {
+			word = MarkStart + word + MarkEnd
+			marked = true
+		}
+		b.WriteString(word)
+		rest = rest[end:]
+	}
+
+	return b.String(), marked
+}
+
+// Highlights returns the searched fields of link in which terms match.
+func Highlights(link storage.Link, terms []string) storage.Highlights {
+	var h storage.Highlights
+	mark := func(field *string, text string) {
+		if marked, ok := Highlight(text, terms); ok {
+			*field = marked
+		}
+	}
+	mark(&h.Alias, link.Alias)
+	mark(&h.URL, link.URL)
+	mark(&h.Title, link.Title)
+	for _, tag := range link.Tags {
+		if marked, ok := Highlight(tag, terms); ok {
+			h.Tags = append(h.Tags, marked)
+		}
+	}
+	return h
+}
+
+// Field weights of search results. A match in the alias counts most, then
+// one in the tags, the title and the destination URL.
+const (
+	WeightAlias = 4
+	WeightURL   = 1
+	WeightTitle = 2
+	WeightTags  = 3
+)
@@ -0,0 +1,65 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"link-shortener/internal/lib/search"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"go", "blog", "2025"}, search.Terms(`  Go-Blog "go" 2025!`))
+	assert.Equal(t, []string{"acme", "docs"}, search.Terms("acme~docs"))
+	assert.Empty(t, search.Terms(`"*" - ~`))
+}
+
+func TestHighlight(t *testing.T) {
+	cases := []struct {
+		name       string
+		text       string
+		terms      []string
+		want       string
+		wantMarked bool
+	}{
+		{
+			name:       "Prefix",
+			text:       "The Go Blog",
+			terms:      []string{"blo"},
+			want:       "The Go <mark>Blog</mark>",
+			wantMarked: true,
+		},
+		{
+			name:       "URL",
+			text:       "https://go.dev/blog?q=a&b",
+			terms:      []string{"go", "b"},
+			want:       "https://<mark>go</mark>.dev/<mark>blog</mark>?q=a&amp;<mark>b</mark>",
+			wantMarked: true,
+		},
+		{
+			name:  "Escapes Without Match",
+			text:  "<b>Tom & Jerry</b>",
+			terms: []string{"x"},
+			want:  "&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;",
+		},
+		{
+			name:       "Unicode",
+			text:       "Grüße aus Köln",
+			terms:      []string{"köln"},
+			want:       "Grüße aus <mark>Köln</mark>",
+			wantMarked: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, marked := search.Highlight(tc.text, tc.terms)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantMarked, marked)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package shortdomain tells which of the short domains of an instance a
+// request was sent to, and what each domain serves besides its links.
+package shortdomain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrUnknown = errors.New("unknown domain")
+
+// Config describes a short domain.
+type Config struct {
+	// Host is the name of the domain, empty for the default domain.
+	Host string
+	// RootURL, if set, is where the domain itself (the path /) redirects.
+	RootURL string
+	// NotFoundPage, if set, is the path of an HTML file shown to browsers
+	// that ask for an alias the domain does not have.
+	NotFoundPage string
+	// Workspace, if set, is the name of the workspace owning the domain.
+	Workspace string
+}
+
+// Domain is a short domain, ready to serve.
+type Domain struct {
+	// Host is empty for the default domain.
+	Host    string
+	RootURL string
+	// NotFound is the page for missing aliases, nil if there is none.
+	NotFound []byte
+	// Workspace is the name of the workspace owning the domain, empty if
+	// the domain is shared.
+	Workspace string
+}
+
+// Set is the short domains of an instance. Requests to hosts that are none
+// of them go to the default domain. A nil *Set has only a default domain
+// with neither a root URL nor a page for missing aliases.
+type Set struct {
+	def   Domain
+	hosts map[string]Domain
+}
+
+// New returns the set of the default domain def and the others, with their
+// pages read.
+func New(def Config, others []Config) (*Set, error) {
+	const op = "shortdomain.New"
+
+	s := &Set{hosts: make(map[string]Domain, len(others))}
+
+	var err error
+	if s.def, err = load(def); err != nil {
+		return nil, fmt.Errorf("%s: default domain: %w", op, err)
+	}
+	s.def.Host = ""
+
+	for _, cfg := range others {
+		d, err := load(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, cfg.Host, err)
+		}
+		if d.Host == "" {
+			return nil, fmt.Errorf("%s: domain without a host", op)
+		}
+		if _, ok := s.hosts[d.Host]; ok {
+			return nil, fmt.Errorf("%s: %s: listed twice", op, d.Host)
+		}
+		s.hosts[d.Host] = d
+	}
+
+	return s, nil
+}
+
+func load(cfg Config) (Domain, error) {
+	d := Domain{Host: Host(cfg.Host), RootURL: cfg.RootURL, Workspace: cfg.Workspace}
+
+	if d.RootURL != "" {
+		u, err := url.Parse(d.RootURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Domain{}, fmt.Errorf("root url %q is not an absolute http or https url", d.RootURL)
+		}
+	}
+
+	if cfg.NotFoundPage != "" {
+		page, err := os.ReadFile(cfg.NotFoundPage)
+		if err != nil {
+			return Domain{}, err
+		}
+		d.NotFound = page
+	}
+
+	return d, nil
+}
+
+// ForRequest returns the domain r was sent to.
+func (s *Set) ForRequest(r *http.Request) Domain {
+	return s.Lookup(r.Host)
+}
+
+// Lookup returns the domain named host, or the default domain if there is
+// none.
+func (s *Set) Lookup(host string) Domain {
+	if s == nil {
+		return Domain{}
+	}
+	if d, ok := s.hosts[Host(host)]; ok {
+		return d
+	}
+	return s.def
+}
+
+// Normalize returns the name links on the domain host are stored under:
+// empty for the default domain, which host may leave empty, and the host
+// without port or case otherwise. Hosts that are no domain of s fail with
+// ErrUnknown.
+func (s *Set) Normalize(host string) (string, error) {
+	if host == "" {
+		return "", nil
+	}
+	if s != nil {
+		if d, ok := s.hosts[Host(host)]; ok {
+			return d.Host, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknown, host)
+}
+
+// Get returns the domain links stored under host are on, as Normalize
+// names it, and whether s has it.
+func (s *Set) Get(host string) (Domain, bool) {
+	if host == "" {
+		return s.Lookup(""), true
+	}
+	if s == nil {
+		return Domain{}, false
+	}
+	d, ok := s.hosts[host]
+	return d, ok
+}
+
+// Hosts returns the names of the domains other than the default one, in
+// order.
+func (s *Set) Hosts() []string {
+	if s == nil {
+		return nil
+	}
+	hosts := make([]string, 0, len(s.hosts))
+	for h := range s.hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Host returns the name in hostport, as a Host header has it, lowercased
+// and without port or trailing dot.
+func Host(hostport string) string {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,84 @@
+package shortdomain_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/shortdomain"
+)
+
+func TestSet(t *testing.T) {
+	page := filepath.Join(t.TempDir(), "404.html")
+	require.NoError(t, os.WriteFile(page, []byte("<h1>Nothing here</h1>"), 0o600))
+
+	set, err := shortdomain.New(
+		shortdomain.Config{RootURL: "https://example.com/"},
+		[]shortdomain.Config{
+			{Host: "Go.Acme.com", RootURL: "https://acme.com/", NotFoundPage: page},
+			{Host: "brand.example"},
+		},
+	)
+	require.NoError(t, err)
+
+	d := set.ForRequest(httptest.NewRequest("GET", "http://go.acme.com:8080/spring", nil))
+	assert.Equal(t, "go.acme.com", d.Host)
+	assert.Equal(t, "https://acme.com/", d.RootURL)
+	assert.Equal(t, "<h1>Nothing here</h1>", string(d.NotFound))
+
+	assert.Equal(t, "brand.example", set.Lookup("BRAND.example.").Host)
+
+	// Anything else is the default domain.
+	d = set.Lookup("localhost:8087")
+	assert.Equal(t, shortdomain.Domain{RootURL: "https://example.com/"}, d)
+
+	host, err := set.Normalize("GO.ACME.COM")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", host)
+
+	host, err = set.Normalize("")
+	require.NoError(t, err)
+	assert.Empty(t, host)
+
+	_, err = set.Normalize("evil.example")
+	assert.True(t, errors.Is(err, shortdomain.ErrUnknown))
+
+	assert.Equal(t, []string{"brand.example", "go.acme.com"}, set.Hosts())
+}
+
+func TestNilSet(t *testing.T) {
+	var set *shortdomain.Set
+
+	assert.Equal(t, shortdomain.Domain{}, set.Lookup("go.acme.com"))
+	_, err := set.Normalize("go.acme.com")
+	assert.True(t, errors.Is(err, shortdomain.ErrUnknown))
+	assert.Empty(t, set.Hosts())
+}
+
+func TestNewInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		others []shortdomain.Config
+	}{
+		{name: "No Host", others: []shortdomain.Config{{RootURL: "https://acme.com"}}},
+		{name: "Twice", others: []shortdomain.Config{{Host: "go.acme.com"}, {Host: "GO.acme.com"}}},
+		{name: "Relative Root", others: []shortdomain.Config{{Host: "go.acme.com", RootURL: "/home"}}},
+		{name: "Missing Page", others: []shortdomain.Config{{Host: "go.acme.com", NotFoundPage: "/nonexistent/404.html"}}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := shortdomain.New(shortdomain.Config{}, tc.others)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package shorturl builds the public URLs of short links.
+package shorturl
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Base returns the URL short links are served under: public if it is set,
+// otherwise the scheme and host r was sent to.
+func Base(r *http.Request, public string) string {
+	if public != "" {
+		return strings.TrimRight(public, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// OnDomain returns base moved to the short domain host, keeping its
+// scheme. An empty host, the default domain, leaves base as it is.
+func OnDomain(base, host string) string {
+	if host == "" {
+		return base
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" {
+		return "https://" + host
+	}
+	return u.Scheme + "://" + host
+}
+
+// For returns the short URL of alias under base.
+func For(base, alias string) string {
+	return base + "/" + url.PathEscape(alias)
+}
+
+// QR returns the URL of the QR code of alias under base. It is kept out of
+// the paths below the alias, which prefix links forward.
+func QR(base, alias string) string {
+	return base + "/qr/" + url.PathEscape(alias)
+}
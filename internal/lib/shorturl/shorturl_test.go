@@ -0,0 +1,31 @@
+package shorturl_test
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"link-shortener/internal/lib/shorturl"
+)
+
+func TestBase(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8087/url", nil)
+	assert.Equal(t, "https://sho.rt", shorturl.Base(r, "https://sho.rt/"))
+	assert.Equal(t, "http://localhost:8087", shorturl.Base(r, ""))
+
+	r.TLS = &tls.ConnectionState{}
+	assert.Equal(t, "https://localhost:8087", shorturl.Base(r, ""))
+}
+
+func TestFor(t *testing.T) {
+	assert.Equal(t, "https://sho.rt/spring", shorturl.For("https://sho.rt", "spring"))
+	assert.Equal(t, "https://sho.rt/qr/spring", shorturl.QR("https://sho.rt", "spring"))
+}
+
+func TestOnDomain(t *testing.T) {
+	assert.Equal(t, "https://sho.rt", shorturl.OnDomain("https://sho.rt", ""))
+	assert.Equal(t, "https://go.acme.com", shorturl.OnDomain("https://sho.rt", "go.acme.com"))
+	assert.Equal(t, "http://go.acme.com", shorturl.OnDomain("http://localhost:8087", "go.acme.com"))
+}
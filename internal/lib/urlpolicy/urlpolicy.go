@@ -0,0 +1,322 @@
+// Package urlpolicy decides which destination URLs links may point to:
+// only some schemes, no private networks, not the shortener itself, and
+// domains from an allow/deny list that is reloaded when its file changes.
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"link-shortener/internal/lib/logger/sl"
+)
+
+var (
+	ErrScheme     = errors.New("scheme is not allowed")
+	ErrNoHost     = errors.New("url has no host")
+	ErrPrivate    = errors.New("host is in a private network")
+	ErrSelf       = errors.New("url points to this shortener")
+	ErrBlocked    = errors.New("domain is blocked")
+	ErrNotAllowed = errors.New("domain is not on the allow list")
+)
+
+// defaultSchemes are allowed if Config.Schemes is empty.
+var defaultSchemes = []string{"http", "https"}
+
+// cgnat is the shared address space of carrier-grade NAT, which
+// netip.Addr.IsPrivate does not cover.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Config configures a Policy.
+type Config struct {
+	// Schemes are the allowed URL schemes; empty means http and https.
+	Schemes []string
+	// AllowPrivate lets URLs point to loopback, private and link-local
+	// addresses and to localhost.
+	AllowPrivate bool
+	// DomainsFile, if set, lists allowed and denied domains, see
+	// ParseDomains.
+	DomainsFile string
+	// OwnHosts are the hosts short links are served on. Links to them
+	// would redirect to other short links, or to themselves.
+	OwnHosts []string
+}
+
+// Policy checks URLs against a Config. It is safe for concurrent use.
+type Policy struct {
+	log          *slog.Logger
+	schemes      map[string]bool
+	allowPrivate bool
+	own          []string
+	file         string
+
+	mu    sync.RWMutex
+	lists Domains
+	stamp fileStamp
+}
+
+// Domains are the lists of a domains file. A domain also covers its
+// subdomains.
+type Domains struct {
+	Allow []string
+	Deny  []string
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New returns the policy described by cfg, with the domains file loaded.
+func New(log *slog.Logger, cfg Config) (*Policy, error) {
+	const op = "urlpolicy.New"
+
+	schemes := cfg.Schemes
+	if len(schemes) == 0 {
+		schemes = defaultSchemes
+	}
+
+	p := &Policy{
+		log:          log,
+		schemes:      make(map[string]bool, len(schemes)),
+		allowPrivate: cfg.AllowPrivate,
+		own:          normalize(cfg.OwnHosts),
+		file:         cfg.DomainsFile,
+	}
+	for _, s := range schemes {
+		p.schemes[strings.ToLower(s)] = true
+	}
+
+	if _, err := p.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, nil
+}
+
+// Check returns an error wrapping one of the package errors if rawURL is
+// not allowed.
+func (p *Policy) Check(rawURL string) error {
+	const op = "urlpolicy.Policy.Check"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if scheme := strings.ToLower(u.Scheme); !p.schemes[scheme] {
+		return fmt.Errorf("%w: %s", ErrScheme, scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return ErrNoHost
+	}
+
+	if covers(p.own, host) {
+		return fmt.Errorf("%w: %s", ErrSelf, host)
+	}
+
+	if !p.allowPrivate && private(host) {
+		return fmt.Errorf("%w: %s", ErrPrivate, host)
+	}
+
+	lists := p.Domains()
+	if covers(lists.Deny, host) {
+		return fmt.Errorf("%w: %s", ErrBlocked, host)
+	}
+	if len(lists.Allow) > 0 && !covers(lists.Allow, host) {
+		return fmt.Errorf("%w: %s", ErrNotAllowed, host)
+	}
+
+	return nil
+}
+
+// Domains returns the lists currently in effect.
+func (p *Policy) Domains() Domains {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lists
+}
+
+// Reload reads the domains file again if it changed since it was last
+// read, and reports whether it did. If the file cannot be read, the old
+// lists stay in effect.
+func (p *Policy) Reload() (bool, error) {
+	const op = "urlpolicy.Policy.Reload"
+
+	if p.file == "" {
+		return false, nil
+	}
+
+	f, err := os.Open(p.file)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+
+	p.mu.RLock()
+	unchanged := stamp == p.stamp
+	p.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	lists, err := ParseDomains(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.mu.Lock()
+	p.lists, p.stamp = lists, stamp
+	p.mu.Unlock()
+
+	return true, nil
+}
+
+// Run reloads the domains file every interval until ctx is done.
+func (p *Policy) Run(ctx context.Context, interval time.Duration) {
+	const op = "urlpolicy.Policy.Run"
+
+	log := p.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := p.Reload()
+			if err != nil {
+				log.Error("failed to reload domains, keeping the old ones", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				lists := p.Domains()
+				log.Info("domains reloaded",
+					slog.Int("allow", len(lists.Allow)),
+					slog.Int("deny", len(lists.Deny)),
+				)
+			}
+		}
+	}
+}
+
+// ParseDomains reads a domains file. Each line is "allow DOMAIN" or "deny
+// DOMAIN"; blank lines and those starting with # are skipped. Denied
+// domains are never allowed. If any domain is allowed, all others are
+// denied.
+func ParseDomains(r io.Reader) (Domains, error) {
+	var d Domains
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return Domains{}, fmt.Errorf("line %d: want \"allow DOMAIN\" or \"deny DOMAIN\"", n)
+		}
+
+		domain := normalize(fields[1:])
+		switch strings.ToLower(fields[0]) {
+		case "allow":
+			d.Allow = append(d.Allow, domain...)
+		case "deny":
+			d.Deny = append(d.Deny, domain...)
+		default:
+			return Domains{}, fmt.Errorf("line %d: unknown action %q", n, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Domains{}, err
+	}
+
+	return d, nil
+}
+
+// private reports whether host is localhost or an address that is not
+// publicly routable. Hosts ending in a number are taken for addresses in
+// a notation netip does not parse (such as 2130706433 or 127.1), which
+// browsers accept, as no top-level domain is numeric.
+func private(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		last := host[strings.LastIndex(host, ".")+1:]
+		return last != "" && (strings.HasPrefix(last, "0x") || strings.Trim(last, "0123456789") == "")
+	}
+
+	return PrivateAddr(addr)
+}
+
+// PrivateAddr reports whether addr is not publicly routable: loopback,
+// private, link-local, unspecified or carrier-grade NAT.
+func PrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || cgnat.Contains(addr)
+}
+
+// RefusePrivate is a net.Dialer Control function refusing connections to
+// private addresses, whatever name they were looked up by. It keeps
+// requests to link destinations from reaching the internal network.
+func RefusePrivate(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if PrivateAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivate, addrPort.Addr())
+	}
+	return nil
+}
+
+// covers reports whether host is one of domains or a subdomain of one.
+func covers(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lowercases domains and strips wildcards and dots around them.
+func normalize(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(d, "*")
+		d = strings.Trim(d, ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package urlpolicy_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/urlpolicy"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "domains.txt")
+	require.NoError(t, os.WriteFile(file, []byte(`
+# partners only
+allow example.com
+allow *.partner.org
+deny ads.example.com
+`), 0o644))
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), urlpolicy.Config{
+		DomainsFile: file,
+		OwnHosts:    []string{"sho.rt"},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://example.com/sale"},
+		{url: "http://www.Example.com./"},
+		{url: "https://shop.partner.org"},
+		{url: "https://partner.org"},
+		{url: "javascript:alert(1)", wantErr: urlpolicy.ErrScheme},
+		{url: "data:text/html,hi", wantErr: urlpolicy.ErrScheme},
+		{url: "ftp://example.com/file", wantErr: urlpolicy.ErrScheme},
+		{url: "https:///path", wantErr: urlpolicy.ErrNoHost},
+		{url: "https://sho.rt/other", wantErr: urlpolicy.ErrSelf},
+		{url: "https://www.sho.rt/other", wantErr: urlpolicy.ErrSelf},
+		{url: "http://localhost:8080/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://api.localhost/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://127.0.0.1/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://10.1.2.3/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://192.168.0.1/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://100.64.0.1/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://0.0.0.0/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://[::1]/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://[::ffff:127.0.0.1]/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://[fd00::1]/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://2130706433/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://127.1/", wantErr: urlpolicy.ErrPrivate},
+		{url: "http://0x7f.0x0.0x0.0x1/", wantErr: urlpolicy.ErrPrivate},
+		{url: "https://ads.example.com/", wantErr: urlpolicy.ErrBlocked},
+		{url: "https://x.ads.example.com/", wantErr: urlpolicy.ErrBlocked},
+		{url: "https://example.org/", wantErr: urlpolicy.ErrNotAllowed},
+		{url: "https://notexample.com/", wantErr: urlpolicy.ErrNotAllowed},
+		{url: "http://8.8.8.8/", wantErr: urlpolicy.ErrNotAllowed},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.url, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy.Check(tc.url)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), urlpolicy.Config{
+		Schemes:      []string{"https", "mailto"},
+		AllowPrivate: true,
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, policy.Check("https://localhost/"))
+	assert.NoError(t, policy.Check("https://10.0.0.1/"))
+	assert.NoError(t, policy.Check("https://anything.example/"))
+	assert.ErrorIs(t, policy.Check("http://example.com/"), urlpolicy.ErrScheme)
+	// Without a host there is nothing to redirect to.
+	assert.ErrorIs(t, policy.Check("mailto:team@example.com"), urlpolicy.ErrNoHost)
+}
+
+func TestReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(file, []byte("deny evil.com\n"), 0o644))
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), urlpolicy.Config{DomainsFile: file})
+	require.NoError(t, err)
+	require.ErrorIs(t, policy.Check("https://evil.com"), urlpolicy.ErrBlocked)
+
+	reloaded, err := policy.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file")
+
+	require.NoError(t, os.WriteFile(file, []byte("deny evil.com\ndeny worse.com\n"), 0o644))
+	reloaded, err = policy.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.ErrorIs(t, policy.Check("https://worse.com"), urlpolicy.ErrBlocked)
+
+	// A broken file leaves the lists as they were.
+	require.NoError(t, os.WriteFile(file, []byte("block evil.com\n"), 0o644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	_, err = policy.Reload()
+	require.Error(t, err)
+	assert.Equal(t, []string{"evil.com", "worse.com"}, policy.Domains().Deny)
+}
+
+func TestNewMissingFile(t *testing.T) {
+	_, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), urlpolicy.Config{
+		DomainsFile: filepath.Join(t.TempDir(), "missing.txt"),
+	})
+	require.Error(t, err)
+}
+
+func TestParseDomains(t *testing.T) {
+	d, err := urlpolicy.ParseDomains(strings.NewReader("ALLOW Example.COM.\n\n# note\ndeny .bad.net\n"))
+	require.NoError(t, err)
+	assert.Equal(t, urlpolicy.Domains{Allow: []string{"example.com"}, Deny: []string{"bad.net"}}, d)
+
+	_, err = urlpolicy.ParseDomains(strings.NewReader("deny\n"))
+	require.EqualError(t, err, `line 1: want "allow DOMAIN" or "deny DOMAIN"`)
+
+	_, err = urlpolicy.ParseDomains(strings.NewReader("allow a.com\nblock b.com\n"))
+	require.EqualError(t, err, `line 2: unknown action "block"`)
+}
@@ -0,0 +1,60 @@
+// Package urlquery adds query parameters to URLs without disturbing what
+// is already there.
+package urlquery
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Merge adds the parameters of each layer to the query of rawURL. A key is
+// only taken from the first place that has it: rawURL itself, then the
+// layers in order, so pass the most important layer first. The existing
+// query and the fragment are kept as they are; added parameters follow
+// them, each layer's in key order.
+func Merge(rawURL string, layers ...url.Values) (string, error) {
+	const op = "urlquery.Merge"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	present, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var added []string
+	for _, layer := range layers {
+		keys := make([]string, 0, len(layer))
+		for key := range layer {
+			if _, ok := present[key]; !ok && key != "" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, value := range layer[key] {
+				added = append(added, url.QueryEscape(key)+"="+url.QueryEscape(value))
+			}
+			present[key] = layer[key]
+		}
+	}
+
+	if len(added) == 0 {
+		return rawURL, nil
+	}
+
+	if u.RawQuery != "" {
+		added = append([]string{u.RawQuery}, added...)
+	}
+	u.RawQuery = strings.Join(added, "&")
+	// A URL ending in a bare "?" would otherwise keep an empty query part.
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
@@ -0,0 +1,95 @@
+package urlquery_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/urlquery"
+)
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name   string
+		url    string
+		layers []url.Values
+		want   string
+	}{
+		{
+			name:   "No Parameters",
+			url:    "https://example.com/a?b=1#top",
+			layers: []url.Values{{}},
+			want:   "https://example.com/a?b=1#top",
+		},
+		{
+			name:   "Appended In Key Order",
+			url:    "https://example.com/sale",
+			layers: []url.Values{{"utm_source": {"news"}, "utm_medium": {"email"}}},
+			want:   "https://example.com/sale?utm_medium=email&utm_source=news",
+		},
+		{
+			name:   "Existing Query Kept",
+			url:    "https://example.com/?z=1&a=2",
+			layers: []url.Values{{"utm_source": {"news"}}},
+			want:   "https://example.com/?z=1&a=2&utm_source=news",
+		},
+		{
+			name:   "Destination Wins",
+			url:    "https://example.com/?utm_source=site",
+			layers: []url.Values{{"utm_source": {"news"}}},
+			want:   "https://example.com/?utm_source=site",
+		},
+		{
+			name: "First Layer Wins",
+			url:  "https://example.com/",
+			layers: []url.Values{
+				{"utm_source": {"twitter"}, "ref": {"x"}},
+				{"utm_source": {"news"}, "utm_medium": {"email"}},
+			},
+			want: "https://example.com/?ref=x&utm_source=twitter&utm_medium=email",
+		},
+		{
+			name:   "Fragment Preserved",
+			url:    "https://example.com/docs#section-2",
+			layers: []url.Values{{"ref": {"x"}}},
+			want:   "https://example.com/docs?ref=x#section-2",
+		},
+		{
+			name:   "Values Escaped",
+			url:    "https://example.com/",
+			layers: []url.Values{{"q": {"a&b=c #d"}}},
+			want:   "https://example.com/?q=a%26b%3Dc+%23d",
+		},
+		{
+			name:   "Repeated Key",
+			url:    "https://example.com/",
+			layers: []url.Values{{"tag": {"a", "b"}}},
+			want:   "https://example.com/?tag=a&tag=b",
+		},
+		{
+			name:   "Bare Question Mark",
+			url:    "https://example.com/?",
+			layers: []url.Values{{"ref": {"x"}}},
+			want:   "https://example.com/?ref=x",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := urlquery.Merge(tc.url, tc.layers...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMergeInvalidURL(t *testing.T) {
+	_, err := urlquery.Merge("https://example.com/%zz", url.Values{"a": {"b"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+// Package useragent recognizes the platform of a visitor from the
+// User-Agent header, as far as link targeting needs it, and the crawlers
+// of chat apps and social networks that preview links.
+package useragent
+
+import "strings"
+
+// Platforms a link target can be restricted to. The operating systems are
+// exclusive; mobile and desktop are broader and overlap with them.
+const (
+	IOS     = "ios"
+	Android = "android"
+	Windows = "windows"
+	MacOS   = "macos"
+	Linux   = "linux"
+	Mobile  = "mobile"
+	Desktop = "desktop"
+)
+
+// Device is what is known about a visitor's device.
+type Device struct {
+	// OS is one of the operating system platforms, or empty if unknown.
+	OS     string
+	Mobile bool
+}
+
+// Parse recognizes the device a User-Agent header belongs to. Unknown
+// agents, such as bots and command-line tools, yield a zero Device.
+func Parse(ua string) Device {
+	lower := strings.ToLower(ua)
+	has := func(s string) bool { return strings.Contains(lower, s) }
+
+	var d Device
+	switch {
+	// Checked first: Windows Phone agents also claim to be Android and iOS.
+	case has("windows phone"):
+		d.Mobile = true
+	case has("iphone"), has("ipad"), has("ipod"):
+		d.OS = IOS
+		d.Mobile = true
+	case has("android"):
+		d.OS = Android
+		d.Mobile = true
+	case has("windows"):
+		d.OS = Windows
+	case has("macintosh"), has("mac os x"):
+		d.OS = MacOS
+	case has("cros"), has("linux"):
+		d.OS = Linux
+	}
+
+	// Other phones and tablets mostly say so.
+	if d.OS == "" && (has("mobile") || has("tablet")) {
+		d.Mobile = true
+	}
+
+	return d
+}
+
+// Matches reports whether the device belongs to platform.
+func (d Device) Matches(platform string) bool {
+	switch platform {
+	case Mobile:
+		return d.Mobile
+	case Desktop:
+		return d.OS != "" && !d.Mobile
+	default:
+		return d.OS != "" && d.OS == platform
+	}
+}
+
+// unfurlers are in the User-Agent of the crawlers that fetch a link when it
+// is pasted into a chat or a post, to show a preview of it. The in-app
+// browsers of the same apps do not send them.
+var unfurlers = []string{
+	"slackbot",            // Slack: "Slackbot-LinkExpanding 1.0"
+	"skypeuripreview",     // Teams and Skype
+	"microsoftpreview",    // Teams, newer clients
+	"discordbot",          // Discord
+	"telegrambot",         // Telegram
+	"whatsapp/",           // WhatsApp: "WhatsApp/2.23.20.0 A"
+	"facebookexternalhit", // Facebook, Messenger and iMessage
+	"twitterbot",          // X
+	"linkedinbot",         // LinkedIn
+	"mattermost-bot",      // Mattermost
+	"pinterestbot",        // Pinterest
+	"redditbot",           // Reddit
+	"embedly",             // Embedly, used by many others
+	"iframely",            // Iframely, likewise
+}
+
+// Unfurler reports whether ua belongs to a crawler fetching a link to
+// preview it in a chat app or social network.
+func Unfurler(ua string) bool {
+	lower := strings.ToLower(ua)
+	for _, token := range unfurlers {
+		if strings.Contains(lower, token) {
+			return true
+		}
+	}
+	return false
+}
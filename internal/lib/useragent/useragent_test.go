@@ -0,0 +1,133 @@
+package useragent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"link-shortener/internal/lib/useragent"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		ua   string
+		want useragent.Device
+	}{
+		{
+			name: "iPhone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want: useragent.Device{OS: useragent.IOS, Mobile: true},
+		},
+		{
+			name: "Android",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+			want: useragent.Device{OS: useragent.Android, Mobile: true},
+		},
+		{
+			name: "Windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			want: useragent.Device{OS: useragent.Windows},
+		},
+		{
+			name: "Mac",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			want: useragent.Device{OS: useragent.MacOS},
+		},
+		{
+			name: "Linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; rv:124.0) Gecko/20100101 Firefox/124.0",
+			want: useragent.Device{OS: useragent.Linux},
+		},
+		{
+			name: "Windows Phone",
+			ua:   "Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0 Mobile Safari/537.36 Edge/15.14977",
+			want: useragent.Device{Mobile: true},
+		},
+		{
+			name: "Command Line",
+			ua:   "curl/8.5.0",
+			want: useragent.Device{},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, useragent.Parse(tc.ua))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	iphone := useragent.Device{OS: useragent.IOS, Mobile: true}
+	mac := useragent.Device{OS: useragent.MacOS}
+	unknown := useragent.Device{}
+
+	assert.True(t, iphone.Matches(useragent.IOS))
+	assert.True(t, iphone.Matches(useragent.Mobile))
+	assert.False(t, iphone.Matches(useragent.Desktop))
+	assert.False(t, iphone.Matches(useragent.MacOS))
+
+	assert.True(t, mac.Matches(useragent.MacOS))
+	assert.True(t, mac.Matches(useragent.Desktop))
+	assert.False(t, mac.Matches(useragent.Mobile))
+
+	for _, platform := range []string{useragent.IOS, useragent.Mobile, useragent.Desktop, ""} {
+		assert.False(t, unknown.Matches(platform), platform)
+	}
+}
+
+func TestUnfurler(t *testing.T) {
+	cases := []struct {
+		name string
+		ua   string
+		want bool
+	}{
+		{
+			name: "Slack",
+			ua:   "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want: true,
+		},
+		{
+			name: "Teams",
+			ua:   "Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) SkypeUriPreview Preview/0.5 skype-url-preview@microsoft.com",
+			want: true,
+		},
+		{
+			name: "Facebook",
+			ua:   "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			want: true,
+		},
+		{
+			name: "WhatsApp",
+			ua:   "WhatsApp/2.23.20.0 A",
+			want: true,
+		},
+		{
+			name: "Browser",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+		},
+		{
+			name: "Facebook In-App Browser",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/460.0.0.38.109]",
+		},
+		{
+			name: "Search Crawler",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, useragent.Unfurler(tc.ua))
+		})
+	}
+}
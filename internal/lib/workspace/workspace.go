@@ -0,0 +1,105 @@
+// Package workspace carries the workspace a request acts for, and maps the
+// aliases a workspace uses to those its links are stored under.
+//
+// Each workspace has a namespace on the shared domains: the link it calls
+// "docs" on the default domain is stored, and served, as "acme~docs" when
+// the workspace is called "acme". On the domains it owns a workspace uses
+// aliases as they are.
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/storage"
+	"regexp"
+	"strings"
+)
+
+// Separator joins the name of a workspace to the aliases in its namespace.
+const Separator = "~"
+
+var (
+	ErrInvalidName   = errors.New("workspace names are 1 to 32 lowercase letters, digits and hyphens")
+	ErrForeignDomain = errors.New("domain belongs to another workspace")
+	ErrForeignAlias  = errors.New("alias is in the namespace of another workspace")
+	ErrWorkspaceOnly = errors.New("aliases with '" + Separator + "' are only for the links of workspaces")
+)
+
+var nameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidateName returns ErrInvalidName unless name can be that of a
+// workspace.
+func ValidateName(name string) error {
+	if !nameRe.MatchString(name) {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying ws.
+func NewContext(ctx context.Context, ws storage.Workspace) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ws)
+}
+
+// FromContext returns the workspace in ctx, if the request acts for one
+// rather than for the admin.
+func FromContext(ctx context.Context) (storage.Workspace, bool) {
+	ws, ok := ctx.Value(ctxKey{}).(storage.Workspace)
+	return ws, ok
+}
+
+// Alias returns the alias the link ws calls alias on domain is stored
+// under. Aliases already in the namespace of ws are returned as they are.
+// It fails with ErrForeignDomain on the domains of other workspaces and
+// with ErrForeignAlias for aliases in their namespaces.
+func Alias(ws storage.Workspace, domain shortdomain.Domain, alias string) (string, error) {
+	switch {
+	case domain.Workspace == ws.Name:
+		return alias, nil
+	case domain.Workspace != "":
+		return "", ErrForeignDomain
+	}
+
+	prefix := ws.Name + Separator
+	switch {
+	case strings.HasPrefix(alias, prefix):
+		return alias, nil
+	case strings.Contains(alias, Separator):
+		return "", ErrForeignAlias
+	default:
+		return prefix + alias, nil
+	}
+}
+
+// CheckUnowned returns ErrWorkspaceOnly if alias is in the namespace of a
+// workspace, which a link of no workspace must not take: the workspace
+// could neither see the link nor use the alias.
+func CheckUnowned(alias string) error {
+	if strings.Contains(alias, Separator) {
+		return ErrWorkspaceOnly
+	}
+	return nil
+}
+
+// Resolve returns the domain and alias the link ws calls alias on host,
+// which is empty for the default domain, is stored under. It fails like
+// Alias, and with shortdomain.ErrUnknown if host is none of domains.
+func Resolve(ws storage.Workspace, domains *shortdomain.Set, host, alias string) (string, string, error) {
+	host = shortdomain.Host(host)
+
+	domain, ok := domains.Get(host)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", shortdomain.ErrUnknown, host)
+	}
+
+	alias, err := Alias(ws, domain, alias)
+	if err != nil {
+		return "", "", err
+	}
+
+	return host, alias, nil
+}
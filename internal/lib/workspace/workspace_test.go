@@ -0,0 +1,73 @@
+package workspace_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/shortdomain"
+	"link-shortener/internal/lib/workspace"
+	"link-shortener/internal/storage"
+)
+
+func TestAlias(t *testing.T) {
+	acme := storage.Workspace{ID: 1, Name: "acme"}
+	shared := shortdomain.Domain{}
+	own := shortdomain.Domain{Host: "go.acme.com", Workspace: "acme"}
+	foreign := shortdomain.Domain{Host: "go.other.com", Workspace: "other"}
+
+	cases := []struct {
+		name    string
+		domain  shortdomain.Domain
+		alias   string
+		want    string
+		wantErr error
+	}{
+		{name: "Shared", domain: shared, alias: "docs", want: "acme~docs"},
+		{name: "Shared Namespaced", domain: shared, alias: "acme~docs", want: "acme~docs"},
+		{name: "Shared Foreign Alias", domain: shared, alias: "other~docs", wantErr: workspace.ErrForeignAlias},
+		{name: "Own Domain", domain: own, alias: "docs", want: "docs"},
+		{name: "Foreign Domain", domain: foreign, alias: "docs", wantErr: workspace.ErrForeignDomain},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := workspace.Alias(acme, tc.domain, tc.alias)
+			require.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	domains, err := shortdomain.New(shortdomain.Config{}, []shortdomain.Config{{Host: "go.acme.com", Workspace: "acme"}})
+	require.NoError(t, err)
+	acme := storage.Workspace{ID: 1, Name: "acme"}
+
+	domain, alias, err := workspace.Resolve(acme, domains, "", "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "", domain)
+	assert.Equal(t, "acme~docs", alias)
+
+	domain, alias, err = workspace.Resolve(acme, domains, "Go.Acme.com", "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", domain)
+	assert.Equal(t, "docs", alias)
+
+	_, _, err = workspace.Resolve(acme, domains, "go.unknown.com", "docs")
+	require.ErrorIs(t, err, shortdomain.ErrUnknown)
+}
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, workspace.ValidateName("team-a"))
+	assert.ErrorIs(t, workspace.ValidateName(""), workspace.ErrInvalidName)
+	assert.ErrorIs(t, workspace.ValidateName("Team"), workspace.ErrInvalidName)
+	assert.ErrorIs(t, workspace.ValidateName("a~b"), workspace.ErrInvalidName)
+}
+
+func TestCheckUnowned(t *testing.T) {
+	assert.NoError(t, workspace.CheckUnowned("docs"))
+	assert.NoError(t, workspace.CheckUnowned(""))
+	assert.ErrorIs(t, workspace.CheckUnowned("acme~docs"), workspace.ErrWorkspaceOnly)
+}
@@ -0,0 +1,127 @@
+// Package linkmeta fetches the metadata of link destinations in the
+// background, so that saving a link does not wait for its page.
+package linkmeta
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"link-shortener/internal/lib/logger/sl"
+	"link-shortener/internal/lib/opengraph"
+	"link-shortener/internal/storage"
+)
+
+const (
+	defaultWorkers   = 2
+	defaultQueueSize = 1000
+)
+
+// Store keeps the fetched metadata.
+type Store interface {
+	SetLinkMeta(linkID int64, url string, meta storage.PageMeta) error
+}
+
+// Fetcher fetches the metadata of a page, see opengraph.Fetcher.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (opengraph.Meta, error)
+}
+
+// Config configures a Queue. Zero values mean the defaults.
+type Config struct {
+	// Workers is how many pages are fetched at once; 2 by default.
+	Workers int
+	// QueueSize is how many links may wait to be fetched; 1000 by default.
+	QueueSize int
+}
+
+type job struct {
+	linkID int64
+	url    string
+}
+
+// Queue fetches metadata for the links handed to Enqueue.
+type Queue struct {
+	log     *slog.Logger
+	store   Store
+	fetcher Fetcher
+	workers int
+	jobs    chan job
+}
+
+// New returns a Queue storing what fetcher finds in store. Nothing is
+// fetched until Run is called.
+func New(log *slog.Logger, store Store, fetcher Fetcher, cfg Config) *Queue {
+	q := &Queue{log: log, store: store, fetcher: fetcher, workers: cfg.Workers}
+	if q.workers <= 0 {
+		q.workers = defaultWorkers
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	q.jobs = make(chan job, size)
+
+	return q
+}
+
+// Enqueue asks for the metadata of the page at url to be stored for the
+// link with linkID. It never blocks: if the queue is full, the link is left
+// without metadata.
+func (q *Queue) Enqueue(linkID int64, url string) {
+	select {
+	case q.jobs <- job{linkID: linkID, url: url}:
+	default:
+		q.log.Warn("metadata queue is full, skipping link",
+			slog.String("op", "linkmeta.Queue.Enqueue"),
+			slog.Int64("link_id", linkID),
+		)
+	}
+}
+
+// Run fetches metadata for queued links until ctx is done.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-q.jobs:
+					q.fetch(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fetch stores the metadata of j's page. A page that cannot be fetched
+// leaves the link without metadata, replacing any fetched for an earlier
+// destination.
+func (q *Queue) fetch(ctx context.Context, j job) {
+	const op = "linkmeta.Queue.fetch"
+
+	log := q.log.With(slog.String("op", op), slog.Int64("link_id", j.linkID))
+
+	meta, err := q.fetcher.Fetch(ctx, j.url)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Info("failed to fetch page metadata", slog.String("url", j.url), sl.Err(err))
+	}
+
+	err = q.store.SetLinkMeta(j.linkID, j.url, storage.PageMeta{
+		Title:       meta.Title,
+		Description: meta.Description,
+		Image:       meta.Image,
+	})
+	if err != nil {
+		log.Error("failed to store page metadata", sl.Err(err))
+	}
+}
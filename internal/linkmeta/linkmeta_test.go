@@ -0,0 +1,79 @@
+package linkmeta_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/lib/logger/handlers/slogdiscard"
+	"link-shortener/internal/lib/opengraph"
+	"link-shortener/internal/linkmeta"
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestQueue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<head><title>Page ` + r.URL.Path + `</title>` +
+			`<meta property="og:image" content="/cover.png"></head>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	ok, err := s.SaveURL(srv.URL+"/a", "a")
+	require.NoError(t, err)
+	gone, err := s.SaveLink(storage.Link{Alias: "gone", URL: srv.URL + "/gone", Title: "Before"})
+	require.NoError(t, err)
+
+	q := linkmeta.New(slogdiscard.NewDiscardLogger(), s,
+		opengraph.New(opengraph.Config{AllowPrivate: true}), linkmeta.Config{})
+	q.Enqueue(ok, srv.URL+"/a")
+	q.Enqueue(gone, srv.URL+"/gone")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		link, err := s.GetLinkByID(ok)
+		return err == nil && link.Title != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	link, err := s.GetLinkByID(ok)
+	require.NoError(t, err)
+	require.Equal(t, "Page /a", link.Title)
+	require.Equal(t, srv.URL+"/cover.png", link.Image)
+
+	require.Eventually(t, func() bool {
+		link, err := s.GetLinkByID(gone)
+		return err == nil && link.Title == ""
+	}, 5*time.Second, 10*time.Millisecond, "metadata of a page that is gone is cleared")
+}
+
+func TestQueueFull(t *testing.T) {
+	q := linkmeta.New(slogdiscard.NewDiscardLogger(), nil, nil, linkmeta.Config{QueueSize: 1})
+
+	// Neither blocks, though nothing runs the queue.
+	q.Enqueue(1, "https://example.com/1")
+	q.Enqueue(2, "https://example.com/2")
+}
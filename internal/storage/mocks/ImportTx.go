@@ -0,0 +1,105 @@
+// Code generated by mockery v2.28.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	storage "link-shortener/internal/storage"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ImportTx is an autogenerated mock type for the ImportTx type
+type ImportTx struct {
+	mock.Mock
+}
+
+// Commit provides a mock function with given fields:
+func (_m *ImportTx) Commit() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OverwriteLink provides a mock function with given fields: link
+func (_m *ImportTx) OverwriteLink(link storage.Link) (int64, error) {
+	ret := _m.Called(link)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(storage.Link) (int64, error)); ok {
+		return rf(link)
+	}
+	if rf, ok := ret.Get(0).(func(storage.Link) int64); ok {
+		r0 = rf(link)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(storage.Link) error); ok {
+		r1 = rf(link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Rollback provides a mock function with given fields:
+func (_m *ImportTx) Rollback() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveLink provides a mock function with given fields: link
+func (_m *ImportTx) SaveLink(link storage.Link) (int64, error) {
+	ret := _m.Called(link)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(storage.Link) (int64, error)); ok {
+		return rf(link)
+	}
+	if rf, ok := ret.Get(0).(func(storage.Link) int64); ok {
+		r0 = rf(link)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(storage.Link) error); ok {
+		r1 = rf(link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewImportTx interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewImportTx creates a new instance of ImportTx. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewImportTx(t mockConstructorTestingTNewImportTx) *ImportTx {
+	mock := &ImportTx{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
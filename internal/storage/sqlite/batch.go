@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"fmt"
+	"link-shortener/internal/storage"
+)
+
+// SaveURLs stores links in a single transaction and returns the outcome of
+// each one, in order. A failing link does not stop the others from being
+// tried, so that callers get all conflicts at once. If atomic is set and any
+// link failed, the transaction is rolled back and no IDs are returned.
+func (s *Storage) SaveURLs(links []storage.Link, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.sqlite.SaveURLs"
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]storage.SaveResult, len(links))
+	failed := false
+
+	for i, link := range links {
+		results[i].ID, err = insertLink(tx, link)
+		if err != nil {
+			results[i].Err = fmt.Errorf("%s: %w", op, err)
+			failed = true
+		}
+	}
+
+	if failed && atomic {
+		for i := range results {
+			results[i].ID = 0
+		}
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,50 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestSaveURLsUndoesFailedLinks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme"})
+	require.NoError(t, err)
+
+	// Tagging comes after the link is inserted and counted.
+	_, err = s.DB.Exec(`CREATE TRIGGER fail_tag BEFORE INSERT ON link_tags WHEN NEW.tag = 'boom'
+		BEGIN SELECT RAISE(ABORT, 'boom'); END`)
+	require.NoError(t, err)
+
+	results, err := s.SaveURLs([]storage.Link{
+		{Alias: "acme~a", URL: "https://example.com/a", WorkspaceID: wsID, Tags: []string{"boom"}},
+		{Alias: "acme~b", URL: "https://example.com/b", WorkspaceID: wsID, Tags: []string{"ok"}},
+	}, false)
+	require.NoError(t, err)
+	require.Error(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+
+	_, err = s.GetURL("acme~a")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	links, err := s.ListLinks(storage.ListOptions{WorkspaceID: wsID})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, "acme~b", links[0].Alias)
+	require.Equal(t, []string{"ok"}, links[0].Tags)
+
+	usage, err := s.WorkspaceUsage(wsID)
+	require.NoError(t, err)
+	require.Equal(t, storage.WorkspaceUsage{Links: 1, CreatedToday: 1}, usage)
+
+	found, err := s.SearchLinks(storage.SearchOptions{Query: "acme"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+}
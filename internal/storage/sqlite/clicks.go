@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"link-shortener/internal/storage"
+	"time"
+)
+
+// statsDays is how many days of daily click counts GetStats returns.
+const statsDays = 30
+
+// sqliteTimeLayout is the layout times are written in (see dsn).
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+func (s *Storage) RecordClick(click storage.Click) error {
+	const op = "storage.sqlite.RecordClick"
+
+	_, err := s.DB.Exec(
+		"INSERT INTO clicks (link_id, clicked_at, referer, user_agent, variant, country) VALUES (?, ?, ?, ?, ?, ?)",
+		click.LinkID, click.At.UTC(), click.Referer, click.UserAgent, click.Variant, click.Country,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConsumeClick uses up one click of a limited link, or fails with
+// storage.ErrLinkExhausted if none are left. The check and the increment are
+// a single statement, so concurrent redirects cannot exceed the limit.
+func (s *Storage) ConsumeClick(linkID int64) error {
+	const op = "storage.sqlite.ConsumeClick"
+
+	res, err := s.DB.Exec(
+		"UPDATE links SET used_clicks = used_clicks + 1 WHERE id = ? AND max_clicks > 0 AND used_clicks < max_clicks",
+		linkID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrLinkExhausted)
+	}
+
+	return nil
+}
+
+// GetStats returns the clicks of the link with alias on domain.
+func (s *Storage) GetStats(domain, alias string) (storage.Stats, error) {
+	const op = "storage.sqlite.GetStats"
+
+	var linkID int64
+	err := s.DB.QueryRow("SELECT id FROM links WHERE domain = ? AND alias = ?", domain, alias).Scan(&linkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Stats{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	stats, err := s.clickStats("link_id = ?", linkID)
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// clickStats sums up the clicks matching filter, a condition on the clicks
+// table with args for its placeholders.
+func (s *Storage) clickStats(filter string, args ...any) (storage.Stats, error) {
+	var (
+		stats     storage.Stats
+		lastClick sql.NullString
+	)
+
+	err := s.DB.QueryRow("SELECT COUNT(*), MAX(clicked_at) FROM clicks WHERE "+filter, args...).
+		Scan(&stats.Clicks, &lastClick)
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("execute statement: %w", err)
+	}
+
+	if lastClick.Valid {
+		t, err := time.Parse(sqliteTimeLayout, lastClick.String)
+		if err != nil {
+			return storage.Stats{}, fmt.Errorf("parse last click: %w", err)
+		}
+		stats.LastClickAt = &t
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -statsDays)
+
+	rows, err := s.DB.Query(`
+		SELECT date(clicked_at), COUNT(*)
+		FROM clicks
+		WHERE `+filter+` AND clicked_at >= ?
+		GROUP BY date(clicked_at)
+		ORDER BY 1
+	`, append(args, since)...)
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("execute statement: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var day storage.DailyClicks
+		if err := rows.Scan(&day.Date, &day.Clicks); err != nil {
+			return storage.Stats{}, fmt.Errorf("scan row: %w", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.Stats{}, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	variants, err := s.clicksBy("variant", filter, args...)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	for _, g := range variants {
+		stats.Variants = append(stats.Variants, storage.VariantClicks{URL: g.value, Clicks: g.clicks})
+	}
+
+	countries, err := s.clicksBy("country", filter, args...)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	for _, g := range countries {
+		stats.Countries = append(stats.Countries, storage.CountryClicks{Country: g.value, Clicks: g.clicks})
+	}
+
+	return stats, nil
+}
+
+type clickGroup struct {
+	value  string
+	clicks int64
+}
+
+// clicksBy counts the clicks matching filter (see clickStats) per value of
+// column, over all time and most clicked first. Clicks without a value are
+// left out.
+func (s *Storage) clicksBy(column, filter string, args ...any) ([]clickGroup, error) {
+	rows, err := s.DB.Query(`
+		SELECT `+column+`, COUNT(*)
+		FROM clicks
+		WHERE `+filter+` AND `+column+` != ''
+		GROUP BY `+column+`
+		ORDER BY 2 DESC, 1
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("execute statement: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var res []clickGroup
+	for rows.Next() {
+		var g clickGroup
+		if err := rows.Scan(&g.value, &g.clicks); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		res = append(res, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return res, nil
+}
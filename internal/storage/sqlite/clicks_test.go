@@ -0,0 +1,106 @@
+package sqlite_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestConsumeClickIsAtomic(t *testing.T) {
+	const (
+		maxClicks = 10
+		attempts  = 50
+	)
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// Separate handles, as if several processes shared the file.
+	var handles []*sqlite.Storage
+	for i := 0; i < 3; i++ {
+		s, err := sqlite.New(path)
+		require.NoError(t, err)
+		defer func() { _ = s.DB.Close() }()
+		handles = append(handles, s)
+	}
+
+	id, err := handles[0].SaveLink(storage.Link{Alias: "once", URL: "https://google.com", MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		consumed  atomic.Int64
+		exhausted atomic.Int64
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(s *sqlite.Storage) {
+			defer wg.Done()
+
+			err := s.ConsumeClick(id)
+			switch {
+			case err == nil:
+				consumed.Add(1)
+			case errors.Is(err, storage.ErrLinkExhausted):
+				exhausted.Add(1)
+			default:
+				t.Error(err)
+			}
+		}(handles[i%len(handles)])
+	}
+	wg.Wait()
+
+	require.EqualValues(t, maxClicks, consumed.Load())
+	require.EqualValues(t, attempts-maxClicks, exhausted.Load())
+
+	link, err := handles[0].GetLink("", "once")
+	require.NoError(t, err)
+	require.True(t, link.Exhausted())
+	require.Zero(t, *link.Public().RemainingClicks)
+}
+
+func TestStatsGroupClicks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	id, err := s.SaveLink(storage.Link{
+		Alias: "ab",
+		URL:   "https://example.com",
+		Destinations: []storage.Destination{
+			{URL: "https://example.com/a", Weight: 1},
+			{URL: "https://example.com/b", Weight: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	clicks := []storage.Click{
+		{Variant: "https://example.com/a", Country: "DE"},
+		{Variant: "https://example.com/b", Country: "GB"},
+		{Variant: "https://example.com/b", Country: "DE"},
+		{},
+	}
+	for _, click := range clicks {
+		click.LinkID, click.At = id, time.Now()
+		require.NoError(t, s.RecordClick(click))
+	}
+
+	stats, err := s.GetStats("", "ab")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, stats.Clicks)
+	require.Equal(t, []storage.VariantClicks{
+		{URL: "https://example.com/b", Clicks: 2},
+		{URL: "https://example.com/a", Clicks: 1},
+	}, stats.Variants)
+	require.Equal(t, []storage.CountryClicks{
+		{Country: "DE", Clicks: 2},
+		{Country: "GB", Clicks: 1},
+	}, stats.Countries)
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"fmt"
+	"link-shortener/internal/storage"
+)
+
+// RecordHealth stores the outcome of a check. Failed checks of the same URL
+// are counted, and once there are brokenAfter of them in a row the link is
+// broken until a check succeeds. A new URL starts over.
+func (s *Storage) RecordHealth(check storage.HealthCheck, brokenAfter int) error {
+	const op = "storage.sqlite.RecordHealth"
+
+	failures := 1
+	var brokenSince any
+	if check.OK {
+		failures = 0
+	} else if brokenAfter <= 1 {
+		brokenSince = check.At.UTC()
+	}
+
+	// The SET expressions see the row as it was before the update.
+	_, err := s.DB.Exec(`
+		INSERT INTO link_health (link_id, url, status_code, latency_ms, error, checked_at, failures, broken_since)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (link_id) DO UPDATE SET
+		    failures = CASE
+		        WHEN excluded.failures = 0 THEN 0
+		        WHEN link_health.url = excluded.url THEN link_health.failures + 1
+		        ELSE 1
+		    END,
+		    broken_since = CASE
+		        WHEN excluded.failures = 0 THEN NULL
+		        WHEN link_health.url = excluded.url AND link_health.broken_since IS NOT NULL THEN link_health.broken_since
+		        WHEN link_health.url = excluded.url AND link_health.failures + 1 >= ? THEN excluded.checked_at
+		        ELSE excluded.broken_since
+		    END,
+		    url = excluded.url,
+		    status_code = excluded.status_code,
+		    latency_ms = excluded.latency_ms,
+		    error = excluded.error,
+		    checked_at = excluded.checked_at
+	`,
+		check.LinkID, check.URL, check.StatusCode, check.Latency.Milliseconds(), check.Error,
+		check.At.UTC(), failures, brokenSince, brokenAfter,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// HealthReport returns the health of the checked links, broken ones first
+// and longest broken first among them.
+func (s *Storage) HealthReport(opts storage.HealthOptions) (storage.HealthReport, error) {
+	const op = "storage.sqlite.HealthReport"
+
+	var report storage.HealthReport
+
+	err := s.DB.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE failures > 0), COUNT(broken_since)
+		FROM link_health
+	`).Scan(&report.Checked, &report.Failing, &report.Broken)
+	if err != nil {
+		return storage.HealthReport{}, fmt.Errorf("%s: count: %w", op, err)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as "no limit"
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT h.link_id, l.domain, l.alias, h.url, h.status_code, h.latency_ms, h.error,
+		       h.checked_at, h.failures, h.broken_since
+		FROM link_health h JOIN links l ON l.id = h.link_id
+		WHERE NOT ? OR h.broken_since IS NOT NULL
+		ORDER BY h.broken_since IS NULL, h.broken_since, h.failures DESC, h.link_id
+		LIMIT ? OFFSET ?
+	`, opts.BrokenOnly, limit, opts.Offset)
+	if err != nil {
+		return storage.HealthReport{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	report.Links = []storage.Health{}
+	for rows.Next() {
+		var h storage.Health
+		err := rows.Scan(&h.LinkID, &h.Domain, &h.Alias, &h.URL, &h.StatusCode, &h.LatencyMS, &h.Error,
+			&h.CheckedAt, &h.Failures, &h.BrokenSince)
+		if err != nil {
+			return storage.HealthReport{}, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		// The driver returns a fixed +00:00 zone; callers expect time.UTC.
+		h.CheckedAt = h.CheckedAt.UTC()
+		if h.BrokenSince != nil {
+			t := h.BrokenSince.UTC()
+			h.BrokenSince = &t
+		}
+
+		report.Links = append(report.Links, h)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.HealthReport{}, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return report, nil
+}
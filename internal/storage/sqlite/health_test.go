@@ -0,0 +1,76 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestRecordHealth(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	id, err := s.SaveURL("https://example.com/gone", "gone")
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com", "fine")
+	require.NoError(t, err)
+
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := func(url string, n int, ok bool) {
+		check := storage.HealthCheck{LinkID: id, URL: url, At: start.Add(time.Duration(n) * time.Hour), OK: ok}
+		if !ok {
+			check.StatusCode = 404
+		}
+		require.NoError(t, s.RecordHealth(check, 3))
+	}
+	health := func() storage.Health {
+		report, err := s.HealthReport(storage.HealthOptions{})
+		require.NoError(t, err)
+		require.Len(t, report.Links, 1)
+		return report.Links[0]
+	}
+
+	record("https://example.com/gone", 0, false)
+	record("https://example.com/gone", 1, false)
+	require.Equal(t, 2, health().Failures)
+	require.Nil(t, health().BrokenSince)
+
+	record("https://example.com/gone", 2, false)
+	record("https://example.com/gone", 3, false)
+	h := health()
+	require.Equal(t, 4, h.Failures)
+	require.NotNil(t, h.BrokenSince)
+	require.Equal(t, start.Add(2*time.Hour), *h.BrokenSince, "broken since the third failure")
+	require.Equal(t, start.Add(3*time.Hour), h.CheckedAt)
+	require.Equal(t, "gone", h.Alias)
+
+	report, err := s.HealthReport(storage.HealthOptions{BrokenOnly: true})
+	require.NoError(t, err)
+	require.Equal(t, storage.HealthReport{Checked: 1, Failing: 1, Broken: 1, Links: []storage.Health{h}}, report)
+
+	// Another URL is another destination.
+	record("https://example.com/moved", 4, false)
+	require.Equal(t, 1, health().Failures)
+	require.Nil(t, health().BrokenSince)
+
+	record("https://example.com/moved", 5, true)
+	h = health()
+	require.Zero(t, h.Failures)
+	require.Zero(t, h.StatusCode)
+
+	report, err = s.HealthReport(storage.HealthOptions{BrokenOnly: true})
+	require.NoError(t, err)
+	require.Empty(t, report.Links)
+
+	// Deleting the link deletes its health.
+	require.NoError(t, s.DeleteURL(id))
+	report, err = s.HealthReport(storage.HealthOptions{})
+	require.NoError(t, err)
+	require.Zero(t, report.Checked)
+}
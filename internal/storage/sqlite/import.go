@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"link-shortener/internal/storage"
+)
+
+// ImportTx writes imported links in a single transaction, so that an
+// aborted import leaves the storage untouched.
+type ImportTx struct {
+	tx *sql.Tx
+}
+
+func (s *Storage) BeginImport() (storage.ImportTx, error) {
+	const op = "storage.sqlite.BeginImport"
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ImportTx{tx: tx}, nil
+}
+
+// SaveLink inserts a new link. It fails with storage.ErrURLExist if the
+// alias is taken. Imported links do not count against workspace quotas, as
+// they are restored rather than created.
+func (t *ImportTx) SaveLink(link storage.Link) (int64, error) {
+	const op = "storage.sqlite.ImportTx.SaveLink"
+
+	res, err := t.tx.Exec(insertLinkQuery, insertValues(link)...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExist)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id %w", op, err)
+	}
+
+	if err := setTags(t.tx, id, link.Tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := indexLink(t.tx, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// OverwriteLink replaces the link stored under link.Alias on link.Domain and
+// returns its id.
+func (t *ImportTx) OverwriteLink(link storage.Link) (int64, error) {
+	const op = "storage.sqlite.ImportTx.OverwriteLink"
+
+	var id int64
+	err := t.tx.QueryRow("UPDATE links SET "+setInsertColumns+" WHERE domain = ? AND alias = ? RETURNING id",
+		append(insertValues(link), link.Domain, link.Alias)...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setTags(t.tx, id, link.Tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := indexLink(t.tx, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (t *ImportTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *ImportTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations upgrade the schema created by New. migrations[i] moves a
+// database from user_version i to i+1, so entries must only ever be
+// appended.
+var migrations = []string{
+	// 1: per-link redirect status, 0 means the server default.
+	`ALTER TABLE links ADD COLUMN redirect_status INTEGER NOT NULL DEFAULT 0`,
+	// 2: bcrypt hash of the link password, empty if there is none.
+	`ALTER TABLE links ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+	// 3: click limit and the number of clicks used against it.
+	`ALTER TABLE links ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
+	 ALTER TABLE links ADD COLUMN used_clicks INTEGER NOT NULL DEFAULT 0`,
+	// 4: activation window and the URL used outside of it.
+	`ALTER TABLE links ADD COLUMN active_from DATETIME;
+	 ALTER TABLE links ADD COLUMN active_until DATETIME;
+	 ALTER TABLE links ADD COLUMN fallback_url TEXT NOT NULL DEFAULT ''`,
+	// 5: platform targets as a JSON array, empty if there are none.
+	`ALTER TABLE links ADD COLUMN targets TEXT NOT NULL DEFAULT ''`,
+	// 6: weighted destinations as a JSON array, and the one each click was
+	// sent to.
+	`ALTER TABLE links ADD COLUMN destinations TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE clicks ADD COLUMN variant TEXT NOT NULL DEFAULT ''`,
+	// 7: country targets as a JSON array, and the country of each click.
+	`ALTER TABLE links ADD COLUMN geo_targets TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE clicks ADD COLUMN country TEXT NOT NULL DEFAULT ''`,
+	// 8: query parameters added to redirects, as a JSON array, and whether
+	// the short URL's own query is forwarded.
+	`ALTER TABLE links ADD COLUMN query_params TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE links ADD COLUMN query_passthrough INTEGER NOT NULL DEFAULT 0`,
+	// 9: whether the link matches paths below its alias.
+	`ALTER TABLE links ADD COLUMN prefix INTEGER NOT NULL DEFAULT 0`,
+	// 10: whether the link warns before leaving for an external domain.
+	`ALTER TABLE links ADD COLUMN interstitial INTEGER NOT NULL DEFAULT 0`,
+	// 11: the latest health check of each link, with the number of checks
+	// that failed in a row and since when the link counts as broken.
+	`CREATE TABLE link_health (
+	    link_id INTEGER PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+	    url TEXT NOT NULL,
+	    status_code INTEGER NOT NULL DEFAULT 0,
+	    latency_ms INTEGER NOT NULL DEFAULT 0,
+	    error TEXT NOT NULL DEFAULT '',
+	    checked_at DATETIME NOT NULL,
+	    failures INTEGER NOT NULL DEFAULT 0,
+	    broken_since DATETIME
+	 );
+	 CREATE INDEX idx_link_health_broken ON link_health(broken_since)`,
+	// 12: title, description and image of the destination page.
+	`ALTER TABLE links ADD COLUMN title TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE links ADD COLUMN description TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE links ADD COLUMN image TEXT NOT NULL DEFAULT ''`,
+	// 13: JSON object of the title, description and image shown when the
+	// link is unfurled, empty if the fetched ones are used.
+	`ALTER TABLE links ADD COLUMN unfurl TEXT NOT NULL DEFAULT ''`,
+	// 14: the short domain of each link, empty for the default one. Aliases
+	// are unique per domain; the table is rebuilt as SQLite cannot drop the
+	// UNIQUE constraint of a column.
+	`CREATE TABLE links_new (
+	    id INTEGER PRIMARY KEY,
+	    domain TEXT NOT NULL DEFAULT '',
+	    alias TEXT NOT NULL,
+	    url TEXT NOT NULL,
+	    redirect_status INTEGER NOT NULL DEFAULT 0,
+	    password_hash TEXT NOT NULL DEFAULT '',
+	    max_clicks INTEGER NOT NULL DEFAULT 0,
+	    used_clicks INTEGER NOT NULL DEFAULT 0,
+	    active_from DATETIME,
+	    active_until DATETIME,
+	    fallback_url TEXT NOT NULL DEFAULT '',
+	    targets TEXT NOT NULL DEFAULT '',
+	    destinations TEXT NOT NULL DEFAULT '',
+	    geo_targets TEXT NOT NULL DEFAULT '',
+	    query_params TEXT NOT NULL DEFAULT '',
+	    query_passthrough INTEGER NOT NULL DEFAULT 0,
+	    prefix INTEGER NOT NULL DEFAULT 0,
+	    interstitial INTEGER NOT NULL DEFAULT 0,
+	    title TEXT NOT NULL DEFAULT '',
+	    description TEXT NOT NULL DEFAULT '',
+	    image TEXT NOT NULL DEFAULT '',
+	    unfurl TEXT NOT NULL DEFAULT '',
+	    UNIQUE (domain, alias)
+	 );
+	 INSERT INTO links_new (id, alias, url, redirect_status, password_hash, max_clicks, used_clicks,
+	     active_from, active_until, fallback_url, targets, destinations, geo_targets, query_params,
+	     query_passthrough, prefix, interstitial, title, description, image, unfurl)
+	 SELECT id, alias, url, redirect_status, password_hash, max_clicks, used_clicks,
+	     active_from, active_until, fallback_url, targets, destinations, geo_targets, query_params,
+	     query_passthrough, prefix, interstitial, title, description, image, unfurl
+	 FROM links;
+	 DROP TABLE links;
+	 ALTER TABLE links_new RENAME TO links;
+	 CREATE INDEX idx_alias ON links(alias)`,
+	// 15: workspaces with their quotas and API keys, the workspace owning
+	// each link (0 for none) and the links each workspace created per day.
+	`CREATE TABLE workspaces (
+	    id INTEGER PRIMARY KEY,
+	    name TEXT NOT NULL UNIQUE,
+	    max_links INTEGER NOT NULL DEFAULT 0,
+	    max_daily_creates INTEGER NOT NULL DEFAULT 0
+	 );
+	 CREATE TABLE api_keys (
+	    id INTEGER PRIMARY KEY,
+	    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	    name TEXT NOT NULL DEFAULT '',
+	    hash TEXT NOT NULL UNIQUE,
+	    created_at DATETIME NOT NULL
+	 );
+	 CREATE TABLE workspace_creates (
+	    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+	    day TEXT NOT NULL,
+	    count INTEGER NOT NULL DEFAULT 0,
+	    PRIMARY KEY (workspace_id, day)
+	 );
+	 ALTER TABLE links ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+	 CREATE INDEX idx_links_workspace ON links(workspace_id)`,
+	// 16: the folder of each link, empty for none, and the tags of links.
+	`ALTER TABLE links ADD COLUMN folder TEXT NOT NULL DEFAULT '';
+	 CREATE INDEX idx_links_folder ON links(folder);
+	 CREATE TABLE link_tags (
+	    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+	    tag TEXT NOT NULL,
+	    PRIMARY KEY (link_id, tag)
+	 );
+	 CREATE INDEX idx_link_tags_tag ON link_tags(tag)`,
+	// 17: full-text index of the alias, destination, title and tags of each
+	// link, under the id of the link. Words are split as search.Terms
+	// splits queries, so diacritics are kept.
+	`CREATE VIRTUAL TABLE links_fts USING fts5(
+	    alias, url, title, tags,
+	    tokenize = 'unicode61 remove_diacritics 0'
+	 );
+	 INSERT INTO links_fts (rowid, alias, url, title, tags)
+	 SELECT id, alias, url, title, COALESCE((SELECT group_concat(tag, ' ') FROM link_tags WHERE link_id = links.id), '')
+	 FROM links`,
+}
+
+// schemaVersion is the user_version of a fully migrated database.
+var schemaVersion = len(migrations)
+
+// migrate applies the migrations db has not seen yet, each in its own
+// transaction.
+func migrate(db *sql.DB) error {
+	version, err := userVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("schema version %d is newer than supported version %d", version, schemaVersion)
+	}
+	if version == schemaVersion {
+		return nil
+	}
+
+	// Migrations that rebuild a table drop the old one, which must not
+	// cascade to the rows referencing it. Foreign keys cannot be turned off
+	// inside a transaction, so they are turned off around the migrations,
+	// on the one connection that runs them.
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON") }()
+
+	for ; version < schemaVersion; version++ {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		// PRAGMA does not take bound parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+	}
+
+	return nil
+}
+
+func userVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"link-shortener/internal/lib/search"
+	"link-shortener/internal/storage"
+	"strings"
+)
+
+// indexLink writes the alias, destination, title and tags of the link with
+// linkID to the full-text index within tx, replacing what was indexed for
+// it before. It must run after the link and its tags are written.
+func indexLink(tx *sql.Tx, linkID int64) error {
+	if _, err := tx.Exec("DELETE FROM links_fts WHERE rowid = ?", linkID); err != nil {
+		return fmt.Errorf("unindex link: %w", err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO links_fts (rowid, alias, url, title, tags)
+		SELECT id, alias, url, title, COALESCE((SELECT group_concat(tag, ' ') FROM link_tags WHERE link_id = links.id), '')
+		FROM links WHERE id = ?`,
+		linkID,
+	)
+	if err != nil {
+		return fmt.Errorf("index link: %w", err)
+	}
+
+	return nil
+}
+
+// ftsQuery turns terms into an FTS5 query matching the links in which each
+// term starts a word. Terms only hold letters and digits, so quoting them
+// is enough to keep FTS5 operators out.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SearchLinks returns the links matching opts.Query, ranked by BM25 with
+// the columns weighted by search.WeightAlias and the like. A query without
+// any words matches nothing.
+func (s *Storage) SearchLinks(opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	const op = "storage.sqlite.SearchLinks"
+
+	terms := search.Terms(opts.Query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	// bm25 is lower for better matches. Joining links leaves out index
+	// entries of links deleted behind the storage's back.
+	rows, err := s.DB.Query(
+		"SELECT "+linkColumns+", m.score FROM links JOIN ("+
+			"SELECT rowid, -bm25(links_fts, ?, ?, ?, ?) AS score FROM links_fts WHERE links_fts MATCH ?"+
+			") m ON m.rowid = links.id WHERE ? = 0 OR workspace_id = ? "+
+			"ORDER BY m.score DESC, links.id LIMIT ? OFFSET ?",
+		search.WeightAlias, search.WeightURL, search.WeightTitle, search.WeightTags, ftsQuery(terms),
+		opts.WorkspaceID, opts.WorkspaceID, limit, opts.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []storage.SearchResult
+	for rows.Next() {
+		var score float64
+		link, err := scanLink(scoredRow{rows, &score})
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		results = append(results, storage.SearchResult{
+			Link:       link,
+			Score:      score,
+			Highlights: search.Highlights(link, terms),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// scoredRow scans the score following the link columns of a row.
+type scoredRow struct {
+	row   rowScanner
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...any) error {
+	return r.row.Scan(append(dest, r.score)...)
+}
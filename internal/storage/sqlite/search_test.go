@@ -0,0 +1,65 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestSearchLinks(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme"})
+	require.NoError(t, err)
+
+	launch, err := s.SaveLink(storage.Link{Alias: "launch", URL: "https://example.com/plan", Tags: []string{"q3"}})
+	require.NoError(t, err)
+	docs, err := s.SaveLink(storage.Link{Alias: "docs", URL: "https://example.com/launch"})
+	require.NoError(t, err)
+	own, err := s.SaveLink(storage.Link{Alias: "acme~blog", URL: "https://blog.acme.com", WorkspaceID: wsID})
+	require.NoError(t, err)
+
+	found := func(opts storage.SearchOptions) []int64 {
+		results, err := s.SearchLinks(opts)
+		require.NoError(t, err)
+		var ids []int64
+		for _, res := range results {
+			ids = append(ids, res.Link.ID)
+		}
+		return ids
+	}
+
+	// A match in the alias ranks above one in the URL.
+	require.Equal(t, []int64{launch, docs}, found(storage.SearchOptions{Query: "laun"}))
+	require.Equal(t, []int64{docs}, found(storage.SearchOptions{Query: "laun", Limit: 1, Offset: 1}))
+	require.Equal(t, []int64{launch}, found(storage.SearchOptions{Query: "launch Q3"}))
+	require.Equal(t, []int64{own}, found(storage.SearchOptions{Query: "blog", WorkspaceID: wsID}))
+	require.Empty(t, found(storage.SearchOptions{Query: "launch", WorkspaceID: wsID}))
+	require.Empty(t, found(storage.SearchOptions{Query: `"*" -`}))
+
+	results, err := s.SearchLinks(storage.SearchOptions{Query: "launch"})
+	require.NoError(t, err)
+	require.Equal(t, storage.Highlights{Alias: "<mark>launch</mark>"}, results[0].Highlights)
+	require.Equal(t, "https://example.com/<mark>launch</mark>", results[1].Highlights.URL)
+	require.Greater(t, results[0].Score, results[1].Score)
+
+	// Titles fetched later, updates and deletes reach the index.
+	require.NoError(t, s.SetLinkMeta(docs, "https://example.com/launch", storage.PageMeta{Title: "Release notes"}))
+	require.Equal(t, []int64{docs}, found(storage.SearchOptions{Query: "release"}))
+
+	link, err := s.GetLinkByID(launch)
+	require.NoError(t, err)
+	link.Alias, link.Tags = "kickoff", []string{"q4"}
+	require.NoError(t, s.UpdateLink(link))
+	require.Equal(t, []int64{docs}, found(storage.SearchOptions{Query: "launch"}))
+	require.Equal(t, []int64{launch}, found(storage.SearchOptions{Query: "kick q4"}))
+
+	require.NoError(t, s.DeleteURL(docs))
+	require.Empty(t, found(storage.SearchOptions{Query: "release"}))
+}
@@ -2,10 +2,15 @@ package sqlite
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"link-shortener/internal/storage"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"os"
+	"strings"
+	"time"
 )
 
 type Storage struct {
@@ -15,7 +20,7 @@ type Storage struct {
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite", storagePath)
+	db, err := sql.Open("sqlite", dsn(storagePath))
 	if err != nil {
 		return nil, fmt.Errorf("%s (opening database): %w", op, err)
 	}
@@ -39,33 +44,210 @@ func New(storagePath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s (creating index): %w", op, err)
 	}
 
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS clicks (
+		    id INTEGER PRIMARY KEY,
+		    link_id INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+		    clicked_at DATETIME NOT NULL,
+		    referer TEXT NOT NULL DEFAULT '',
+		    user_agent TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_clicks_link ON clicks(link_id, clicked_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s (creating clicks table): %w", op, err)
+	}
+
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("%s (migrating schema): %w", op, err)
+	}
+
 	return &Storage{DB: db}, nil
 }
 
+// dsn adds the connection parameters the storage relies on to the path:
+// times are written in SQLite's own format so that date functions work on
+// them, foreign keys are enforced, and concurrent writers wait for each
+// other instead of failing with SQLITE_BUSY.
+func dsn(storagePath string) string {
+	sep := "?"
+	if strings.Contains(storagePath, "?") {
+		sep = "&"
+	}
+	return storagePath + sep + "_time_format=sqlite&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+// linkColumns are the columns scanLink expects, in order.
+const linkColumns = "id, domain, workspace_id, alias, url, redirect_status, password_hash, max_clicks, used_clicks, " +
+	"active_from, active_until, fallback_url, targets, geo_targets, destinations, " +
+	"query_params, query_passthrough, prefix, interstitial, title, description, image, unfurl, folder, " +
+	"(SELECT json_group_array(tag) FROM (SELECT tag FROM link_tags WHERE link_id = links.id ORDER BY tag))"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row rowScanner) (storage.Link, error) {
+	var link storage.Link
+	var targets, geoTargets, destinations, queryParams, unfurl, tags string
+	err := row.Scan(
+		&link.ID, &link.Domain, &link.WorkspaceID, &link.Alias, &link.URL, &link.RedirectStatus, &link.PasswordHash,
+		&link.MaxClicks, &link.UsedClicks, &link.ActiveFrom, &link.ActiveUntil, &link.FallbackURL,
+		&targets, &geoTargets, &destinations, &queryParams, &link.QueryPassthrough,
+		&link.Prefix, &link.Interstitial, &link.Title, &link.Description, &link.Image,
+		&unfurl, &link.Folder, &tags,
+	)
+	if err != nil {
+		return link, err
+	}
+
+	// The driver returns a fixed +00:00 zone; callers expect time.UTC.
+	for _, t := range []*time.Time{link.ActiveFrom, link.ActiveUntil} {
+		if t != nil {
+			*t = t.UTC()
+		}
+	}
+
+	if err := decodeList(targets, &link.Targets); err != nil {
+		return link, fmt.Errorf("decode targets: %w", err)
+	}
+	if err := decodeList(geoTargets, &link.GeoTargets); err != nil {
+		return link, fmt.Errorf("decode geo targets: %w", err)
+	}
+	if err := decodeList(destinations, &link.Destinations); err != nil {
+		return link, fmt.Errorf("decode destinations: %w", err)
+	}
+	if err := decodeList(queryParams, &link.QueryParams); err != nil {
+		return link, fmt.Errorf("decode query params: %w", err)
+	}
+	if err := decodeObject(unfurl, &link.Unfurl); err != nil {
+		return link, fmt.Errorf("decode unfurl: %w", err)
+	}
+	if err := decodeList(tags, &link.Tags); err != nil {
+		return link, fmt.Errorf("decode tags: %w", err)
+	}
+	if len(link.Tags) == 0 {
+		link.Tags = nil
+	}
+
+	return link, nil
+}
+
+// linkWriteColumns are the columns written when a link is saved or updated,
+// in the order of linkValues.
+var linkWriteColumns = []string{
+	"domain", "alias", "url", "redirect_status", "password_hash", "max_clicks",
+	"active_from", "active_until", "fallback_url", "targets", "geo_targets", "destinations",
+	"query_params", "query_passthrough", "prefix", "interstitial", "unfurl", "folder",
+}
+
+func linkValues(link storage.Link) []any {
+	return []any{
+		link.Domain, link.Alias, link.URL, link.RedirectStatus, link.PasswordHash, link.MaxClicks,
+		utcTime(link.ActiveFrom), utcTime(link.ActiveUntil), link.FallbackURL,
+		jsonList(link.Targets), jsonList(link.GeoTargets), jsonList(link.Destinations),
+		jsonList(link.QueryParams), link.QueryPassthrough, link.Prefix,
+		link.Interstitial, jsonObject(link.Unfurl), link.Folder,
+	}
+}
+
+// jsonList encodes a list for a JSON column, empty if the list is.
+func jsonList[T any](list []T) string {
+	if len(list) == 0 {
+		return ""
+	}
+	// Encoding a slice of plain structs cannot fail.
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// decodeList decodes a JSON column written by jsonList into list.
+func decodeList[T any](column string, list *[]T) error {
+	if column == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(column), list)
+}
+
+// jsonObject encodes v for a JSON column, empty if v is nil.
+func jsonObject[T any](v *T) string {
+	if v == nil {
+		return ""
+	}
+	// Encoding a plain struct cannot fail.
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// decodeObject decodes a JSON column written by jsonObject into v, which is
+// left nil if the column is empty.
+func decodeObject[T any](column string, v **T) error {
+	if column == "" {
+		return nil
+	}
+	*v = new(T)
+	return json.Unmarshal([]byte(column), *v)
+}
+
+// utcTime returns t in UTC, or nil (NULL) if t is nil.
+func utcTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// Inserts and imports also write the click counter, the page metadata and
+// the owning workspace. UpdateLink leaves them alone so that an edit cannot
+// undo clicks made, or metadata fetched, in the meantime, nor move a link
+// to another workspace.
+var (
+	insertColumns   = append(linkWriteColumns[:len(linkWriteColumns):len(linkWriteColumns)], "used_clicks", "title", "description", "image", "workspace_id")
+	insertLinkQuery = "INSERT INTO links (" + strings.Join(insertColumns, ", ") + ") VALUES (" +
+		strings.Repeat("?, ", len(insertColumns)-1) + "?)"
+	setLinkColumns   = strings.Join(linkWriteColumns, " = ?, ") + " = ?"
+	setInsertColumns = strings.Join(insertColumns, " = ?, ") + " = ?"
+)
+
+func insertValues(link storage.Link) []any {
+	return append(linkValues(link), link.UsedClicks, link.Title, link.Description, link.Image, link.WorkspaceID)
+}
+
+// SaveURL stores a link with no settings besides its destination.
 func (s *Storage) SaveURL(URL string, alias string) (int64, error) {
+	return s.SaveLink(storage.Link{Alias: alias, URL: URL})
+}
+
+// SaveLink stores a new link. It fails with storage.ErrURLExist if the
+// alias is taken on the link's domain, and with storage.ErrLinkQuota or
+// storage.ErrDailyQuota if the link's workspace has reached a quota.
+func (s *Storage) SaveLink(link storage.Link) (int64, error) {
 	const op = "storage.sqlite.SaveLink"
-	stmt, err := s.DB.Prepare("INSERT INTO links (url, alias) VALUES (?, ?)")
+
+	tx, err := s.DB.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExist)
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	res, err := stmt.Exec(URL, alias)
+	id, err := insertLink(tx, link)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("%s: failed to get id %w", op, err)
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit: %w", op, err)
 	}
 
-	return int64(id), nil
+	return id, nil
 }
 
+// GetURL returns the destination of the link with alias on the default
+// domain.
 func (s *Storage) GetURL(alias string) (string, error) {
 	const op = "storage.sqlite.GetLink"
 
-	stmt, err := s.DB.Prepare("SELECT url FROM links WHERE alias = ?")
+	stmt, err := s.DB.Prepare("SELECT url FROM links WHERE domain = '' AND alias = ?")
 	if err != nil {
 		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
@@ -82,19 +264,212 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return resUrl, nil
 }
 
+// DeleteURL deletes the link with urlID, and drops it from the search
+// index.
 func (s *Storage) DeleteURL(urlID int64) error {
 	const op = "storage.sqlite.DeleteURL"
 
-	stmt, err := s.DB.Prepare("DELETE FROM links WHERE id = ?")
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("DELETE FROM links WHERE id = ?", urlID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	res, err := stmt.Exec(urlID)
+	n, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+
+	if _, err := tx.Exec("DELETE FROM links_fts WHERE rowid = ?", urlID); err != nil {
+		return fmt.Errorf("%s: unindex link: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLink returns the link with alias on domain, which is empty for the
+// default domain.
+func (s *Storage) GetLink(domain, alias string) (storage.Link, error) {
+	const op = "storage.sqlite.GetLink"
+
+	stmt, err := s.DB.Prepare("SELECT " + linkColumns + " FROM links WHERE domain = ? AND alias = ?")
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+
+	link, err := scanLink(stmt.QueryRow(domain, alias))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return link, nil
+}
+
+func (s *Storage) ListLinks(opts storage.ListOptions) ([]storage.Link, error) {
+	const op = "storage.sqlite.ListLinks"
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as "no limit"
+	}
+
+	rows, err := s.DB.Query(
+		"SELECT "+linkColumns+" FROM links WHERE (? = '' OR title LIKE ? ESCAPE '\\') AND (? = 0 OR workspace_id = ?) "+
+			"AND (? = '' OR folder = ?) AND (? = '' OR id IN (SELECT link_id FROM link_tags WHERE tag = ?)) "+
+			"ORDER BY id LIMIT ? OFFSET ?",
+		opts.Title, "%"+escapeLike(opts.Title)+"%", opts.WorkspaceID, opts.WorkspaceID,
+		opts.Folder, opts.Folder, opts.Tag, opts.Tag, limit, opts.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ForEachLink calls fn for every link ordered by id, without loading all of
+// them into memory. Iteration stops at the first error returned by fn.
+func (s *Storage) ForEachLink(fn func(link storage.Link) error) error {
+	const op = "storage.sqlite.ForEachLink"
+
+	rows, err := s.DB.Query("SELECT " + linkColumns + " FROM links ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, for ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SetLinkMeta stores what the page at url says about itself as the
+// metadata of the link with linkID, unless the link has been deleted or
+// leads elsewhere by now. The new title is indexed for search.
+func (s *Storage) SetLinkMeta(linkID int64, url string, meta storage.PageMeta) error {
+	const op = "storage.sqlite.SetLinkMeta"
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(
+		"UPDATE links SET title = ?, description = ?, image = ? WHERE id = ? AND url = ?",
+		meta.Title, meta.Description, meta.Image, linkID, url,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n > 0 {
+		if err := indexLink(tx, linkID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err was caused by a UNIQUE constraint.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func (s *Storage) GetLinkByID(id int64) (storage.Link, error) {
+	const op = "storage.sqlite.GetLinkByID"
+
+	stmt, err := s.DB.Prepare("SELECT " + linkColumns + " FROM links WHERE id = ?")
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+
+	link, err := scanLink(stmt.QueryRow(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Link{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return link, nil
+}
+
+// UpdateLink replaces the settings, including the tags, of the link with
+// link.ID, and reindexes it for search.
+func (s *Storage) UpdateLink(link storage.Link) error {
+	const op = "storage.sqlite.UpdateLink"
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("UPDATE links SET "+setLinkColumns+" WHERE id = ?", append(linkValues(link), link.ID)...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrURLExist)
+		}
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
 	n, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -104,5 +479,122 @@ func (s *Storage) DeleteURL(urlID int64) error {
 		return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
 	}
 
+	if err := setTags(tx, link.ID, link.Tags); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := indexLink(tx, link.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// Backup writes a consistent, compacted copy of the database to path while
+// the storage stays usable. path must not exist yet.
+func (s *Storage) Backup(path string) error {
+	const op = "storage.sqlite.Backup"
+
+	if _, err := s.DB.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// requiredColumns are the columns a database must have to be usable as
+// storage. Anything added later is created by New when the file is opened.
+var requiredColumns = map[string][]string{
+	"links": {"id", "alias", "url"},
+}
+
+// requiredTables are the tables a database must have from the schema version
+// they map to on. Older databases get them from the migrations run by New.
+var requiredTables = map[string]int{
+	"clicks":     0,
+	"workspaces": 15,
+	"link_tags":  16,
+	"links_fts":  17,
+}
+
+// ValidateSnapshot checks that the database file at path is intact and has
+// the schema the storage expects at its schema version, without modifying
+// it. Snapshots of a newer schema than this build knows are rejected; older
+// ones are valid, as New migrates them.
+func ValidateSnapshot(path string) error {
+	const op = "storage.sqlite.ValidateSnapshot"
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var result string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s: integrity check: %w", op, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s: integrity check: %s", op, result)
+	}
+
+	version, err := userVersion(db)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("%s: schema version %d is newer than supported version %d", op, version, schemaVersion)
+	}
+
+	for table, columns := range requiredColumns {
+		rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+		if err != nil {
+			return fmt.Errorf("%s: read schema: %w", op, err)
+		}
+
+		have := make(map[string]bool)
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("%s: read schema: %w", op, err)
+			}
+			have[name] = true
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%s: read schema: %w", op, err)
+		}
+
+		for _, column := range columns {
+			if !have[column] {
+				return fmt.Errorf("%s: table %s: missing column %s", op, table, column)
+			}
+		}
+	}
+
+	for table, since := range requiredTables {
+		if version < since {
+			continue
+		}
+
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("%s: read schema: %w", op, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%s: missing table %s", op, table)
+		}
+	}
+
 	return nil
 }
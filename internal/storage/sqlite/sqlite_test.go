@@ -0,0 +1,197 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestActivationWindowIsStoredInUTC(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	kyiv := time.FixedZone("EET", 2*60*60)
+	from := time.Date(2025, 3, 1, 9, 0, 0, 0, kyiv)
+
+	_, err = s.SaveLink(storage.Link{
+		Alias:       "sale",
+		URL:         "https://shop.example.com/sale",
+		ActiveFrom:  &from,
+		FallbackURL: "https://shop.example.com",
+	})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "sale")
+	require.NoError(t, err)
+
+	require.NotNil(t, link.ActiveFrom)
+	require.Equal(t, time.UTC, link.ActiveFrom.Location())
+	require.True(t, from.Equal(*link.ActiveFrom))
+	require.Nil(t, link.ActiveUntil)
+	require.Equal(t, "https://shop.example.com", link.FallbackURL)
+
+	var raw string
+	require.NoError(t, s.DB.QueryRow("SELECT CAST(active_from AS TEXT) FROM links WHERE alias = 'sale'").Scan(&raw))
+	require.Equal(t, "2025-03-01 07:00:00+00:00", raw)
+}
+
+func TestTargetsRoundTrip(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	targets := []storage.Target{
+		{Platform: "ios", URL: "https://apps.apple.com/app/id1"},
+		{Platform: "android", URL: "https://play.google.com/store/apps/details?id=app"},
+	}
+
+	id, err := s.SaveLink(storage.Link{Alias: "app", URL: "https://example.com", Targets: targets})
+	require.NoError(t, err)
+
+	link, err := s.GetLinkByID(id)
+	require.NoError(t, err)
+	require.Equal(t, targets, link.Targets)
+
+	link.Targets = nil
+	require.NoError(t, s.UpdateLink(link))
+
+	link, err = s.GetLink("", "app")
+	require.NoError(t, err)
+	require.Empty(t, link.Targets)
+}
+
+func TestLinkMeta(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	id, err := s.SaveURL("https://example.com/rivers", "rivers")
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "pct", URL: "https://example.com/pct", Title: "100% of_rivers"})
+	require.NoError(t, err)
+
+	meta := storage.PageMeta{Title: "Rivers Are Rising", Description: "News.", Image: "https://example.com/r.png"}
+	require.NoError(t, s.SetLinkMeta(id, "https://example.com/rivers", meta))
+	// Fetched for a destination the link no longer has.
+	require.NoError(t, s.SetLinkMeta(id, "https://example.com/old", storage.PageMeta{Title: "Old"}))
+
+	link, err := s.GetLinkByID(id)
+	require.NoError(t, err)
+	require.Equal(t, meta, storage.PageMeta{Title: link.Title, Description: link.Description, Image: link.Image})
+
+	// Edits leave the metadata alone.
+	link.Title = ""
+	link.RedirectStatus = 301
+	require.NoError(t, s.UpdateLink(link))
+	link, err = s.GetLinkByID(id)
+	require.NoError(t, err)
+	require.Equal(t, "Rivers Are Rising", link.Title)
+
+	aliases := func(title string) []string {
+		links, err := s.ListLinks(storage.ListOptions{Title: title})
+		require.NoError(t, err)
+		var res []string
+		for _, l := range links {
+			res = append(res, l.Alias)
+		}
+		return res
+	}
+	require.Equal(t, []string{"rivers", "pct"}, aliases(""))
+	require.Equal(t, []string{"rivers", "pct"}, aliases("RIVERS"))
+	require.Equal(t, []string{"pct"}, aliases("100%"))
+	require.Equal(t, []string{"pct"}, aliases("of_"))
+	require.Empty(t, aliases("s_r"))
+}
+
+func TestLinkUnfurl(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	unfurl := &storage.PageMeta{Title: "Team Wiki", Image: "https://example.com/wiki.png"}
+	id, err := s.SaveLink(storage.Link{Alias: "wiki", URL: "https://example.com/login", Unfurl: unfurl})
+	require.NoError(t, err)
+
+	link, err := s.GetLinkByID(id)
+	require.NoError(t, err)
+	require.Equal(t, unfurl, link.Unfurl)
+
+	// Unlike the fetched metadata, the card is the owner's to edit.
+	link.Unfurl = nil
+	require.NoError(t, s.UpdateLink(link))
+	link, err = s.GetLink("", "wiki")
+	require.NoError(t, err)
+	require.Nil(t, link.Unfurl)
+}
+
+func TestAliasesPerDomain(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	_, err = s.SaveLink(storage.Link{Alias: "docs", URL: "https://example.com/docs"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Domain: "go.acme.com", Alias: "docs", URL: "https://acme.com/docs"})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Domain: "go.acme.com", Alias: "docs", URL: "https://acme.com/other"})
+	require.ErrorIs(t, err, storage.ErrURLExist)
+
+	link, err := s.GetLink("", "docs")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/docs", link.URL)
+
+	link, err = s.GetLink("go.acme.com", "docs")
+	require.NoError(t, err)
+	require.Equal(t, "go.acme.com", link.Domain)
+	require.Equal(t, "https://acme.com/docs", link.URL)
+
+	_, err = s.GetLink("go.other.com", "docs")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	url, err := s.GetURL("docs")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/docs", url)
+}
+
+func TestDomainMigrationKeepsClicks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path)
+	require.NoError(t, err)
+
+	id, err := s.SaveLink(storage.Link{Alias: "docs", URL: "https://example.com/docs"})
+	require.NoError(t, err)
+	require.NoError(t, s.RecordClick(storage.Click{LinkID: id, At: time.Now()}))
+
+	// Migration 14 rebuilds the links table; running it again must not
+	// take the clicks of the old table with it. The later migrations are
+	// undone first so that they can run again too.
+	_, err = s.DB.Exec(`
+		DROP TABLE links_fts;
+		DROP TABLE link_tags;
+		DROP INDEX idx_links_folder;
+		ALTER TABLE links DROP COLUMN folder;
+		DROP TABLE workspace_creates;
+		DROP TABLE api_keys;
+		DROP TABLE workspaces;
+		DROP INDEX idx_links_workspace;
+		ALTER TABLE links DROP COLUMN workspace_id;
+		PRAGMA user_version = 13`)
+	require.NoError(t, err)
+	require.NoError(t, s.DB.Close())
+
+	s, err = sqlite.New(path)
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	stats, err := s.GetStats("", "docs")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, stats.Clicks)
+}
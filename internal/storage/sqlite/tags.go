@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"link-shortener/internal/storage"
+)
+
+// setTags replaces the tags of the link with linkID by tags, within tx.
+func setTags(tx *sql.Tx, linkID int64, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM link_tags WHERE link_id = ?", linkID); err != nil {
+		return fmt.Errorf("clear tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		// OR IGNORE so that a tag listed twice is stored once.
+		if _, err := tx.Exec("INSERT OR IGNORE INTO link_tags (link_id, tag) VALUES (?, ?)", linkID, tag); err != nil {
+			return fmt.Errorf("add tag %q: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// taggedLinks selects the ids of the links with a tag, of the workspace
+// with an id unless that is zero. Its placeholders take the tag and the
+// workspace id twice.
+const taggedLinks = `SELECT t.link_id FROM link_tags t JOIN links l ON l.id = t.link_id
+	WHERE t.tag = ? AND (? = 0 OR l.workspace_id = ?)`
+
+// GetTagStats sums up the clicks of all links with tag, only counting the
+// links of the workspace with workspaceID unless it is zero. It fails with
+// storage.ErrTagNotFound if there are no such links.
+func (s *Storage) GetTagStats(workspaceID int64, tag string) (storage.TagStats, error) {
+	const op = "storage.sqlite.GetTagStats"
+
+	args := []any{tag, workspaceID, workspaceID}
+
+	var links int64
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM ("+taggedLinks+")", args...).Scan(&links); err != nil {
+		return storage.TagStats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if links == 0 {
+		return storage.TagStats{}, storage.ErrTagNotFound
+	}
+
+	stats, err := s.clickStats("link_id IN ("+taggedLinks+")", args...)
+	if err != nil {
+		return storage.TagStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return storage.TagStats{Tag: tag, Links: links, Stats: stats}, nil
+}
@@ -0,0 +1,89 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestTagsAndFolders(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme"})
+	require.NoError(t, err)
+
+	launch, err := s.SaveLink(storage.Link{Alias: "launch", URL: "https://example.com", Folder: "Marketing", Tags: []string{"launch", "q3"}})
+	require.NoError(t, err)
+	own, err := s.SaveLink(storage.Link{Alias: "acme~launch", URL: "https://example.com", WorkspaceID: wsID, Tags: []string{"launch"}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "plain", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	link, err := s.GetLink("", "launch")
+	require.NoError(t, err)
+	require.Equal(t, "Marketing", link.Folder)
+	require.Equal(t, []string{"launch", "q3"}, link.Tags)
+
+	link, err = s.GetLink("", "plain")
+	require.NoError(t, err)
+	require.Nil(t, link.Tags)
+
+	aliases := func(opts storage.ListOptions) []string {
+		links, err := s.ListLinks(opts)
+		require.NoError(t, err)
+		var aliases []string
+		for _, link := range links {
+			aliases = append(aliases, link.Alias)
+		}
+		return aliases
+	}
+	require.ElementsMatch(t, []string{"launch", "acme~launch"}, aliases(storage.ListOptions{Tag: "launch"}))
+	require.Equal(t, []string{"acme~launch"}, aliases(storage.ListOptions{Tag: "launch", WorkspaceID: wsID}))
+	require.Equal(t, []string{"launch"}, aliases(storage.ListOptions{Folder: "Marketing"}))
+	require.Empty(t, aliases(storage.ListOptions{Tag: "q4"}))
+
+	for _, id := range []int64{launch, launch, own} {
+		require.NoError(t, s.RecordClick(storage.Click{LinkID: id, At: time.Now(), Country: "DE"}))
+	}
+
+	stats, err := s.GetTagStats(0, "launch")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, stats.Links)
+	require.EqualValues(t, 3, stats.Clicks)
+	require.Equal(t, []storage.CountryClicks{{Country: "DE", Clicks: 3}}, stats.Countries)
+
+	stats, err = s.GetTagStats(wsID, "launch")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, stats.Links)
+	require.EqualValues(t, 1, stats.Clicks)
+
+	_, err = s.GetTagStats(wsID, "q3")
+	require.ErrorIs(t, err, storage.ErrTagNotFound)
+
+	// Updating a link replaces its tags.
+	link, err = s.GetLink("", "launch")
+	require.NoError(t, err)
+	link.Tags, link.Folder = []string{"q4"}, ""
+	require.NoError(t, s.UpdateLink(link))
+
+	link, err = s.GetLink("", "launch")
+	require.NoError(t, err)
+	require.Equal(t, []string{"q4"}, link.Tags)
+	require.Empty(t, link.Folder)
+
+	_, err = s.GetTagStats(0, "q3")
+	require.ErrorIs(t, err, storage.ErrTagNotFound)
+
+	// Deleting a link drops its tags.
+	require.NoError(t, s.DeleteURL(own))
+	stats, err = s.GetTagStats(0, "launch")
+	require.ErrorIs(t, err, storage.ErrTagNotFound)
+	require.Zero(t, stats.Links)
+}
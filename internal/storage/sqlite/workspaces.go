@@ -0,0 +1,306 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"link-shortener/internal/storage"
+	"time"
+)
+
+// insertLink inserts link within tx and returns its id. A link of a
+// workspace is counted against the quotas of the workspace. The writes are
+// made under a savepoint, so a failing link leaves nothing behind and the
+// caller can go on with the transaction.
+func insertLink(tx *sql.Tx, link storage.Link) (int64, error) {
+	if _, err := tx.Exec("SAVEPOINT insert_link"); err != nil {
+		return 0, fmt.Errorf("savepoint: %w", err)
+	}
+
+	id, err := writeLink(tx, link)
+	if err != nil {
+		if _, rbErr := tx.Exec("ROLLBACK TO insert_link"); rbErr != nil {
+			return 0, fmt.Errorf("roll back to savepoint: %w", rbErr)
+		}
+	}
+	if _, relErr := tx.Exec("RELEASE insert_link"); relErr != nil {
+		return 0, fmt.Errorf("release savepoint: %w", relErr)
+	}
+
+	return id, err
+}
+
+// writeLink does the writes of insertLink.
+func writeLink(tx *sql.Tx, link storage.Link) (int64, error) {
+	res, err := tx.Exec(insertLinkQuery, insertValues(link)...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, storage.ErrURLExist
+		}
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get id %w", err)
+	}
+
+	if link.WorkspaceID != 0 {
+		if err := chargeQuota(tx, link.WorkspaceID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := setTags(tx, id, link.Tags); err != nil {
+		return 0, err
+	}
+
+	if err := indexLink(tx, id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// chargeQuota counts a link of the workspace with workspaceID, which was
+// just inserted, against the quotas of the workspace. Inserting first makes
+// the transaction hold the write lock while counting, so concurrent saves
+// cannot both take the last slot.
+func chargeQuota(tx *sql.Tx, workspaceID int64) error {
+	day := time.Now().UTC().Format(time.DateOnly)
+
+	var ws storage.Workspace
+	var usage storage.WorkspaceUsage
+	err := tx.QueryRow(`
+		SELECT max_links, max_daily_creates,
+		    (SELECT COUNT(*) FROM links WHERE workspace_id = w.id),
+		    COALESCE((SELECT count FROM workspace_creates WHERE workspace_id = w.id AND day = ?), 0)
+		FROM workspaces w WHERE id = ?`,
+		day, workspaceID,
+	).Scan(&ws.MaxLinks, &ws.MaxDailyCreates, &usage.Links, &usage.CreatedToday)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return storage.ErrWorkspaceNotFound
+	case err != nil:
+		return fmt.Errorf("read quota: %w", err)
+	case ws.MaxLinks > 0 && usage.Links > ws.MaxLinks:
+		return storage.ErrLinkQuota
+	case ws.MaxDailyCreates > 0 && usage.CreatedToday >= ws.MaxDailyCreates:
+		return storage.ErrDailyQuota
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO workspace_creates (workspace_id, day, count) VALUES (?, ?, 1)
+		ON CONFLICT (workspace_id, day) DO UPDATE SET count = count + 1`,
+		workspaceID, day,
+	)
+	if err != nil {
+		return fmt.Errorf("count create: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWorkspace stores a new workspace and returns its id. It fails with
+// storage.ErrWorkspaceExists if the name is taken.
+func (s *Storage) CreateWorkspace(ws storage.Workspace) (int64, error) {
+	const op = "storage.sqlite.CreateWorkspace"
+
+	res, err := s.DB.Exec(
+		"INSERT INTO workspaces (name, max_links, max_daily_creates) VALUES (?, ?, ?)",
+		ws.Name, ws.MaxLinks, ws.MaxDailyCreates,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrWorkspaceExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id %w", op, err)
+	}
+
+	return id, nil
+}
+
+const workspaceColumns = "id, name, max_links, max_daily_creates"
+
+func scanWorkspace(row rowScanner) (storage.Workspace, error) {
+	var ws storage.Workspace
+	err := row.Scan(&ws.ID, &ws.Name, &ws.MaxLinks, &ws.MaxDailyCreates)
+	return ws, err
+}
+
+// GetWorkspace returns the workspace called name.
+func (s *Storage) GetWorkspace(name string) (storage.Workspace, error) {
+	const op = "storage.sqlite.GetWorkspace"
+
+	ws, err := scanWorkspace(s.DB.QueryRow("SELECT "+workspaceColumns+" FROM workspaces WHERE name = ?", name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return storage.Workspace{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ws, nil
+}
+
+// ListWorkspaces returns all workspaces ordered by name.
+func (s *Storage) ListWorkspaces() ([]storage.Workspace, error) {
+	const op = "storage.sqlite.ListWorkspaces"
+
+	rows, err := s.DB.Query("SELECT " + workspaceColumns + " FROM workspaces ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var list []storage.Workspace
+	for rows.Next() {
+		ws, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		list = append(list, ws)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return list, nil
+}
+
+// SetWorkspaceQuota replaces the quotas of the workspace with id by those of
+// ws. Links over a lowered quota are kept; only new ones are refused.
+func (s *Storage) SetWorkspaceQuota(id int64, ws storage.Workspace) error {
+	const op = "storage.sqlite.SetWorkspaceQuota"
+
+	res, err := s.DB.Exec(
+		"UPDATE workspaces SET max_links = ?, max_daily_creates = ? WHERE id = ?",
+		ws.MaxLinks, ws.MaxDailyCreates, id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWorkspaceNotFound)
+	}
+
+	return nil
+}
+
+// WorkspaceUsage returns how many links the workspace with id has, and how
+// many it created today.
+func (s *Storage) WorkspaceUsage(id int64) (storage.WorkspaceUsage, error) {
+	const op = "storage.sqlite.WorkspaceUsage"
+
+	var usage storage.WorkspaceUsage
+	err := s.DB.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM links WHERE workspace_id = ?),
+		    COALESCE((SELECT count FROM workspace_creates WHERE workspace_id = ? AND day = ?), 0)`,
+		id, id, time.Now().UTC().Format(time.DateOnly),
+	).Scan(&usage.Links, &usage.CreatedToday)
+	if err != nil {
+		return usage, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return usage, nil
+}
+
+// CreateAPIKey stores key with the hash of its secret and returns its id.
+func (s *Storage) CreateAPIKey(key storage.APIKey, hash string) (int64, error) {
+	const op = "storage.sqlite.CreateAPIKey"
+
+	res, err := s.DB.Exec(
+		"INSERT INTO api_keys (workspace_id, name, hash, created_at) VALUES (?, ?, ?, ?)",
+		key.WorkspaceID, key.Name, hash, key.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get id %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListAPIKeys returns the API keys of the workspace with workspaceID, oldest
+// first.
+func (s *Storage) ListAPIKeys(workspaceID int64) ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+
+	rows, err := s.DB.Query(
+		"SELECT id, workspace_id, name, created_at FROM api_keys WHERE workspace_id = ? ORDER BY id",
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []storage.APIKey
+	for rows.Next() {
+		var key storage.APIKey
+		if err := rows.Scan(&key.ID, &key.WorkspaceID, &key.Name, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		key.CreatedAt = key.CreatedAt.UTC()
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes the API key with id.
+func (s *Storage) DeleteAPIKey(id int64) error {
+	const op = "storage.sqlite.DeleteAPIKey"
+
+	res, err := s.DB.Exec("DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// WorkspaceByKey returns the workspace of the API key whose secret has hash.
+func (s *Storage) WorkspaceByKey(hash string) (storage.Workspace, error) {
+	const op = "storage.sqlite.WorkspaceByKey"
+
+	ws, err := scanWorkspace(s.DB.QueryRow(
+		"SELECT w.id, w.name, w.max_links, w.max_daily_creates FROM api_keys k "+
+			"JOIN workspaces w ON w.id = k.workspace_id WHERE k.hash = ?",
+		hash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Workspace{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.Workspace{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ws, nil
+}
@@ -0,0 +1,113 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"link-shortener/internal/storage"
+	"link-shortener/internal/storage/sqlite"
+)
+
+func TestWorkspaceQuotas(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme", MaxLinks: 2, MaxDailyCreates: 3})
+	require.NoError(t, err)
+	_, err = s.CreateWorkspace(storage.Workspace{Name: "acme"})
+	require.ErrorIs(t, err, storage.ErrWorkspaceExists)
+
+	save := func(alias string) (int64, error) {
+		return s.SaveLink(storage.Link{Alias: "acme~" + alias, URL: "https://example.com", WorkspaceID: wsID})
+	}
+
+	first, err := save("a")
+	require.NoError(t, err)
+	_, err = save("b")
+	require.NoError(t, err)
+
+	_, err = save("c")
+	require.ErrorIs(t, err, storage.ErrLinkQuota)
+	_, err = s.GetLink("", "acme~c")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Deleting a link frees a slot for the link quota, but the link still
+	// counts against the daily one.
+	require.NoError(t, s.DeleteURL(first))
+	_, err = save("c")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURL(first+1))
+	_, err = save("d")
+	require.ErrorIs(t, err, storage.ErrDailyQuota)
+
+	// Links outside of workspaces have no quota.
+	_, err = s.SaveURL("https://example.com", "free")
+	require.NoError(t, err)
+
+	usage, err := s.WorkspaceUsage(wsID)
+	require.NoError(t, err)
+	require.Equal(t, storage.WorkspaceUsage{Links: 1, CreatedToday: 3}, usage)
+
+	require.NoError(t, s.SetWorkspaceQuota(wsID, storage.Workspace{}))
+	_, err = save("d")
+	require.NoError(t, err)
+
+	links, err := s.ListLinks(storage.ListOptions{WorkspaceID: wsID})
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	for _, link := range links {
+		require.Equal(t, wsID, link.WorkspaceID)
+	}
+}
+
+func TestWorkspaceQuotasInBatch(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme", MaxLinks: 1})
+	require.NoError(t, err)
+
+	results, err := s.SaveURLs([]storage.Link{
+		{Alias: "acme~a", URL: "https://example.com", WorkspaceID: wsID},
+		{Alias: "acme~b", URL: "https://example.com", WorkspaceID: wsID},
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, storage.ErrLinkQuota)
+
+	usage, err := s.WorkspaceUsage(wsID)
+	require.NoError(t, err)
+	require.Equal(t, storage.WorkspaceUsage{Links: 1, CreatedToday: 1}, usage)
+}
+
+func TestAPIKeys(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.DB.Close() }()
+
+	wsID, err := s.CreateWorkspace(storage.Workspace{Name: "acme"})
+	require.NoError(t, err)
+
+	created := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	keyID, err := s.CreateAPIKey(storage.APIKey{WorkspaceID: wsID, Name: "ci", CreatedAt: created}, "hash")
+	require.NoError(t, err)
+
+	ws, err := s.WorkspaceByKey("hash")
+	require.NoError(t, err)
+	require.Equal(t, "acme", ws.Name)
+
+	keys, err := s.ListAPIKeys(wsID)
+	require.NoError(t, err)
+	require.Equal(t, []storage.APIKey{{ID: keyID, WorkspaceID: wsID, Name: "ci", CreatedAt: created}}, keys)
+
+	require.NoError(t, s.DeleteAPIKey(keyID))
+	_, err = s.WorkspaceByKey("hash")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+	require.ErrorIs(t, s.DeleteAPIKey(keyID), storage.ErrAPIKeyNotFound)
+}
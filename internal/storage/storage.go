@@ -1,6 +1,355 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var ErrURLNotFound = errors.New("URL not found")
 var ErrURLExist = errors.New("URL with the same alias already exists")
+var ErrLinkExhausted = errors.New("link has no clicks left")
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+var ErrWorkspaceExists = errors.New("workspace with the same name already exists")
+var ErrAPIKeyNotFound = errors.New("API key not found")
+var ErrLinkQuota = errors.New("workspace has reached its link quota")
+var ErrDailyQuota = errors.New("workspace has reached its daily quota of new links")
+var ErrTagNotFound = errors.New("tag not found")
+
+// Link is a single short link stored in the links table.
+type Link struct {
+	ID    int64  `json:"id"`
+	Alias string `json:"alias"`
+	URL   string `json:"url"`
+	// Domain is the short domain the link is served on, empty for the
+	// default one. Aliases are unique per domain.
+	Domain string `json:"domain,omitempty"`
+	// WorkspaceID is the workspace owning the link, zero if none does. It is
+	// set when the link is created and counts against the quotas of the
+	// workspace.
+	WorkspaceID int64 `json:"workspace_id,omitempty"`
+	// Folder, if set, is the folder the link is filed in.
+	Folder string `json:"folder,omitempty"`
+	// Tags label the link, in order. A link has any number of tags and a
+	// tag any number of links.
+	Tags []string `json:"tags,omitempty"`
+	// RedirectStatus is the HTTP status used to redirect (301, 302, 307 or
+	// 308). Zero means the server default.
+	RedirectStatus int `json:"redirect_status,omitempty"`
+	// PasswordHash is the bcrypt hash of the password needed to follow the
+	// link, empty if the link is not protected. It is only ever exposed
+	// through admin exports; API responses use Public.
+	PasswordHash string `json:"password_hash,omitempty"`
+	// MaxClicks is the number of redirects after which the link stops
+	// working (1 for a one-time link). Zero means no limit.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// UsedClicks counts the redirects of a limited link.
+	UsedClicks int64 `json:"used_clicks,omitempty"`
+	// ActiveFrom and ActiveUntil, if set, bound the time (in UTC) in which
+	// the link redirects to URL. Outside of it the link redirects to
+	// FallbackURL, or does not work if there is none.
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+	FallbackURL string     `json:"fallback_url,omitempty"`
+	// Targets send visitors on particular platforms elsewhere than URL. The
+	// first one that matches the visitor's User-Agent is used.
+	Targets []Target `json:"targets,omitempty"`
+	// GeoTargets send visitors from particular countries elsewhere than URL.
+	// They apply to visitors no platform target matches.
+	GeoTargets []GeoTarget `json:"geo_targets,omitempty"`
+	// Destinations split the visitors that no target applies to between
+	// several URLs by weight, in place of URL.
+	Destinations []Destination `json:"destinations,omitempty"`
+	// QueryParams are added to the query of whichever URL a visitor is sent
+	// to. Values may contain the placeholders {alias} and {country}.
+	QueryParams []QueryParam `json:"query_params,omitempty"`
+	// QueryPassthrough forwards the query of the short URL as well.
+	// Parameters the destination sets itself are never replaced; forwarded
+	// ones take precedence over QueryParams.
+	QueryPassthrough bool `json:"query_passthrough,omitempty"`
+	// Prefix makes the link also match paths below its alias: /docs/api/v2
+	// redirects to the destination with /api/v2 appended.
+	Prefix bool `json:"prefix,omitempty"`
+	// Interstitial makes browsers see a warning page naming the destination,
+	// instead of being redirected, when it is on an external domain.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Title, Description and Image describe the page at URL. They are
+	// fetched in the background after the link is saved, see PageMeta.
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Unfurl, if set, is what chat apps and social networks show when the
+	// link is shared. Its empty fields fall back to Title, Description and
+	// Image.
+	Unfurl *PageMeta `json:"unfurl,omitempty"`
+
+	// Protected is set by Public on links that have a password.
+	Protected bool `json:"protected,omitempty" linkio:"-"`
+	// RemainingClicks is set by Public on limited links.
+	RemainingClicks *int64 `json:"remaining_clicks,omitempty" linkio:"-"`
+}
+
+// Target is a destination for the visitors on one platform, such as "ios"
+// or "desktop" (see package useragent).
+type Target struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// GeoTarget is a destination for the visitors from one country, given as
+// an ISO 3166-1 alpha-2 code such as "DE".
+type GeoTarget struct {
+	Country string `json:"country"`
+	URL     string `json:"url"`
+}
+
+// Destination is one variant of a split link. Visitors are sent to it with
+// a probability of Weight over the total weight; zero pauses it.
+type Destination struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// QueryParam is a query parameter added to redirects, such as utm_source.
+type QueryParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Window tells where t falls relative to a link's activation window.
+type Window int
+
+const (
+	WindowActive Window = iota
+	WindowPending
+	WindowEnded
+)
+
+// WindowAt reports whether the link is active at t.
+func (l Link) WindowAt(t time.Time) Window {
+	switch {
+	case l.ActiveFrom != nil && t.Before(*l.ActiveFrom):
+		return WindowPending
+	case l.ActiveUntil != nil && !t.Before(*l.ActiveUntil):
+		return WindowEnded
+	default:
+		return WindowActive
+	}
+}
+
+// Exhausted reports whether a limited link has used up its clicks.
+func (l Link) Exhausted() bool {
+	return l.MaxClicks > 0 && l.UsedClicks >= l.MaxClicks
+}
+
+// Public returns the link with its secrets removed and derived fields set,
+// for API responses.
+func (l Link) Public() Link {
+	l.Protected = l.PasswordHash != ""
+	l.PasswordHash = ""
+
+	if l.MaxClicks > 0 {
+		remaining := max(l.MaxClicks-l.UsedClicks, 0)
+		l.RemainingClicks = &remaining
+	}
+
+	return l
+}
+
+// ListOptions limits which links are returned by a listing.
+// A zero Limit means no limit.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Title, if set, only lists links whose title contains it, ignoring
+	// case.
+	Title string
+	// WorkspaceID, if set, only lists the links of that workspace.
+	WorkspaceID int64
+	// Tag and Folder, if set, only list the links with that tag and in
+	// that folder.
+	Tag    string
+	Folder string
+}
+
+// SearchOptions select the links a search returns, best matches first.
+// A zero Limit means no limit.
+type SearchOptions struct {
+	// Query is the free text searched for. Every word of it must start a
+	// word of the alias, destination URL, title or tags of a link.
+	Query  string
+	Limit  int
+	Offset int
+	// WorkspaceID, if set, only searches the links of that workspace.
+	WorkspaceID int64
+}
+
+// SearchResult is a link matching a search.
+type SearchResult struct {
+	Link Link `json:"link"`
+	// Score ranks the result among those of the same search; higher is
+	// better. Scores of different searches or storages do not compare.
+	Score      float64    `json:"score"`
+	Highlights Highlights `json:"highlights"`
+}
+
+// Highlights are the searched fields of a link that match a search,
+// HTML-escaped and with the matching words wrapped in <mark> tags. Fields
+// without a match are left empty.
+type Highlights struct {
+	Alias string   `json:"alias,omitempty"`
+	URL   string   `json:"url,omitempty"`
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// Workspace is a team sharing the instance. It owns links, API keys and
+// domains; its links on shared domains have aliases in its own namespace.
+type Workspace struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// MaxLinks is the number of links the workspace may have. Zero means no
+	// limit.
+	MaxLinks int64 `json:"max_links,omitempty"`
+	// MaxDailyCreates is the number of links the workspace may create per
+	// UTC day, deleted ones included. Zero means no limit.
+	MaxDailyCreates int64 `json:"max_daily_creates,omitempty"`
+}
+
+// WorkspaceUsage is how much of its quotas a workspace uses.
+type WorkspaceUsage struct {
+	Links        int64 `json:"links"`
+	CreatedToday int64 `json:"created_today"`
+}
+
+// APIKey lets clients act on behalf of a workspace. Only a hash of the key
+// itself is stored.
+type APIKey struct {
+	ID          int64     `json:"id"`
+	WorkspaceID int64     `json:"workspace_id"`
+	Name        string    `json:"name,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PageMeta is what the page a link leads to says about itself, see
+// Link.Title, or what the owner of a link wants said about it, see
+// Link.Unfurl.
+type PageMeta struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// Empty reports whether m has no field set.
+func (m PageMeta) Empty() bool {
+	return m == PageMeta{}
+}
+
+// Click is a single visit of a short link.
+type Click struct {
+	LinkID    int64
+	At        time.Time
+	Referer   string
+	UserAgent string
+	// Variant is the destination URL served by a split link.
+	Variant string
+	// Country is the visitor's ISO 3166-1 alpha-2 code, if known.
+	Country string
+}
+
+// Stats summarizes the clicks of a link.
+type Stats struct {
+	Clicks      int64           `json:"clicks"`
+	LastClickAt *time.Time      `json:"last_click_at,omitempty"`
+	Daily       []DailyClicks   `json:"daily,omitempty"`
+	Variants    []VariantClicks `json:"variants,omitempty"`
+	Countries   []CountryClicks `json:"countries,omitempty"`
+}
+
+// TagStats summarizes the clicks of all links with a tag.
+type TagStats struct {
+	Tag   string `json:"tag"`
+	Links int64  `json:"links"`
+	Stats
+}
+
+// DailyClicks is the number of clicks on a single UTC day (YYYY-MM-DD).
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// VariantClicks is the number of clicks served by one destination of a
+// split link.
+type VariantClicks struct {
+	URL    string `json:"url"`
+	Clicks int64  `json:"clicks"`
+}
+
+// CountryClicks is the number of clicks from one country.
+type CountryClicks struct {
+	Country string `json:"country"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// HealthCheck is the outcome of requesting the destination of a link once.
+type HealthCheck struct {
+	LinkID int64
+	URL    string
+	At     time.Time
+	// StatusCode is that of the response, zero if there was none.
+	StatusCode int
+	// Error says why there was no response.
+	Error   string
+	Latency time.Duration
+	// OK is set if the destination answered with a status below 400.
+	OK bool
+}
+
+// Health is the state of the destination of a link, as of its latest check.
+type Health struct {
+	LinkID     int64     `json:"link_id"`
+	Domain     string    `json:"domain,omitempty"`
+	Alias      string    `json:"alias"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	LatencyMS  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	// Failures counts the checks that failed in a row.
+	Failures int `json:"failures"`
+	// BrokenSince is when Failures reached the threshold of the checker.
+	// A link is broken while it is set.
+	BrokenSince *time.Time `json:"broken_since,omitempty"`
+}
+
+// HealthOptions limit which links a health report lists.
+type HealthOptions struct {
+	ListOptions
+	// BrokenOnly leaves out links that are not broken.
+	BrokenOnly bool
+}
+
+// HealthReport sums up the health of all checked links and lists some of
+// them, broken ones first.
+type HealthReport struct {
+	Checked int64    `json:"checked"`
+	Failing int64    `json:"failing"`
+	Broken  int64    `json:"broken"`
+	Links   []Health `json:"links"`
+}
+
+// SaveResult is the outcome of saving one link of a batch.
+type SaveResult struct {
+	ID  int64
+	Err error
+}
+
+// ImportTx stores imported links in a single transaction.
+type ImportTx interface {
+	// SaveLink inserts a new link or fails with ErrURLExist.
+	SaveLink(link Link) (int64, error)
+	// OverwriteLink replaces the link with the same domain and alias.
+	OverwriteLink(link Link) (int64, error)
+	Commit() error
+	Rollback() error
+}